@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/admin"
+	"github.com/siddarth99/banking-ledger/pkg/deadletter"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+)
+
+// adminTokenHeader is the shared-secret header operators present to reach
+// the /admin endpoints.
+const adminTokenHeader = "X-Admin-Token"
+
+// AdminAuth rejects any request that doesn't present token via the
+// X-Admin-Token header. token is typically read from an environment
+// variable by main.go; an empty token disables every /admin route by
+// rejecting all requests, rather than accidentally leaving them open.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" || c.GetHeader(adminTokenHeader) != token {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"errorCode": http.StatusUnauthorized,
+				"error":     "Missing or invalid admin token",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetQueueStatsHandler reports the depth and consumer count of each of
+// queueNames, for operators who'd otherwise need the RabbitMQ UI.
+func GetQueueStatsHandler(channel internal.AMQPQueueInspector, queueNames []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats, err := admin.InspectQueues(channel, queueNames)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"queues": stats})
+	}
+}
+
+// GetWorkerStatusHandler reports the last-known heartbeat and processed/
+// failed counters for every worker that has ever reported in.
+func GetWorkerStatusHandler(heartbeats *admin.Heartbeats) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statuses, err := heartbeats.List(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"workers": statuses})
+	}
+}
+
+// requeuedTransactionPayload is the queue message shape expected by
+// workers/processor.TransactionData; it's rebuilt from the indexed
+// TransactionDocument rather than replayed verbatim, since a failed
+// transaction's pending-store entry may already be gone by the time an
+// operator requeues it.
+type requeuedTransactionPayload struct {
+	AccountNumber string       `json:"accountNumber"`
+	Amount        money.Amount `json:"amount"`
+	Type          string       `json:"type"`
+	TransactionID string       `json:"transactionId"`
+	BranchCode    string       `json:"branchCode"`
+	RetryCount    int          `json:"retryCount"`
+}
+
+// RequeueTransactionHandler republishes a failed transaction back onto the
+// processing queue, incrementing its retry count so the worker and any
+// downstream monitoring can tell a requeue apart from the original attempt.
+// It publishes via topology's PublishWithRetryTopology rather than a bare
+// internal.PublishWithContext, so the retry ladder it registers the worker
+// also drains through, in case this manual requeue fails too.
+func RequeueTransactionHandler(esClient internal.ElasticsearchClient, amqpChannel internal.AMQPChannel, topology *deadletter.Topology) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transactionID := c.Param("id")
+		if transactionID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     "Transaction ID is required",
+			})
+			return
+		}
+
+		doc, err := findFailedTransaction(esClient, transactionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     "Failed to look up transaction: " + err.Error(),
+			})
+			return
+		}
+		if doc == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"errorCode": http.StatusNotFound,
+				"error":     "No failed transaction found for this ID",
+			})
+			return
+		}
+
+		payload := requeuedTransactionPayload{
+			AccountNumber: doc.AccountNumber,
+			Amount:        doc.Amount,
+			Type:          doc.Type,
+			TransactionID: doc.TransactionID,
+			BranchCode:    doc.BranchCode,
+			RetryCount:    doc.RetryCount + 1,
+		}
+		body, err := json.Marshal(payload)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		if err := deadletter.PublishWithRetryTopology(c.Request.Context(), amqpChannel, topology, body); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"transactionID": payload.TransactionID,
+			"retryCount":    payload.RetryCount,
+		})
+	}
+}
+
+// failedTransactionDoc is the subset of TransactionDocument fields needed to
+// rebuild a requeue payload.
+type failedTransactionDoc struct {
+	TransactionID string       `json:"transaction_id"`
+	AccountNumber string       `json:"account_number"`
+	Amount        money.Amount `json:"amount"`
+	Type          string       `json:"type"`
+	BranchCode    string       `json:"branch_code"`
+	RetryCount    int          `json:"retry_count"`
+}
+
+// findFailedTransaction looks up the most recent FAILED document for
+// transactionID in bank-transactions-*. It returns (nil, nil) if no failed
+// document exists.
+func findFailedTransaction(esClient internal.ElasticsearchClient, transactionID string) (*failedTransactionDoc, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": []map[string]interface{}{
+					{"match": map[string]interface{}{"transaction_id": transactionID}},
+					{"match": map[string]interface{}{"status": "FAILED"}},
+				},
+			},
+		},
+		"sort": []map[string]interface{}{
+			{"timestamp": map[string]interface{}{"order": "desc"}},
+		},
+		"size": 1,
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, err
+	}
+
+	res, err := esClient.Search([]string{"bank-transactions-*"}, &buf)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("elasticsearch returned an error response")
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source failedTransactionDoc `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Hits.Hits) == 0 {
+		return nil, nil
+	}
+
+	doc := result.Hits.Hits[0].Source
+	return &doc, nil
+}
+
+// DrainWorkersHandler asks every consuming worker to stop picking up new
+// messages once its current in-flight message finishes, so an operator can
+// roll out a deploy without killing in-progress transactions.
+func DrainWorkersHandler(db internal.PgDBConnection) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := admin.RequestDrain(c.Request.Context(), db); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"status": "draining"})
+	}
+}
+
+// DefaultDeadLetteredTransactionsLimit bounds how many messages
+// GetDeadLetteredTransactionsHandler peeks off the terminal dead queue per
+// request.
+const DefaultDeadLetteredTransactionsLimit = 50
+
+// GetDeadLetteredTransactionsHandler lists the transactions sitting in
+// topology's terminal dead queue, i.e. those that exhausted every rung of
+// the retry ladder, for operator inspection. Listing is non-destructive:
+// see deadletter.ListDeadLettered.
+func GetDeadLetteredTransactionsHandler(channel internal.AMQPQueueGetter, topology *deadletter.Topology) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		messages, err := deadletter.ListDeadLettered(channel, topology, DefaultDeadLetteredTransactionsLimit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"transactions": messages})
+	}
+}
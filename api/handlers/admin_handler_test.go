@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/admin"
+	"github.com/siddarth99/banking-ledger/pkg/deadletter"
+)
+
+func TestAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/admin/ping", AdminAuth("secret-token"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	t.Run("Missing token is rejected", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/admin/ping", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Wrong token is rejected", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/admin/ping", nil)
+		req.Header.Set(adminTokenHeader, "wrong-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Correct token is let through", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/admin/ping", nil)
+		req.Header.Set(adminTokenHeader, "secret-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Empty configured token rejects everyone", func(t *testing.T) {
+		emptyTokenRouter := gin.New()
+		emptyTokenRouter.GET("/admin/ping", AdminAuth(""), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"ok": true})
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, "/admin/ping", nil)
+		req.Header.Set(adminTokenHeader, "")
+		w := httptest.NewRecorder()
+		emptyTokenRouter.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestGetQueueStatsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	channel := &internal.MockAMQPChannel{}
+	channel.On("QueueInspect", "account_creator").Return(amqp.Queue{Name: "account_creator", Messages: 2, Consumers: 1}, nil)
+	channel.On("QueueInspect", "transaction_processor").Return(amqp.Queue{Name: "transaction_processor", Messages: 0, Consumers: 1}, nil)
+
+	router := gin.New()
+	router.GET("/admin/queues", GetQueueStatsHandler(channel, []string{"account_creator", "transaction_processor"}))
+
+	req, _ := http.NewRequest(http.MethodGet, "/admin/queues", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Queues []admin.QueueStats `json:"queues"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Queues, 2)
+	assert.Equal(t, "account_creator", response.Queues[0].Name)
+	assert.Equal(t, 2, response.Queues[0].Messages)
+}
+
+func TestGetWorkerStatusHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := &internal.MockPgDBConnection{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			row := 0
+			return &internal.MockPgxRows{
+				NextFunc: func() bool {
+					row++
+					return row <= 1
+				},
+				ScanFunc: func(dest ...interface{}) error {
+					*dest[0].(*string) = "worker-0"
+					*dest[2].(*string) = "TX1"
+					*dest[3].(*int64) = 3
+					*dest[4].(*int64) = 0
+					return nil
+				},
+			}, nil
+		},
+	}
+	heartbeats := admin.NewHeartbeats(db)
+
+	router := gin.New()
+	router.GET("/admin/workers", GetWorkerStatusHandler(heartbeats))
+
+	req, _ := http.NewRequest(http.MethodGet, "/admin/workers", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Workers []admin.WorkerStatus `json:"workers"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Workers, 1)
+	assert.Equal(t, "worker-0", response.Workers[0].WorkerID)
+}
+
+func TestRequeueTransactionHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Requeues a failed transaction with an incremented retry count", func(t *testing.T) {
+		searchResult := map[string]interface{}{
+			"hits": map[string]interface{}{
+				"hits": []map[string]interface{}{
+					{
+						"_source": map[string]interface{}{
+							"transaction_id": "TX1",
+							"account_number": "ACC123456",
+							"amount":         map[string]interface{}{"value": "100.00", "currency": "USD"},
+							"type":           "DEPOSIT",
+							"branch_code":    "BR001",
+							"retry_count":    1,
+						},
+					},
+				},
+			},
+		}
+		searchJSON, _ := json.Marshal(searchResult)
+
+		esClient := &internal.MockElasticsearchClient{
+			SearchFunc: func(indices []string, body io.Reader) (*esapi.Response, error) {
+				return &esapi.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(searchJSON))}, nil
+			},
+		}
+
+		channel := &internal.MockAMQPChannel{}
+		channel.On("QueueDeclare", mock.Anything, true, false, false, false, mock.Anything).Return(nil)
+		channel.On("PublishWithContext", mock.Anything, "", "transaction_processor", false, false, mock.Anything).Return(nil)
+
+		topology := deadletter.New("transaction_processor", deadletter.DefaultDelays)
+		router := gin.New()
+		router.POST("/admin/transactions/:id/requeue", RequeueTransactionHandler(esClient, channel, topology))
+
+		req, _ := http.NewRequest(http.MethodPost, "/admin/transactions/TX1/requeue", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "TX1", response["transactionID"])
+		assert.Equal(t, float64(2), response["retryCount"])
+	})
+
+	t.Run("No failed transaction found", func(t *testing.T) {
+		searchResult := map[string]interface{}{
+			"hits": map[string]interface{}{"hits": []map[string]interface{}{}},
+		}
+		searchJSON, _ := json.Marshal(searchResult)
+
+		esClient := &internal.MockElasticsearchClient{
+			SearchFunc: func(indices []string, body io.Reader) (*esapi.Response, error) {
+				return &esapi.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(searchJSON))}, nil
+			},
+		}
+
+		router := gin.New()
+		router.POST("/admin/transactions/:id/requeue", RequeueTransactionHandler(esClient, &internal.MockAMQPChannel{}, deadletter.New("transaction_processor", deadletter.DefaultDelays)))
+
+		req, _ := http.NewRequest(http.MethodPost, "/admin/transactions/TX404/requeue", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestDrainWorkersHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var execArgs []any
+	db := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			execArgs = arguments
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
+	}
+
+	router := gin.New()
+	router.POST("/admin/workers/drain", DrainWorkersHandler(db))
+
+	req, _ := http.NewRequest(http.MethodPost, "/admin/workers/drain", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, []any{"*"}, execArgs)
+}
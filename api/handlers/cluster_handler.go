@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/cluster"
+)
+
+// GetClusterStatusHandler reports every queue-consumer worker node that has
+// heartbeat within leaseTTL and which one currently holds the leader lease,
+// without itself joining the election.
+func GetClusterStatusHandler(db internal.PgDBConnection, leaseTTL time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		members, err := cluster.Status(c.Request.Context(), db, leaseTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		var leader string
+		for _, m := range members {
+			if m.IsLeader {
+				leader = m.NodeID
+				break
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"leader":  leader,
+			"members": members,
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+func TestGetClusterStatusHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Reports the current leader and members", func(t *testing.T) {
+		leaseQueried := false
+		db := &internal.MockPgDBConnection{
+			QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+				if !leaseQueried {
+					leaseQueried = true
+					return &internal.MockPgxRows{
+						NextFunc: func() bool { return true },
+						ScanFunc: func(dest ...interface{}) error {
+							*dest[0].(*string) = "node-1"
+							return nil
+						},
+					}, nil
+				}
+				row := 0
+				nodes := []string{"node-1", "node-2"}
+				return &internal.MockPgxRows{
+					NextFunc: func() bool {
+						row++
+						return row <= len(nodes)
+					},
+					ScanFunc: func(dest ...interface{}) error {
+						*dest[0].(*string) = nodes[row-1]
+						*dest[1].(*string) = "http://" + nodes[row-1]
+						*dest[2].(*time.Time) = time.Now()
+						*dest[3].(*int64) = 1
+						return nil
+					},
+				}, nil
+			},
+		}
+
+		router := gin.New()
+		router.GET("/cluster/status", GetClusterStatusHandler(db, time.Minute))
+
+		req, _ := http.NewRequest(http.MethodGet, "/cluster/status", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Leader  string `json:"leader"`
+			Members []struct {
+				NodeID   string `json:"NodeID"`
+				IsLeader bool   `json:"IsLeader"`
+			} `json:"members"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "node-1", response.Leader)
+		assert.Len(t, response.Members, 2)
+	})
+
+	t.Run("Propagates a lookup error as 500", func(t *testing.T) {
+		db := &internal.MockPgDBConnection{
+			QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+				return nil, assert.AnError
+			},
+		}
+
+		router := gin.New()
+		router.GET("/cluster/status", GetClusterStatusHandler(db, time.Minute))
+
+		req, _ := http.NewRequest(http.MethodGet, "/cluster/status", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
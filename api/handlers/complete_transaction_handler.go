@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/siddarth99/banking-ledger/pkg/errs"
+	"github.com/siddarth99/banking-ledger/pkg/pending"
+	"github.com/siddarth99/banking-ledger/pkg/resilientamqp"
+)
+
+// DefaultCompletePublishConfirmTimeout bounds how long CompleteTransactionHandler
+// waits for the broker to acknowledge a publish before treating the queue as
+// unavailable.
+const DefaultCompletePublishConfirmTimeout = 5 * time.Second
+
+// CompleteTransactionHandler approves a pending transaction request and
+// publishes it to the processing queue. It is the only path by which a
+// transaction actually reaches RabbitMQ.
+//
+// amqpClient is a resilientamqp.Client rather than a raw internal.AMQPChannel,
+// so a RabbitMQ restart is transparently survived instead of requiring a
+// service restart; the publish is sent with publisher confirms and this
+// handler waits up to confirmTimeout for the broker to ack it. A NACK and a
+// network/timeout failure are classified distinctly, both as errs.Unavailable
+// (the caller should retry) but under different codes, so an operator can
+// tell "the broker rejected this" apart from "the broker never responded".
+func CompleteTransactionHandler(ctx context.Context, pendingRequests *pending.Requests, amqpClient *resilientamqp.Client, queueName string, confirmTimeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transactionID := c.Param("id")
+		if transactionID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     "Transaction ID is required",
+			})
+			return
+		}
+
+		req, err := pendingRequests.Approve(ctx, transactionID)
+		if err != nil {
+			if err == pending.ErrNotFound {
+				c.JSON(http.StatusNotFound, gin.H{
+					"errorCode": http.StatusNotFound,
+					"error":     "Pending transaction not found",
+				})
+				return
+			}
+			c.JSON(http.StatusConflict, gin.H{
+				"errorCode": http.StatusConflict,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		if err := amqpClient.PublishJSON(ctx, "", queueName, json.RawMessage(req.Payload), confirmTimeout); err != nil {
+			switch {
+			case errors.Is(err, resilientamqp.ErrNacked):
+				c.Error(errs.Unavailable(err).WithCode("BANK.PUBLISH_NACKED"))
+			case errors.Is(err, resilientamqp.ErrPublishTimeout):
+				c.Error(errs.Unavailable(err).WithCode("BANK.PUBLISH_TIMEOUT"))
+			default:
+				c.Error(errs.Unavailable(err).WithCode("BANK.PUBLISH_FAILED"))
+			}
+			return
+		}
+
+		if _, err := pendingRequests.MarkInFlight(ctx, transactionID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"transactionID": transactionID,
+			"status":        pending.StateInFlight,
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/pending"
+	"github.com/siddarth99/banking-ledger/pkg/resilientamqp"
+)
+
+// newTestAMQPClient builds a resilientamqp.Client backed by mockChannel,
+// returning the channel a test can send confirmations/returns on to
+// simulate the broker acking or nacking a publish.
+func newTestAMQPClient(t *testing.T, mockChannel *internal.MockAMQPChannel) (*resilientamqp.Client, chan amqp.Confirmation) {
+	t.Helper()
+
+	confirms := make(chan amqp.Confirmation, 1)
+	returns := make(chan amqp.Return, 1)
+	closed := make(chan *amqp.Error)
+
+	mockChannel.On("Confirm", false).Return(nil)
+	mockChannel.On("NotifyPublish", mock.Anything).Return(confirms)
+	mockChannel.On("NotifyReturn", mock.Anything).Return(returns)
+	mockChannel.On("NotifyClose", mock.Anything).Return(closed)
+
+	mockConn := new(internal.MockAMQPConnection)
+	mockConn.On("Channel").Return(mockChannel, nil)
+	mockConn.On("NotifyClose", mock.Anything).Return(closed)
+	mockConn.On("Close").Return(nil)
+
+	client, err := resilientamqp.New(func() (internal.AMQPConnection, error) {
+		return mockConn, nil
+	}, time.Millisecond, 10*time.Millisecond)
+	assert.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client, confirms
+}
+
+func TestCompleteTransactionHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Approving a pending request publishes it", func(t *testing.T) {
+		pendingRequests := newTestPendingRequests()
+		_, err := pendingRequests.Add(context.Background(), "TX1", "ACC123456", []byte(`{}`))
+		assert.NoError(t, err)
+
+		mockChannel := new(internal.MockAMQPChannel)
+		amqpClient, confirms := newTestAMQPClient(t, mockChannel)
+		mockChannel.On("PublishWithContext",
+			mock.Anything, "", "transaction_queue", true, false, mock.Anything).
+			Run(func(mock.Arguments) { confirms <- amqp.Confirmation{Ack: true} }).
+			Return(nil)
+
+		req, _ := http.NewRequest(http.MethodPost, "/transactions/TX1/complete", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(ProblemJSON())
+		router.POST("/transactions/:id/complete", CompleteTransactionHandler(context.Background(), pendingRequests, amqpClient, "transaction_queue", DefaultCompletePublishConfirmTimeout))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		// resilientamqp's watch goroutine registers NotifyClose on its own
+		// schedule, independent of the publish this test just drove through
+		// - give it a moment to land before checking every expectation was
+		// met.
+		assert.Eventually(t, func() bool {
+			for _, call := range mockChannel.Calls {
+				if call.Method == "NotifyClose" {
+					return true
+				}
+			}
+			return false
+		}, time.Second, time.Millisecond, "watch goroutine never registered NotifyClose")
+		mockChannel.AssertExpectations(t)
+
+		stored, _ := pendingRequests.Get(context.Background(), "TX1")
+		assert.Equal(t, pending.StateInFlight, stored.State)
+	})
+
+	t.Run("Unknown transaction returns 404", func(t *testing.T) {
+		pendingRequests := newTestPendingRequests()
+		mockChannel := new(internal.MockAMQPChannel)
+		amqpClient, _ := newTestAMQPClient(t, mockChannel)
+
+		req, _ := http.NewRequest(http.MethodPost, "/transactions/missing/complete", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(ProblemJSON())
+		router.POST("/transactions/:id/complete", CompleteTransactionHandler(context.Background(), pendingRequests, amqpClient, "transaction_queue", DefaultCompletePublishConfirmTimeout))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockChannel.AssertNotCalled(t, "PublishWithContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Already discarded request cannot be completed", func(t *testing.T) {
+		pendingRequests := newTestPendingRequests()
+		_, err := pendingRequests.Add(context.Background(), "TX1", "ACC123456", []byte(`{}`))
+		assert.NoError(t, err)
+		_, err = pendingRequests.Discard(context.Background(), "TX1")
+		assert.NoError(t, err)
+
+		mockChannel := new(internal.MockAMQPChannel)
+		amqpClient, _ := newTestAMQPClient(t, mockChannel)
+
+		req, _ := http.NewRequest(http.MethodPost, "/transactions/TX1/complete", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(ProblemJSON())
+		router.POST("/transactions/:id/complete", CompleteTransactionHandler(context.Background(), pendingRequests, amqpClient, "transaction_queue", DefaultCompletePublishConfirmTimeout))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockChannel.AssertNotCalled(t, "PublishWithContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -3,22 +3,104 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
 	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/errs"
+	"github.com/siddarth99/banking-ledger/pkg/idempotency"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+	"github.com/siddarth99/banking-ledger/pkg/webhook"
 )
 
+// accountIdempotencyKeyHeader is the client-supplied header used to
+// de-duplicate retried account-creation requests.
+const accountIdempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultPublishConfirmTimeout bounds how long createAccount waits for the
+// broker to acknowledge a publish before treating the queue as unavailable.
+const DefaultPublishConfirmTimeout = 5 * time.Second
+
+// publishConfirmer serializes publishes on a channel already in confirm
+// mode: NotifyPublish/NotifyReturn only deliver one ack (or return) per
+// publish, in publish order, so mu must stay held across a publish and the
+// wait that follows it - otherwise a concurrent request's ack could be
+// mistaken for this one's.
+type publishConfirmer struct {
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+	timeout  time.Duration
+	mu       sync.Mutex
+}
+
+// newPublishConfirmer puts channel into confirm mode and registers for the
+// confirmations and returns publishConfirmer.await needs to tell an acked
+// publish from a dropped one. It panics if the channel can't be put into
+// confirm mode - publishing a ledger-affecting message with no delivery
+// guarantee at all isn't a condition CreateAccountHandler can run under.
+func newPublishConfirmer(channel internal.AMQPChannel, timeout time.Duration) *publishConfirmer {
+	if err := channel.Confirm(false); err != nil {
+		panic(fmt.Sprintf("failed to put AMQP channel into confirm mode: %s", err))
+	}
+	return &publishConfirmer{
+		confirms: channel.NotifyPublish(make(chan amqp.Confirmation, 1)),
+		returns:  channel.NotifyReturn(make(chan amqp.Return, 1)),
+		timeout:  timeout,
+	}
+}
+
+// await publishes body as a mandatory message on channel and blocks until
+// the broker acks it, returns it as unroutable, or c.timeout elapses -
+// whichever happens first - classifying every non-ack outcome as
+// errs.Unavailable so the client knows to retry rather than assume success.
+func (c *publishConfirmer) await(ctx context.Context, channel internal.AMQPChannel, exchange, key string, body []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := internal.PublishWithContext(ctx, body, channel, exchange, key, true, false); err != nil {
+		return errs.Internal(err).WithCode("BANK.PUBLISH_FAILED")
+	}
+
+	select {
+	case ret := <-c.returns:
+		return errs.Unavailable(fmt.Errorf("message returned as unroutable: %s", ret.ReplyText)).WithCode("BANK.PUBLISH_UNROUTABLE")
+	case conf := <-c.confirms:
+		if !conf.Ack {
+			return errs.Unavailable(fmt.Errorf("broker nacked publish")).WithCode("BANK.PUBLISH_NACKED")
+		}
+		return nil
+	case <-time.After(c.timeout):
+		return errs.Unavailable(fmt.Errorf("timed out waiting for publish confirmation")).WithCode("BANK.PUBLISH_TIMEOUT")
+	}
+}
+
 // AccountRequest represents the data structure for account creation requests
 // It contains all necessary fields required to create a new bank account
 type AccountRequest struct {
-	AccountHolderName string  `json:"accountHolderName" binding:"required"` // Name of the account holder
-	BranchCode        string  `json:"branchCode" binding:"required"`        // 3-character branch code
-	InitialDeposit    float64 `json:"initialDeposit" binding:"required"`    // Initial amount to deposit
-	ReferenceID       string  `json:"referenceID"`                          // Unique identifier for tracking
+	AccountHolderName string       `json:"accountHolderName" binding:"required"` // Name of the account holder
+	BranchCode        string       `json:"branchCode" binding:"required"`        // 3-character branch code
+	InitialDeposit    money.Amount `json:"initialDeposit"`                       // Initial amount to deposit
+	ReferenceID       string       `json:"referenceID"`                          // Unique identifier for tracking
+	// Callback, if present, registers a webhook subscription scoped to this
+	// request's ReferenceID: once the consumer finishes processing it,
+	// pkg/webhook delivers a signed COMPLETED/FAILED notification instead of
+	// forcing the client to poll GET /account/status/:referenceId.
+	Callback *Callback `json:"callback,omitempty"`
+}
+
+// Callback registers a webhook URL to notify once an asynchronous
+// account-creation request finishes. See pkg/webhook for the delivery and
+// signing details.
+type Callback struct {
+	URL    string   `json:"url" binding:"required"`
+	Secret string   `json:"secret" binding:"required"`
+	Events []string `json:"events" binding:"required"`
 }
 
 // accountResponse represents the response structure sent back to clients
@@ -29,9 +111,31 @@ type accountResponse struct {
 }
 
 // CreateAccountHandler creates a new HTTP handler for account creation requests
-// It takes a context, an AMQP channel, and a queue name for message publishing
+// It takes a context, an AMQP channel, and a queue name for message publishing.
+//
+// If the caller sends an Idempotency-Key header, idempotencyStore is used to
+// de-duplicate retries: a second request with the same key and the same
+// accountHolderName/branchCode/initialDeposit replays the original response
+// instead of queuing a second account-creation message. idempotencyStore may
+// be nil to disable de-duplication entirely.
+//
+// If the caller sets Callback, webhookStore registers it as a subscription
+// scoped to the generated ReferenceID. webhookStore may be nil to disable
+// inline callback registration entirely.
+//
+// amqpChannel is put into publisher-confirm mode at startup and every
+// publish is sent mandatory=true; the handler waits up to confirmTimeout for
+// the broker to ack it before responding, so a dropped or unroutable
+// message surfaces as a 503 instead of a false 200.
+//
+// Once the broker acks the publish, esClient records a StageEnqueued entry
+// for the generated ReferenceID so GetAccountStatusHandler can report it as
+// part of the request's stage timeline; esClient may be nil to disable that
+// recording entirely.
 // Returns a gin.HandlerFunc that can be registered with the router
-func CreateAccountHandler(ctx context.Context, amqpChannel internal.AMQPChannel, queueName string) gin.HandlerFunc {
+func CreateAccountHandler(ctx context.Context, amqpChannel internal.AMQPChannel, queueName string, esClient internal.ElasticsearchClient, idempotencyStore *idempotency.Store, webhookStore *webhook.Store, confirmTimeout time.Duration) gin.HandlerFunc {
+	confirmer := newPublishConfirmer(amqpChannel, confirmTimeout)
+
 	return func(c *gin.Context) {
 		var accountRequestJson AccountRequest
 		// Parse and validate the incoming JSON request
@@ -43,10 +147,34 @@ func CreateAccountHandler(ctx context.Context, amqpChannel internal.AMQPChannel,
 			return
 		}
 
+		// binding:"required" is a no-op on a struct-typed field, so
+		// InitialDeposit is checked by hand.
+		if accountRequestJson.InitialDeposit.IsZero() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     "initialDeposit is required",
+			})
+			return
+		}
+
 		// Process the account creation request
-		response, err := accountRequestJson.createAccount(ctx, amqpChannel, queueName)
+		response, err := accountRequestJson.createAccount(ctx, amqpChannel, queueName, esClient, idempotencyStore, c.GetHeader(accountIdempotencyKeyHeader), webhookStore, confirmer)
 
 		if err != nil {
+			var conflict *idempotency.ConflictError
+			if errors.As(err, &conflict) {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"errorCode": http.StatusUnprocessableEntity,
+					"error":     conflict.Error(),
+					"diff":      conflict.Diff(),
+				})
+				return
+			}
+			var e *errs.E
+			if errors.As(err, &e) {
+				c.Error(e)
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"errorCode": http.StatusInternalServerError,
 				"error":     err.Error(),
@@ -54,8 +182,10 @@ func CreateAccountHandler(ctx context.Context, amqpChannel internal.AMQPChannel,
 			return
 		}
 
-		// Return a successful response with tracking information
-		c.JSON(http.StatusAccepted, gin.H{
+		// The queue has acked the publish by this point, so the client gets a
+		// plain 200 rather than the 202 Accepted a fire-and-forget publish
+		// would warrant.
+		c.JSON(http.StatusOK, gin.H{
 			"referenceID": response.ReferenceID,
 			"createdAt":   response.CreatedAt,
 		})
@@ -63,13 +193,62 @@ func CreateAccountHandler(ctx context.Context, amqpChannel internal.AMQPChannel,
 }
 
 // createAccount processes an account creation request by:
-// 1. Generating a unique reference ID
-// 2. Publishing the request to a message queue for asynchronous processing
-// 3. Returning a response with tracking information
-func (a *AccountRequest) createAccount(ctx context.Context, amqpChannel internal.AMQPChannel, queueName string) (accountResponse, error) {
+//  1. Replaying the original response if idempotencyKey was already used for
+//     an identical request, or rejecting it with ConflictError if it wasn't
+//  2. Generating a unique reference ID
+//  3. Registering Callback, if set, as a webhook subscription scoped to that
+//     reference ID
+//  4. Publishing the request to a message queue for asynchronous processing,
+//     waiting for confirmer to report the publish landed if confirmer is set
+//  5. Returning a response with tracking information
+func (a *AccountRequest) createAccount(ctx context.Context, amqpChannel internal.AMQPChannel, queueName string, esClient internal.ElasticsearchClient, idempotencyStore *idempotency.Store, idempotencyKey string, webhookStore *webhook.Store, confirmer *publishConfirmer) (accountResponse, error) {
 	// Generate a unique reference ID for tracking this request
 	a.ReferenceID = uuid.New().String()
 
+	response := accountResponse{
+		ReferenceID: a.ReferenceID,
+		CreatedAt:   time.Now(),
+	}
+
+	if idempotencyKey != "" && idempotencyStore != nil {
+		responseJSON, err := json.Marshal(response)
+		if err != nil {
+			fmt.Printf("Error while marshalling idempotent response: %s", err.Error())
+			return accountResponse{}, err
+		}
+
+		existing, err := idempotencyStore.Reserve(ctx, idempotency.Record{
+			Key:           idempotencyKey,
+			AccountNumber: a.AccountHolderName,
+			Amount:        a.InitialDeposit,
+			Type:          a.BranchCode,
+			TransactionID: a.ReferenceID,
+			ResponseBody:  responseJSON,
+			CreatedAt:     response.CreatedAt,
+		})
+		if err != nil {
+			return accountResponse{}, err
+		}
+		if existing != nil {
+			var replay accountResponse
+			if err := json.Unmarshal(existing.ResponseBody, &replay); err != nil {
+				return accountResponse{}, err
+			}
+			return replay, nil
+		}
+	}
+
+	if a.Callback != nil && webhookStore != nil {
+		if _, err := webhookStore.Create(ctx, webhook.Subscription{
+			ReferenceID: a.ReferenceID,
+			URL:         a.Callback.URL,
+			Secret:      a.Callback.Secret,
+			Events:      a.Callback.Events,
+		}); err != nil {
+			return accountResponse{}, errs.Internal(err).WithCode("BANK.WEBHOOK_SUBSCRIPTION_FAILED")
+		}
+	}
+
 	// Create JSON payload
 	requestByteArray, err := json.Marshal(a)
 	if err != nil {
@@ -78,26 +257,35 @@ func (a *AccountRequest) createAccount(ctx context.Context, amqpChannel internal
 		return accountResponse{}, err
 	}
 
-	// Publish message to RabbitMQ for asynchronous processing
-	err = internal.PublishWithContext(
-		ctx,
-		requestByteArray,
-		amqpChannel,
-		"",        // default exchange
-		queueName, // routing key = queue name
-		false,     // mandatory - don't require the message to be routed to a queue
-		false,     // immediate - don't require immediate delivery to a consumer
-	)
+	// Publish message to RabbitMQ for asynchronous processing. With a
+	// confirmer, the publish is mandatory and this blocks for the broker's
+	// ack; without one (unit tests exercising createAccount directly) it
+	// falls back to the old fire-and-forget publish.
+	if confirmer != nil {
+		if err := confirmer.await(ctx, amqpChannel, "", queueName, requestByteArray); err != nil {
+			fmt.Printf("Error while Publishing account request to queue %s", err.Error())
+			return accountResponse{}, err
+		}
+	} else {
+		err = internal.PublishWithContext(
+			ctx,
+			requestByteArray,
+			amqpChannel,
+			"",        // default exchange
+			queueName, // routing key = queue name
+			false,     // mandatory - don't require the message to be routed to a queue
+			false,     // immediate - don't require immediate delivery to a consumer
+		)
 
-	if err != nil {
-		// Handle publishing error
-		fmt.Printf("Error while Publishing account request to queue %s", err.Error())
-		return accountResponse{}, err
+		if err != nil {
+			// Handle publishing error
+			fmt.Printf("Error while Publishing account request to queue %s", err.Error())
+			return accountResponse{}, errs.Internal(err).WithCode("BANK.PUBLISH_FAILED")
+		}
 	}
 
+	recordReferenceStage(ctx, esClient, amqpChannel, a.ReferenceID, StageEnqueued, nil)
+
 	// Return response with tracking ID and timestamp
-	return accountResponse{
-		ReferenceID: a.ReferenceID,
-		CreatedAt:   time.Now(),
-	}, nil
+	return response, nil
 }
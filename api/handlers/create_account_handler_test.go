@@ -11,11 +11,34 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	amqp "github.com/rabbitmq/amqp091-go"
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/idempotency"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+	"github.com/siddarth99/banking-ledger/pkg/webhook"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	internal "github.com/siddarth99/banking-ledger/pkg"
 )
 
+// testConfirmTimeout keeps the timeout subtests fast without having to wait
+// out DefaultPublishConfirmTimeout.
+const testConfirmTimeout = 50 * time.Millisecond
+
+// ackingChannel returns a MockAMQPChannel wired for publisher confirms whose
+// confirms channel immediately acks whatever gets published - the "happy
+// path" most subtests below don't care about beyond it not blocking.
+func ackingChannel() *internal.MockAMQPChannel {
+	mockChannel := new(internal.MockAMQPChannel)
+	confirms := make(chan amqp.Confirmation, 1)
+	confirms <- amqp.Confirmation{Ack: true}
+	mockChannel.On("Confirm", false).Return(nil)
+	mockChannel.On("NotifyPublish", mock.Anything).Return(confirms)
+	mockChannel.On("NotifyReturn", mock.Anything).Return(make(chan amqp.Return, 1))
+	return mockChannel
+}
+
 // Test for CreateAccountHandler
 func TestCreateAccountHandler(t *testing.T) {
 	// Set Gin to test mode
@@ -23,15 +46,17 @@ func TestCreateAccountHandler(t *testing.T) {
 
 	t.Run("Valid request should return 200", func(t *testing.T) {
 		// Setup mock channel
-		mockChannel := new(internal.MockAMQPChannel)
+		mockChannel := ackingChannel()
 		mockChannel.On("PublishWithContext",
-			mock.Anything, "", "account_queue", false, false, mock.Anything).Return(nil)
+			mock.Anything, "", "account_queue", true, false, mock.Anything).Return(nil)
 
 		// Create a test request
+		initialDeposit, err := money.FromString("1000.00", "USD")
+		assert.NoError(t, err)
 		validRequest := AccountRequest{
 			AccountHolderName: "John Doe",
 			BranchCode:        "ABC",
-			InitialDeposit:    1000.00,
+			InitialDeposit:    initialDeposit,
 		}
 		jsonValue, _ := json.Marshal(validRequest)
 		req, _ := http.NewRequest(http.MethodPost, "/accounts", bytes.NewBuffer(jsonValue))
@@ -42,7 +67,7 @@ func TestCreateAccountHandler(t *testing.T) {
 
 		// Setup router
 		router := gin.Default()
-		router.POST("/accounts", CreateAccountHandler(context.Background(), mockChannel, "account_queue"))
+		router.POST("/accounts", CreateAccountHandler(context.Background(), mockChannel, "account_queue", nil, nil, nil, testConfirmTimeout))
 		router.ServeHTTP(w, req)
 
 		// Assert response
@@ -50,7 +75,7 @@ func TestCreateAccountHandler(t *testing.T) {
 
 		// Verify response contains expected fields
 		var response map[string]interface{}
-		err := json.Unmarshal(w.Body.Bytes(), &response)
+		err = json.Unmarshal(w.Body.Bytes(), &response)
 		assert.NoError(t, err)
 		assert.Contains(t, response, "referenceID")
 		assert.Contains(t, response, "createdAt")
@@ -61,12 +86,14 @@ func TestCreateAccountHandler(t *testing.T) {
 
 	t.Run("Invalid request should return 400", func(t *testing.T) {
 		// Setup mock channel
-		mockChannel := new(internal.MockAMQPChannel)
+		mockChannel := ackingChannel()
 
 		// Create an invalid request (missing required fields)
+		initialDeposit, err := money.FromString("1000.00", "USD")
+		assert.NoError(t, err)
 		invalidRequest := AccountRequest{
 			// Missing AccountHolderName and BranchCode
-			InitialDeposit: 1000.00,
+			InitialDeposit: initialDeposit,
 		}
 		jsonValue, _ := json.Marshal(invalidRequest)
 		req, _ := http.NewRequest(http.MethodPost, "/accounts", bytes.NewBuffer(jsonValue))
@@ -77,7 +104,7 @@ func TestCreateAccountHandler(t *testing.T) {
 
 		// Setup router
 		router := gin.Default()
-		router.POST("/accounts", CreateAccountHandler(context.Background(), mockChannel, "account_queue"))
+		router.POST("/accounts", CreateAccountHandler(context.Background(), mockChannel, "account_queue", nil, nil, nil, testConfirmTimeout))
 		router.ServeHTTP(w, req)
 
 		// Assert response
@@ -89,15 +116,17 @@ func TestCreateAccountHandler(t *testing.T) {
 
 	t.Run("Publishing error should return 500", func(t *testing.T) {
 		// Setup mock channel that returns an error
-		mockChannel := new(internal.MockAMQPChannel)
+		mockChannel := ackingChannel()
 		mockChannel.On("PublishWithContext",
-			mock.Anything, "", "account_queue", false, false, mock.Anything).Return(errors.New("publish error"))
+			mock.Anything, "", "account_queue", true, false, mock.Anything).Return(errors.New("publish error"))
 
 		// Create a valid request
+		initialDeposit, err := money.FromString("1000.00", "USD")
+		assert.NoError(t, err)
 		validRequest := AccountRequest{
 			AccountHolderName: "John Doe",
 			BranchCode:        "ABC",
-			InitialDeposit:    1000.00,
+			InitialDeposit:    initialDeposit,
 		}
 		jsonValue, _ := json.Marshal(validRequest)
 		req, _ := http.NewRequest(http.MethodPost, "/accounts", bytes.NewBuffer(jsonValue))
@@ -108,15 +137,179 @@ func TestCreateAccountHandler(t *testing.T) {
 
 		// Setup router
 		router := gin.Default()
-		router.POST("/accounts", CreateAccountHandler(context.Background(), mockChannel, "account_queue"))
+		router.Use(ProblemJSON())
+		router.POST("/accounts", CreateAccountHandler(context.Background(), mockChannel, "account_queue", nil, nil, nil, testConfirmTimeout))
 		router.ServeHTTP(w, req)
 
 		// Assert response
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 
+		var problem Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "BANK.PUBLISH_FAILED", problem.Code)
+
 		// Verify our mock was called as expected
 		mockChannel.AssertExpectations(t)
 	})
+
+	t.Run("Idempotency-Key reused with a different payload returns 422 with a diff", func(t *testing.T) {
+		mockChannel := ackingChannel()
+
+		storedDeposit, err := money.FromString("1000.00", "USD")
+		assert.NoError(t, err)
+		storedHash := idempotency.Fingerprint("retry-key", "John Doe", storedDeposit, "ABC")
+
+		db := &internal.MockPgDBConnection{
+			BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+				return &internal.MockPgDBConnection{
+					ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+						return pgconn.NewCommandTag("INSERT 0 0"), nil
+					},
+					QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+						return &internal.MockPgxRow{
+							ScanFunc: func(dest ...interface{}) error {
+								*dest[0].(*string) = storedHash
+								*dest[1].(*string) = "John Doe"
+								*dest[2].(*money.Amount) = storedDeposit
+								*dest[3].(*string) = "USD"
+								*dest[4].(*string) = "ABC"
+								*dest[5].(*string) = "original-ref"
+								*dest[6].(*[]byte) = []byte(`{"referenceID":"original-ref"}`)
+								*dest[7].(*time.Time) = time.Now()
+								*dest[8].(*time.Time) = time.Now().Add(time.Hour)
+								return nil
+							},
+						}
+					},
+				}, nil
+			},
+		}
+		idempotencyStore := idempotency.NewStore(db, time.Hour)
+
+		retriedDeposit, err := money.FromString("2500.00", "USD") // differs from the payload the key was first used with
+		assert.NoError(t, err)
+		retriedRequest := AccountRequest{
+			AccountHolderName: "John Doe",
+			BranchCode:        "ABC",
+			InitialDeposit:    retriedDeposit,
+		}
+		jsonValue, _ := json.Marshal(retriedRequest)
+		req, _ := http.NewRequest(http.MethodPost, "/accounts", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "retry-key")
+
+		w := httptest.NewRecorder()
+		router := gin.Default()
+		router.POST("/accounts", CreateAccountHandler(context.Background(), mockChannel, "account_queue", nil, idempotencyStore, nil, testConfirmTimeout))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		diff, ok := response["diff"].(map[string]interface{})
+		assert.True(t, ok)
+		assert.Contains(t, diff, "amount")
+
+		mockChannel.AssertNotCalled(t, "PublishWithContext")
+	})
+
+	t.Run("Broker nack on publish returns 503", func(t *testing.T) {
+		mockChannel := new(internal.MockAMQPChannel)
+		confirms := make(chan amqp.Confirmation, 1)
+		confirms <- amqp.Confirmation{Ack: false}
+		mockChannel.On("Confirm", false).Return(nil)
+		mockChannel.On("NotifyPublish", mock.Anything).Return(confirms)
+		mockChannel.On("NotifyReturn", mock.Anything).Return(make(chan amqp.Return, 1))
+		mockChannel.On("PublishWithContext",
+			mock.Anything, "", "account_queue", true, false, mock.Anything).Return(nil)
+
+		initialDeposit, err := money.FromString("1000.00", "USD")
+		assert.NoError(t, err)
+		validRequest := AccountRequest{
+			AccountHolderName: "John Doe",
+			BranchCode:        "ABC",
+			InitialDeposit:    initialDeposit,
+		}
+		jsonValue, _ := json.Marshal(validRequest)
+		req, _ := http.NewRequest(http.MethodPost, "/accounts", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(ProblemJSON())
+		router.POST("/accounts", CreateAccountHandler(context.Background(), mockChannel, "account_queue", nil, nil, nil, testConfirmTimeout))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		var problem Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "BANK.PUBLISH_NACKED", problem.Code)
+	})
+
+	t.Run("Mandatory publish returned as unroutable returns 503", func(t *testing.T) {
+		mockChannel := new(internal.MockAMQPChannel)
+		returns := make(chan amqp.Return, 1)
+		returns <- amqp.Return{ReplyText: "NO_ROUTE"}
+		mockChannel.On("Confirm", false).Return(nil)
+		mockChannel.On("NotifyPublish", mock.Anything).Return(make(chan amqp.Confirmation, 1))
+		mockChannel.On("NotifyReturn", mock.Anything).Return(returns)
+		mockChannel.On("PublishWithContext",
+			mock.Anything, "", "account_queue", true, false, mock.Anything).Return(nil)
+
+		initialDeposit, err := money.FromString("1000.00", "USD")
+		assert.NoError(t, err)
+		validRequest := AccountRequest{
+			AccountHolderName: "John Doe",
+			BranchCode:        "ABC",
+			InitialDeposit:    initialDeposit,
+		}
+		jsonValue, _ := json.Marshal(validRequest)
+		req, _ := http.NewRequest(http.MethodPost, "/accounts", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(ProblemJSON())
+		router.POST("/accounts", CreateAccountHandler(context.Background(), mockChannel, "account_queue", nil, nil, nil, testConfirmTimeout))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		var problem Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "BANK.PUBLISH_UNROUTABLE", problem.Code)
+	})
+
+	t.Run("No confirmation before the timeout returns 503", func(t *testing.T) {
+		mockChannel := new(internal.MockAMQPChannel)
+		mockChannel.On("Confirm", false).Return(nil)
+		mockChannel.On("NotifyPublish", mock.Anything).Return(make(chan amqp.Confirmation, 1))
+		mockChannel.On("NotifyReturn", mock.Anything).Return(make(chan amqp.Return, 1))
+		mockChannel.On("PublishWithContext",
+			mock.Anything, "", "account_queue", true, false, mock.Anything).Return(nil)
+
+		initialDeposit, err := money.FromString("1000.00", "USD")
+		assert.NoError(t, err)
+		validRequest := AccountRequest{
+			AccountHolderName: "John Doe",
+			BranchCode:        "ABC",
+			InitialDeposit:    initialDeposit,
+		}
+		jsonValue, _ := json.Marshal(validRequest)
+		req, _ := http.NewRequest(http.MethodPost, "/accounts", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router := gin.Default()
+		router.Use(ProblemJSON())
+		router.POST("/accounts", CreateAccountHandler(context.Background(), mockChannel, "account_queue", nil, nil, nil, testConfirmTimeout))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		var problem Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "BANK.PUBLISH_TIMEOUT", problem.Code)
+	})
 }
 
 // Test for createAccount method
@@ -128,14 +321,16 @@ func TestCreateAccount(t *testing.T) {
 			mock.Anything, "", "test_queue", false, false, mock.Anything).Return(nil)
 
 		// Create request
+		initialDeposit, err := money.FromString("500.00", "USD")
+		assert.NoError(t, err)
 		req := &AccountRequest{
 			AccountHolderName: "Jane Doe",
 			BranchCode:        "XYZ",
-			InitialDeposit:    500.00,
+			InitialDeposit:    initialDeposit,
 		}
 
 		// Call method
-		resp, err := req.createAccount(context.Background(), mockChannel, "test_queue")
+		resp, err := req.createAccount(context.Background(), mockChannel, "test_queue", nil, nil, "", nil, nil)
 
 		// Assert no error
 		assert.NoError(t, err)
@@ -156,14 +351,16 @@ func TestCreateAccount(t *testing.T) {
 			mock.Anything, "", "test_queue", false, false, mock.Anything).Return(errors.New("publish error"))
 
 		// Create request
+		initialDeposit, err := money.FromString("500.00", "USD")
+		assert.NoError(t, err)
 		req := &AccountRequest{
 			AccountHolderName: "Jane Doe",
 			BranchCode:        "XYZ",
-			InitialDeposit:    500.00,
+			InitialDeposit:    initialDeposit,
 		}
 
 		// Call method
-		resp, err := req.createAccount(context.Background(), mockChannel, "test_queue")
+		resp, err := req.createAccount(context.Background(), mockChannel, "test_queue", nil, nil, "", nil, nil)
 
 		// Assert error
 		assert.Error(t, err)
@@ -176,4 +373,148 @@ func TestCreateAccount(t *testing.T) {
 		// Verify our mock was called as expected
 		mockChannel.AssertExpectations(t)
 	})
+
+	t.Run("Retried request with the same Idempotency-Key replays the original response", func(t *testing.T) {
+		mockChannel := new(internal.MockAMQPChannel)
+		mockChannel.On("PublishWithContext",
+			mock.Anything, "", "test_queue", false, false, mock.Anything).Return(nil)
+
+		initialDeposit, err := money.FromString("750.00", "USD")
+		assert.NoError(t, err)
+
+		var storedHash string
+		var storedResponse []byte
+		db := &internal.MockPgDBConnection{
+			BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+				return &internal.MockPgDBConnection{
+					ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+						if storedHash == "" {
+							storedHash = arguments[1].(string)
+							storedResponse = arguments[7].([]byte)
+							return pgconn.NewCommandTag("INSERT 0 1"), nil
+						}
+						return pgconn.NewCommandTag("INSERT 0 0"), nil
+					},
+					QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+						return &internal.MockPgxRow{
+							ScanFunc: func(dest ...interface{}) error {
+								*dest[0].(*string) = storedHash
+								*dest[1].(*string) = "Jane Doe"
+								*dest[2].(*money.Amount) = initialDeposit
+								*dest[3].(*string) = "USD"
+								*dest[4].(*string) = "XYZ"
+								*dest[5].(*string) = "keep-this-ref"
+								*dest[6].(*[]byte) = storedResponse
+								*dest[7].(*time.Time) = time.Now()
+								*dest[8].(*time.Time) = time.Now().Add(time.Hour)
+								return nil
+							},
+						}
+					},
+				}, nil
+			},
+		}
+		idempotencyStore := idempotency.NewStore(db, time.Hour)
+
+		req := &AccountRequest{
+			AccountHolderName: "Jane Doe",
+			BranchCode:        "XYZ",
+			InitialDeposit:    initialDeposit,
+			ReferenceID:       "keep-this-ref",
+		}
+		first, err := req.createAccount(context.Background(), mockChannel, "test_queue", nil, idempotencyStore, "idem-key-1", nil, nil)
+		assert.NoError(t, err)
+
+		retry := &AccountRequest{
+			AccountHolderName: "Jane Doe",
+			BranchCode:        "XYZ",
+			InitialDeposit:    initialDeposit,
+		}
+		second, err := retry.createAccount(context.Background(), mockChannel, "test_queue", nil, idempotencyStore, "idem-key-1", nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, first.ReferenceID, second.ReferenceID)
+	})
+
+	t.Run("Callback registers a webhook subscription scoped to the reference ID", func(t *testing.T) {
+		mockChannel := new(internal.MockAMQPChannel)
+		mockChannel.On("PublishWithContext",
+			mock.Anything, "", "test_queue", false, false, mock.Anything).Return(nil)
+
+		var registeredReferenceID, registeredURL string
+		db := &internal.MockPgDBConnection{
+			ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+				registeredReferenceID = arguments[1].(string)
+				registeredURL = arguments[2].(string)
+				return pgconn.NewCommandTag("INSERT 0 1"), nil
+			},
+		}
+		webhookStore := webhook.NewStore(db)
+
+		initialDeposit, err := money.FromString("500.00", "USD")
+		assert.NoError(t, err)
+		req := &AccountRequest{
+			AccountHolderName: "Jane Doe",
+			BranchCode:        "XYZ",
+			InitialDeposit:    initialDeposit,
+			Callback: &Callback{
+				URL:    "https://example.com/webhook",
+				Secret: "shh",
+				Events: []string{"account.completed", "account.failed"},
+			},
+		}
+
+		resp, err := req.createAccount(context.Background(), mockChannel, "test_queue", nil, nil, "", webhookStore, nil)
+		assert.NoError(t, err)
+
+		assert.Equal(t, resp.ReferenceID, registeredReferenceID)
+		assert.Equal(t, "https://example.com/webhook", registeredURL)
+		mockChannel.AssertExpectations(t)
+	})
+
+	t.Run("Reusing an Idempotency-Key with a different payload returns a conflict", func(t *testing.T) {
+		mockChannel := new(internal.MockAMQPChannel)
+
+		storedDeposit, err := money.FromString("750.00", "USD")
+		assert.NoError(t, err)
+		storedHash := idempotency.Fingerprint("idem-key-2", "Jane Doe", storedDeposit, "XYZ")
+
+		db := &internal.MockPgDBConnection{
+			BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+				return &internal.MockPgDBConnection{
+					ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+						return pgconn.NewCommandTag("INSERT 0 0"), nil
+					},
+					QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+						return &internal.MockPgxRow{
+							ScanFunc: func(dest ...interface{}) error {
+								*dest[0].(*string) = storedHash
+								*dest[1].(*string) = "Jane Doe"
+								*dest[2].(*money.Amount) = storedDeposit
+								*dest[3].(*string) = "USD"
+								*dest[4].(*string) = "XYZ"
+								*dest[5].(*string) = "original-ref"
+								*dest[6].(*[]byte) = []byte(`{"referenceID":"original-ref"}`)
+								*dest[7].(*time.Time) = time.Now()
+								*dest[8].(*time.Time) = time.Now().Add(time.Hour)
+								return nil
+							},
+						}
+					},
+				}, nil
+			},
+		}
+		idempotencyStore := idempotency.NewStore(db, time.Hour)
+
+		differentDeposit, err := money.FromString("9999.00", "USD") // differs from the stored reservation
+		assert.NoError(t, err)
+		req := &AccountRequest{
+			AccountHolderName: "Jane Doe",
+			BranchCode:        "XYZ",
+			InitialDeposit:    differentDeposit,
+		}
+		_, err = req.createAccount(context.Background(), mockChannel, "test_queue", nil, idempotencyStore, "idem-key-2", nil, nil)
+
+		var conflict *idempotency.ConflictError
+		assert.ErrorAs(t, err, &conflict)
+	})
 }
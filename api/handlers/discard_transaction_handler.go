@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/siddarth99/banking-ledger/pkg/pending"
+)
+
+// DiscardTransactionHandler marks a pending transaction request as
+// DISCARDED. A discarded request is terminal and is never published to the
+// processing queue.
+func DiscardTransactionHandler(ctx context.Context, pendingRequests *pending.Requests) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transactionID := c.Param("id")
+		if transactionID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     "Transaction ID is required",
+			})
+			return
+		}
+
+		req, err := pendingRequests.Discard(ctx, transactionID)
+		if err != nil {
+			if err == pending.ErrNotFound {
+				c.JSON(http.StatusNotFound, gin.H{
+					"errorCode": http.StatusNotFound,
+					"error":     "Pending transaction not found",
+				})
+				return
+			}
+			c.JSON(http.StatusConflict, gin.H{
+				"errorCode": http.StatusConflict,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"transactionID": transactionID,
+			"status":        req.State,
+		})
+	}
+}
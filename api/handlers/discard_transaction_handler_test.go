@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/siddarth99/banking-ledger/pkg/pending"
+)
+
+func TestDiscardTransactionHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Discarding a pending request returns 200", func(t *testing.T) {
+		pendingRequests := newTestPendingRequests()
+		_, err := pendingRequests.Add(context.Background(), "TX1", "ACC123456", []byte(`{}`))
+		assert.NoError(t, err)
+
+		req, _ := http.NewRequest(http.MethodPost, "/transactions/TX1/discard", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.POST("/transactions/:id/discard", DiscardTransactionHandler(context.Background(), pendingRequests))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		stored, _ := pendingRequests.Get(context.Background(), "TX1")
+		assert.Equal(t, pending.StateDiscarded, stored.State)
+	})
+
+	t.Run("Unknown transaction returns 404", func(t *testing.T) {
+		pendingRequests := newTestPendingRequests()
+
+		req, _ := http.NewRequest(http.MethodPost, "/transactions/missing/discard", nil)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.POST("/transactions/:id/discard", DiscardTransactionHandler(context.Background(), pendingRequests))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/siddarth99/banking-ledger/pkg/esresilience"
+)
+
+// GetElasticsearchHealthHandler reports the circuit-breaker state esresilience
+// is tracking for the shared Elasticsearch client, so an operator can tell a
+// downed cluster apart from a slow one without digging through logs. The
+// response is 200 while the breaker is closed or half-open (probing), and 503
+// while it's open and rejecting calls outright.
+func GetElasticsearchHealthHandler(client *esresilience.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		health := client.Health()
+
+		status := http.StatusOK
+		if health.State == esresilience.StateOpen {
+			status = http.StatusServiceUnavailable
+		}
+
+		c.JSON(status, health)
+	}
+}
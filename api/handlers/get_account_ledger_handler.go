@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+)
+
+const defaultLedgerPageSize = 20
+const maxLedgerPageSize = 100
+
+// LedgerEntry is a single immutable posting against an account, as written
+// to ledger_entries by the transaction processor.
+type LedgerEntry struct {
+	TransactionID string       `json:"transactionId"`
+	AccountNumber string       `json:"accountNumber"`
+	Direction     string       `json:"direction"`
+	Amount        money.Amount `json:"amount"`
+	BalanceAfter  money.Amount `json:"balanceAfter"`
+	BranchCode    string       `json:"branchCode"`
+	CreatedAt     time.Time    `json:"createdAt"`
+}
+
+// LedgerPage is a single page of an account's ledger history, newest first.
+type LedgerPage struct {
+	AccountNumber string        `json:"accountNumber"`
+	Entries       []LedgerEntry `json:"entries"`
+	NextCursor    string        `json:"nextCursor,omitempty"`
+}
+
+// GetAccountLedgerHandler pages over the immutable ledger_entries written
+// by TransactionProcessor, ordered newest first. Callers can narrow the
+// window with from/to (RFC3339 timestamps) and page with cursor, the
+// opaque value returned as nextCursor on the previous page.
+func GetAccountLedgerHandler(db internal.PgDBConnection) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accountNumber := c.Param("accountNumber")
+		if accountNumber == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     "Account number is required",
+			})
+			return
+		}
+
+		limit := defaultLedgerPageSize
+		if limitParam := c.Query("limit"); limitParam != "" {
+			if parsed, err := strconv.Atoi(limitParam); err == nil && parsed > 0 {
+				limit = parsed
+			}
+		}
+		if limit > maxLedgerPageSize {
+			limit = maxLedgerPageSize
+		}
+
+		var since, until time.Time
+		if fromParam := c.Query("from"); fromParam != "" {
+			parsed, err := time.Parse(time.RFC3339, fromParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"errorCode": http.StatusBadRequest,
+					"error":     "from must be an RFC3339 timestamp",
+				})
+				return
+			}
+			since = parsed
+		}
+		if toParam := c.Query("to"); toParam != "" {
+			parsed, err := time.Parse(time.RFC3339, toParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"errorCode": http.StatusBadRequest,
+					"error":     "to must be an RFC3339 timestamp",
+				})
+				return
+			}
+			until = parsed
+		}
+
+		var cursorCreatedAt time.Time
+		var cursorTransactionID string
+		if cursorParam := c.Query("cursor"); cursorParam != "" {
+			var err error
+			cursorCreatedAt, cursorTransactionID, err = decodeLedgerCursor(cursorParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"errorCode": http.StatusBadRequest,
+					"error":     "Invalid cursor",
+				})
+				return
+			}
+		}
+
+		query, args := buildLedgerQuery(accountNumber, since, until, cursorCreatedAt, cursorTransactionID, limit)
+
+		rows, err := db.Query(c.Request.Context(), query, args...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     "Failed to query ledger entries",
+			})
+			return
+		}
+		defer rows.Close()
+
+		entries := make([]LedgerEntry, 0, limit)
+		for rows.Next() {
+			var entry LedgerEntry
+			var currency string
+			if err := rows.Scan(
+				&entry.TransactionID,
+				&entry.AccountNumber,
+				&entry.Direction,
+				&entry.Amount,
+				&entry.BalanceAfter,
+				&entry.BranchCode,
+				&currency,
+				&entry.CreatedAt,
+			); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"errorCode": http.StatusInternalServerError,
+					"error":     "Failed to read ledger entries",
+				})
+				return
+			}
+			entry.Amount = entry.Amount.WithCurrency(currency)
+			entry.BalanceAfter = entry.BalanceAfter.WithCurrency(currency)
+			entries = append(entries, entry)
+		}
+		if err := rows.Err(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     "Failed to read ledger entries",
+			})
+			return
+		}
+
+		page := LedgerPage{
+			AccountNumber: accountNumber,
+			Entries:       entries,
+		}
+		if len(entries) == limit {
+			last := entries[len(entries)-1]
+			page.NextCursor = encodeLedgerCursor(last.CreatedAt, last.TransactionID)
+		}
+
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// buildLedgerQuery constructs a keyset-paginated SELECT over ledger_entries,
+// ordered newest first by (created_at, transaction_id).
+func buildLedgerQuery(accountNumber string, since, until, cursorCreatedAt time.Time, cursorTransactionID string, limit int) (string, []any) {
+	var b strings.Builder
+	args := []any{accountNumber}
+	b.WriteString(`SELECT transaction_id, account_number, direction, amount, balance_after, branch_code, currency, created_at
+		FROM ledger_entries WHERE account_number = $1`)
+
+	if !since.IsZero() {
+		args = append(args, since)
+		fmt.Fprintf(&b, " AND created_at >= $%d", len(args))
+	}
+	if !until.IsZero() {
+		args = append(args, until)
+		fmt.Fprintf(&b, " AND created_at <= $%d", len(args))
+	}
+	if cursorTransactionID != "" {
+		args = append(args, cursorCreatedAt, cursorTransactionID)
+		fmt.Fprintf(&b, " AND (created_at, transaction_id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	args = append(args, limit)
+	fmt.Fprintf(&b, " ORDER BY created_at DESC, transaction_id DESC LIMIT $%d", len(args))
+
+	return b.String(), args
+}
+
+func encodeLedgerCursor(createdAt time.Time, transactionID string) string {
+	raw := fmt.Sprintf("%s|%s", createdAt.Format(time.RFC3339Nano), transactionID)
+	return base64.URLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeLedgerCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, "", fmt.Errorf("malformed cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	return createdAt, parts[1], nil
+}
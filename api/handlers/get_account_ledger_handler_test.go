@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+)
+
+func TestGetAccountLedgerHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Returns entries for the account, newest first", func(t *testing.T) {
+		createdAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		entryAmount, err := money.FromString("500.00", "USD")
+		assert.NoError(t, err)
+		balanceAfter, err := money.FromString("1500.00", "USD")
+		assert.NoError(t, err)
+		row := 0
+		rows := &internal.MockPgxRows{
+			NextFunc: func() bool {
+				row++
+				return row <= 1
+			},
+			ScanFunc: func(dest ...interface{}) error {
+				*dest[0].(*string) = "TX1"
+				*dest[1].(*string) = "ACC123456"
+				*dest[2].(*string) = "CREDIT"
+				*dest[3].(*money.Amount) = entryAmount
+				*dest[4].(*money.Amount) = balanceAfter
+				*dest[5].(*string) = "BR001"
+				*dest[6].(*string) = "USD"
+				*dest[7].(*time.Time) = createdAt
+				return nil
+			},
+		}
+
+		mockDB := &internal.MockPgDBConnection{
+			QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+				assert.Equal(t, "ACC123456", args[0])
+				return rows, nil
+			},
+		}
+
+		router := gin.New()
+		router.GET("/accounts/:accountNumber/ledger", GetAccountLedgerHandler(mockDB))
+
+		req, _ := http.NewRequest(http.MethodGet, "/accounts/ACC123456/ledger", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var page LedgerPage
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, "ACC123456", page.AccountNumber)
+		assert.Len(t, page.Entries, 1)
+		assert.Equal(t, "TX1", page.Entries[0].TransactionID)
+		assert.Empty(t, page.NextCursor) // fewer entries than the page size
+	})
+
+	t.Run("Missing account number", func(t *testing.T) {
+		mockDB := &internal.MockPgDBConnection{}
+
+		router := gin.New()
+		router.GET("/accounts/:accountNumber/ledger", GetAccountLedgerHandler(mockDB))
+
+		req, _ := http.NewRequest(http.MethodGet, "/accounts//ledger", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Invalid cursor is rejected", func(t *testing.T) {
+		mockDB := &internal.MockPgDBConnection{}
+
+		router := gin.New()
+		router.GET("/accounts/:accountNumber/ledger", GetAccountLedgerHandler(mockDB))
+
+		req, _ := http.NewRequest(http.MethodGet, "/accounts/ACC123456/ledger?cursor=not-base64!!", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -3,10 +3,13 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/errs"
 )
 
 // AccountStatusResponse represents the response structure for account status
@@ -15,25 +18,47 @@ type AccountStatusResponse struct {
 	Status        string `json:"status"`
 	AccountNumber string `json:"accountNumber,omitempty"`
 	Message       string `json:"message,omitempty"`
+	ErrorCode     string `json:"errorCode,omitempty"`
+	ErrorMessage  string `json:"errorMessage,omitempty"`
+	// Stages is the reference ID's processing timeline, oldest first, as
+	// recorded to bank-reference-status-*. It's omitted if no stage
+	// documents have been recorded yet.
+	Stages []ReferenceStatusStage `json:"stages,omitempty"`
 }
 
-// GetAccountStatusHandler handles requests to check account creation status
-func GetAccountStatusHandler(esClient internal.ElasticsearchClient) gin.HandlerFunc {
+// DefaultStatusCacheSize is used when the caller does not configure a
+// specific Elasticsearch status-lookup cache size.
+const DefaultStatusCacheSize = 1000
+
+// GetAccountStatusHandler handles requests to check account creation status.
+// statusCache fronts repeated lookups for the same reference ID so polling
+// clients don't repeatedly round-trip to Elasticsearch.
+func GetAccountStatusHandler(esClient internal.ElasticsearchClient, statusCache *internal.LRUCache[string, AccountStatusResponse]) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		referenceID := c.Param("referenceId")
 		if referenceID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"errorCode": http.StatusBadRequest,
-				"error":     "Reference ID is required",
-			})
+			c.Error(errs.InvalidArgument(errors.New("reference ID is required")).WithCode("BANK.REFERENCE_ID_REQUIRED"))
 			return
 		}
 
-		// Construct Elasticsearch query
+		if statusCache != nil {
+			if cached, ok := statusCache.Get(referenceID); ok && cached.Status == "ACTIVE" {
+				c.JSON(http.StatusOK, cached)
+				return
+			}
+		}
+
+		// Construct Elasticsearch query. referenceID may be either the
+		// transaction ID or the client's Idempotency-Key, since a retried
+		// request only ever has the latter to poll with.
 		query := map[string]interface{}{
 			"query": map[string]interface{}{
-				"match": map[string]interface{}{
-					"transaction_id": referenceID,
+				"bool": map[string]interface{}{
+					"should": []map[string]interface{}{
+						{"match": map[string]interface{}{"transaction_id": referenceID}},
+						{"match": map[string]interface{}{"idempotency_key": referenceID}},
+					},
+					"minimum_should_match": 1,
 				},
 			},
 		}
@@ -41,49 +66,46 @@ func GetAccountStatusHandler(esClient internal.ElasticsearchClient) gin.HandlerF
 		// Convert query to bytes
 		var buf bytes.Buffer
 		if err := json.NewEncoder(&buf).Encode(query); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"errorCode": http.StatusInternalServerError,
-				"error":     "Failed to construct search query",
-			})
+			c.Error(errs.Internal(fmt.Errorf("construct search query: %w", err)).WithCode("BANK.SEARCH_QUERY_FAILED"))
 			return
 		}
 
 		// Search in Elasticsearch
 		res, err := esClient.Search([]string{"bank-transactions-*"}, &buf)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"errorCode": http.StatusInternalServerError,
-				"error":     "Failed to search account status",
-			})
+			c.Error(errs.Unavailable(fmt.Errorf("search account status: %w", err)).WithCode("BANK.ES_UNAVAILABLE"))
 			return
 		}
 		defer res.Body.Close()
 
 		if res.IsError() {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"errorCode": http.StatusInternalServerError,
-				"error":     "Error response from Elasticsearch",
-			})
+			c.Error(errs.Unavailable(errors.New("error response from Elasticsearch")).WithCode("BANK.ES_UNAVAILABLE"))
 			return
 		}
 
 		// Parse the response
 		var result map[string]interface{}
 		if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"errorCode": http.StatusInternalServerError,
-				"error":     "Failed to parse search results",
-			})
+			c.Error(errs.Internal(fmt.Errorf("parse search results: %w", err)).WithCode("BANK.SEARCH_RESULTS_UNPARSEABLE"))
 			return
 		}
 
 		// Check if any hits were found
 		hits := result["hits"].(map[string]interface{})
 		if hits["total"].(map[string]interface{})["value"].(float64) == 0 {
-			c.JSON(http.StatusNotFound, gin.H{
-				"errorCode": http.StatusNotFound,
-				"error":     "Account creation request not found",
-			})
+			// No final transaction document yet doesn't necessarily mean the
+			// reference ID is unknown - it may still be working through the
+			// queue. Fall back to the stage timeline before giving up.
+			stages, err := lookupReferenceStages(esClient, referenceID)
+			if err == nil && len(stages) > 0 {
+				c.JSON(http.StatusOK, AccountStatusResponse{
+					ReferenceID: referenceID,
+					Status:      "PROCESSING",
+					Stages:      stages,
+				})
+				return
+			}
+			c.Error(errs.NotFound(errors.New("account creation request not found")).WithCode("BANK.ACCOUNT_CREATION_REQUEST_NOT_FOUND"))
 			return
 		}
 
@@ -112,6 +134,23 @@ func GetAccountStatusHandler(esClient internal.ElasticsearchClient) gin.HandlerF
 			response.Message = message
 		}
 
+		// Surface the structured failure reason when the transaction didn't
+		// complete successfully.
+		if response.Status != "ACTIVE" {
+			if transactionError, err := lookupTransactionError(esClient, referenceID); err == nil && transactionError != nil {
+				response.ErrorCode = transactionError.ErrorCode
+				response.ErrorMessage = transactionError.ErrorMessage
+			}
+		}
+
+		if stages, err := lookupReferenceStages(esClient, referenceID); err == nil {
+			response.Stages = stages
+		}
+
+		if statusCache != nil && response.Status == "ACTIVE" {
+			statusCache.Put(referenceID, response)
+		}
+
 		c.JSON(http.StatusOK, response)
 	}
 }
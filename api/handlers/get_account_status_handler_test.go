@@ -1,4 +1,4 @@
-package handlers_test
+package handlers
 
 import (
 	"bytes"
@@ -7,12 +7,11 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/gin-gonic/gin"
-	"github.com/siddarth99/banking-ledger/api/handlers"
-	internal "github.com/siddarth99/banking-ledger/pkg"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -24,7 +23,7 @@ func TestGetAccountStatusHandler(t *testing.T) {
 	// Test case: Successful retrieval of account status (ACTIVE)
 	t.Run("Success response with ACTIVE status", func(t *testing.T) {
 
-		mockClient := new(internal.MockElasticsearchClient)
+		mockClient := new(MockElasticsearchClient)
 
 		// Create sample Elasticsearch response
 		responseBody := map[string]interface{}{
@@ -60,13 +59,24 @@ func TestGetAccountStatusHandler(t *testing.T) {
 				if err := json.NewDecoder(reader).Decode(&query); err != nil {
 					return false
 				}
-				match, ok := query["query"].(map[string]interface{})["match"].(map[string]interface{})
+				should, ok := query["query"].(map[string]interface{})["bool"].(map[string]interface{})["should"].([]interface{})
+				if !ok || len(should) == 0 {
+					return false
+				}
+				match, ok := should[0].(map[string]interface{})["match"].(map[string]interface{})
 				return ok && match["transaction_id"] == "ref-123"
 			})).Return(mockResponse, nil)
 
+		// A successful lookup also pulls the reference ID's stage timeline.
+		noStagesResponse := &esapi.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"hits":{"total":{"value":0},"hits":[]}}`)),
+		}
+		mockClient.On("Search", []string{"bank-reference-status-*"}, mock.Anything).Return(noStagesResponse, nil)
+
 		// Setup router
 		router := gin.New()
-		router.GET("/account/status/:referenceId", handlers.GetAccountStatusHandler(mockClient))
+		router.GET("/account/status/:referenceId", GetAccountStatusHandler(mockClient, nil))
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/account/status/ref-123", nil)
@@ -76,7 +86,7 @@ func TestGetAccountStatusHandler(t *testing.T) {
 		// Verify response
 		assert.Equal(t, http.StatusOK, w.Code)
 
-		var response handlers.AccountStatusResponse
+		var response AccountStatusResponse
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 		assert.NoError(t, err)
 
@@ -92,7 +102,7 @@ func TestGetAccountStatusHandler(t *testing.T) {
 	// Test case: Successful retrieval of account status (INACTIVE)
 	t.Run("Success response with INACTIVE status", func(t *testing.T) {
 		// Create mock client
-		mockClient := new(internal.MockElasticsearchClient)
+		mockClient := new(MockElasticsearchClient)
 
 		// Create sample Elasticsearch response
 		responseBody := map[string]interface{}{
@@ -126,13 +136,37 @@ func TestGetAccountStatusHandler(t *testing.T) {
 				if err := json.NewDecoder(reader).Decode(&query); err != nil {
 					return false
 				}
-				match, ok := query["query"].(map[string]interface{})["match"].(map[string]interface{})
+				should, ok := query["query"].(map[string]interface{})["bool"].(map[string]interface{})["should"].([]interface{})
+				if !ok || len(should) == 0 {
+					return false
+				}
+				match, ok := should[0].(map[string]interface{})["match"].(map[string]interface{})
 				return ok && match["transaction_id"] == "ref-456"
 			})).Return(mockResponse, nil)
 
+		// An INACTIVE status also triggers a lookup against the error index.
+		noErrorResponseJSON, _ := json.Marshal(map[string]interface{}{
+			"hits": map[string]interface{}{
+				"total": map[string]interface{}{"value": 0},
+				"hits":  []map[string]interface{}{},
+			},
+		})
+		noErrorResponse := &esapi.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewReader(noErrorResponseJSON)),
+		}
+		mockClient.On("Search", []string{"bank-transaction-errors-*"}, mock.Anything).Return(noErrorResponse, nil)
+
+		// ...and the reference ID's stage timeline, same as the ACTIVE path.
+		noStagesResponse := &esapi.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(`{"hits":{"total":{"value":0},"hits":[]}}`)),
+		}
+		mockClient.On("Search", []string{"bank-reference-status-*"}, mock.Anything).Return(noStagesResponse, nil)
+
 		// Setup router
 		router := gin.New()
-		router.GET("/account/status/:referenceId", handlers.GetAccountStatusHandler(mockClient))
+		router.GET("/account/status/:referenceId", GetAccountStatusHandler(mockClient, nil))
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/account/status/ref-456", nil)
@@ -142,7 +176,7 @@ func TestGetAccountStatusHandler(t *testing.T) {
 		// Verify response
 		assert.Equal(t, http.StatusOK, w.Code)
 
-		var response handlers.AccountStatusResponse
+		var response AccountStatusResponse
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 		assert.NoError(t, err)
 
@@ -157,10 +191,10 @@ func TestGetAccountStatusHandler(t *testing.T) {
 
 	// Test case: Empty reference ID parameter
 	t.Run("Missing reference ID", func(t *testing.T) {
-		mockClient := new(internal.MockElasticsearchClient)
+		mockClient := new(MockElasticsearchClient)
 
 		router := gin.New()
-		router.GET("/account/status/:referenceId", handlers.GetAccountStatusHandler(mockClient))
+		router.GET("/account/status/:referenceId", GetAccountStatusHandler(mockClient, nil))
 
 		// Create request without reference ID
 		req, _ := http.NewRequest("GET", "/account/status/", nil)
@@ -176,13 +210,14 @@ func TestGetAccountStatusHandler(t *testing.T) {
 
 	// Test case: Elasticsearch error
 	t.Run("Elasticsearch client error", func(t *testing.T) {
-		mockClient := new(internal.MockElasticsearchClient)
+		mockClient := new(MockElasticsearchClient)
 
 		// Setup mock to return error
 		mockClient.On("Search", mock.Anything, mock.Anything).Return(nil, errors.New("connection refused"))
 
 		router := gin.New()
-		router.GET("/account/status/:referenceId", handlers.GetAccountStatusHandler(mockClient))
+		router.Use(ProblemJSON())
+		router.GET("/account/status/:referenceId", GetAccountStatusHandler(mockClient, nil))
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/account/status/ref-123", nil)
@@ -190,13 +225,11 @@ func TestGetAccountStatusHandler(t *testing.T) {
 		router.ServeHTTP(w, req)
 
 		// Verify response
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 
-		var response map[string]interface{}
-		err := json.Unmarshal(w.Body.Bytes(), &response)
-		assert.NoError(t, err)
-		assert.Equal(t, float64(http.StatusInternalServerError), response["errorCode"])
-		assert.Contains(t, response["error"].(string), "Failed to search account status")
+		var problem Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "BANK.ES_UNAVAILABLE", problem.Code)
 
 		// Verify expectations
 		mockClient.AssertExpectations(t)
@@ -204,7 +237,7 @@ func TestGetAccountStatusHandler(t *testing.T) {
 
 	// Test case: Reference ID not found
 	t.Run("Reference ID not found", func(t *testing.T) {
-		mockClient := new(internal.MockElasticsearchClient)
+		mockClient := new(MockElasticsearchClient)
 
 		// Create empty response
 		responseBody := map[string]interface{}{
@@ -225,7 +258,8 @@ func TestGetAccountStatusHandler(t *testing.T) {
 		mockClient.On("Search", mock.Anything, mock.Anything).Return(mockResponse, nil)
 
 		router := gin.New()
-		router.GET("/account/status/:referenceId", handlers.GetAccountStatusHandler(mockClient))
+		router.Use(ProblemJSON())
+		router.GET("/account/status/:referenceId", GetAccountStatusHandler(mockClient, nil))
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/account/status/unknown-ref", nil)
@@ -235,11 +269,9 @@ func TestGetAccountStatusHandler(t *testing.T) {
 		// Verify response
 		assert.Equal(t, http.StatusNotFound, w.Code)
 
-		var response map[string]interface{}
-		err := json.Unmarshal(w.Body.Bytes(), &response)
-		assert.NoError(t, err)
-		assert.Equal(t, float64(http.StatusNotFound), response["errorCode"])
-		assert.Contains(t, response["error"].(string), "Account creation request not found")
+		var problem Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "BANK.ACCOUNT_CREATION_REQUEST_NOT_FOUND", problem.Code)
 
 		// Verify expectations
 		mockClient.AssertExpectations(t)
@@ -247,7 +279,7 @@ func TestGetAccountStatusHandler(t *testing.T) {
 
 	// Test case: Elasticsearch error response
 	t.Run("Elasticsearch error response", func(t *testing.T) {
-		mockClient := new(internal.MockElasticsearchClient)
+		mockClient := new(MockElasticsearchClient)
 
 		// Create error response
 		mockResponse := &esapi.Response{
@@ -259,7 +291,8 @@ func TestGetAccountStatusHandler(t *testing.T) {
 		mockClient.On("Search", mock.Anything, mock.Anything).Return(mockResponse, nil)
 
 		router := gin.New()
-		router.GET("/account/status/:referenceId", handlers.GetAccountStatusHandler(mockClient))
+		router.Use(ProblemJSON())
+		router.GET("/account/status/:referenceId", GetAccountStatusHandler(mockClient, nil))
 
 		// Create request
 		req, _ := http.NewRequest("GET", "/account/status/ref-123", nil)
@@ -267,13 +300,11 @@ func TestGetAccountStatusHandler(t *testing.T) {
 		router.ServeHTTP(w, req)
 
 		// Verify response
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 
-		var response map[string]interface{}
-		err := json.Unmarshal(w.Body.Bytes(), &response)
-		assert.NoError(t, err)
-		assert.Equal(t, float64(http.StatusInternalServerError), response["errorCode"])
-		assert.Contains(t, response["error"].(string), "Error response from Elasticsearch")
+		var problem Problem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, "BANK.ES_UNAVAILABLE", problem.Code)
 
 		// Verify expectations
 		mockClient.AssertExpectations(t)
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/ledger"
+)
+
+const selectLedgerProofQuery = `
+	SELECT seq, payload, prev_hash, hash
+	FROM transaction_log
+	WHERE transaction_id = $1
+	ORDER BY seq ASC
+`
+
+// LedgerProofLink is one transaction_log row for the requested transaction,
+// plus whether its own hash actually matches prevHash and payload - an
+// auditor can recompute SHA256(prevHash || canonical_json(payload)) and
+// compare it against Hash without trusting this service at all.
+type LedgerProofLink struct {
+	Seq      int64  `json:"seq"`
+	PrevHash string `json:"prevHash"`
+	Hash     string `json:"hash"`
+	Valid    bool   `json:"valid"`
+}
+
+// LedgerProof is the hash path returned for a transactionId: every
+// transaction_log row written for it, in chain order.
+type LedgerProof struct {
+	TransactionID string            `json:"transactionId"`
+	Links         []LedgerProofLink `json:"links"`
+}
+
+// GetLedgerProofHandler returns the transaction_log row(s) chained for
+// transactionId, so an auditor can independently verify that none of them
+// were altered after the fact without needing to walk the whole ledger via
+// ledger.Verify.
+func GetLedgerProofHandler(db internal.PgDBConnection) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		transactionID := c.Param("transactionId")
+		if transactionID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     "Transaction ID is required",
+			})
+			return
+		}
+
+		rows, err := db.Query(c.Request.Context(), selectLedgerProofQuery, transactionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     "Failed to query transaction log",
+			})
+			return
+		}
+		defer rows.Close()
+
+		links := make([]LedgerProofLink, 0)
+		for rows.Next() {
+			var seq int64
+			var payload, prevHash, hash []byte
+			if err := rows.Scan(&seq, &payload, &prevHash, &hash); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"errorCode": http.StatusInternalServerError,
+					"error":     "Failed to read transaction log",
+				})
+				return
+			}
+
+			canonical, err := ledger.CanonicalJSON(payload)
+			valid := err == nil && bytes.Equal(hash, ledger.ComputeHash(prevHash, canonical))
+
+			links = append(links, LedgerProofLink{
+				Seq:      seq,
+				PrevHash: hex.EncodeToString(prevHash),
+				Hash:     hex.EncodeToString(hash),
+				Valid:    valid,
+			})
+		}
+		if err := rows.Err(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     "Failed to read transaction log",
+			})
+			return
+		}
+
+		if len(links) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{
+				"errorCode": http.StatusNotFound,
+				"error":     "No transaction log entry for this transaction ID",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, LedgerProof{TransactionID: transactionID, Links: links})
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/ledger"
+)
+
+func TestGetLedgerProofHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Returns a verifiable link for the transaction", func(t *testing.T) {
+		payload := []byte(`{"transaction_id":"TX1"}`)
+		canonical, err := ledger.CanonicalJSON(payload)
+		assert.NoError(t, err)
+		hash := ledger.ComputeHash(nil, canonical)
+
+		row := 0
+		rows := &internal.MockPgxRows{
+			NextFunc: func() bool {
+				row++
+				return row <= 1
+			},
+			ScanFunc: func(dest ...interface{}) error {
+				*dest[0].(*int64) = 1
+				*dest[1].(*[]byte) = payload
+				*dest[2].(*[]byte) = nil
+				*dest[3].(*[]byte) = hash
+				return nil
+			},
+		}
+
+		mockDB := &internal.MockPgDBConnection{
+			QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+				assert.Equal(t, "TX1", args[0])
+				return rows, nil
+			},
+		}
+
+		router := gin.New()
+		router.GET("/ledger/proof/:transactionId", GetLedgerProofHandler(mockDB))
+
+		req, _ := http.NewRequest(http.MethodGet, "/ledger/proof/TX1", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var proof LedgerProof
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &proof))
+		assert.Equal(t, "TX1", proof.TransactionID)
+		if assert.Len(t, proof.Links, 1) {
+			assert.Equal(t, int64(1), proof.Links[0].Seq)
+			assert.True(t, proof.Links[0].Valid)
+		}
+	})
+
+	t.Run("Tampered payload is reported as invalid", func(t *testing.T) {
+		hash := ledger.ComputeHash(nil, []byte(`{"transaction_id":"TX1"}`))
+
+		row := 0
+		rows := &internal.MockPgxRows{
+			NextFunc: func() bool {
+				row++
+				return row <= 1
+			},
+			ScanFunc: func(dest ...interface{}) error {
+				*dest[0].(*int64) = 1
+				*dest[1].(*[]byte) = []byte(`{"transaction_id":"TX1","amount":"999999.00"}`)
+				*dest[2].(*[]byte) = nil
+				*dest[3].(*[]byte) = hash
+				return nil
+			},
+		}
+
+		mockDB := &internal.MockPgDBConnection{
+			QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+				return rows, nil
+			},
+		}
+
+		router := gin.New()
+		router.GET("/ledger/proof/:transactionId", GetLedgerProofHandler(mockDB))
+
+		req, _ := http.NewRequest(http.MethodGet, "/ledger/proof/TX1", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var proof LedgerProof
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &proof))
+		if assert.Len(t, proof.Links, 1) {
+			assert.False(t, proof.Links[0].Valid)
+		}
+	})
+
+	t.Run("No transaction log entries for the transaction ID", func(t *testing.T) {
+		mockDB := &internal.MockPgDBConnection{
+			QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+				return &internal.MockPgxRows{NextFunc: func() bool { return false }}, nil
+			},
+		}
+
+		router := gin.New()
+		router.GET("/ledger/proof/:transactionId", GetLedgerProofHandler(mockDB))
+
+		req, _ := http.NewRequest(http.MethodGet, "/ledger/proof/TX-UNKNOWN", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
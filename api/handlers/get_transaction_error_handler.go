@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// TransactionErrorResponse represents the structured failure reason for a
+// transaction, looked up from the bank-transaction-errors-* index.
+type TransactionErrorResponse struct {
+	TransactionID string `json:"transactionID"`
+	ErrorCode     string `json:"errorCode"`
+	ErrorMessage  string `json:"errorMessage"`
+	Stage         string `json:"stage"`
+	Retryable     bool   `json:"retryable"`
+}
+
+// GetTransactionErrorHandler looks up the structured error recorded for a
+// failed transaction.
+func GetTransactionErrorHandler(esClient internal.ElasticsearchClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		referenceID := c.Param("referenceId")
+		if referenceID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     "Reference ID is required",
+			})
+			return
+		}
+
+		response, err := lookupTransactionError(esClient, referenceID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     "Failed to look up transaction error: " + err.Error(),
+			})
+			return
+		}
+		if response == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"errorCode": http.StatusNotFound,
+				"error":     "No recorded error for this transaction",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// lookupTransactionError queries bank-transaction-errors-* for the most
+// recent error recorded against transactionID. It returns (nil, nil) when no
+// error document exists.
+func lookupTransactionError(esClient internal.ElasticsearchClient, transactionID string) (*TransactionErrorResponse, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"match": map[string]interface{}{
+				"transaction_id": transactionID,
+			},
+		},
+		"sort": []map[string]interface{}{
+			{"occurred_at": map[string]interface{}{"order": "desc"}},
+		},
+		"size": 1,
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, err
+	}
+
+	res, err := esClient.Search([]string{"bank-transaction-errors-*"}, &buf)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, nil
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source struct {
+					TransactionID string `json:"transaction_id"`
+					ErrorCode     string `json:"error_code"`
+					ErrorMessage  string `json:"error_message"`
+					Stage         string `json:"stage"`
+					Retryable     bool   `json:"retryable"`
+				} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Hits.Hits) == 0 {
+		return nil, nil
+	}
+
+	source := result.Hits.Hits[0].Source
+	return &TransactionErrorResponse{
+		TransactionID: source.TransactionID,
+		ErrorCode:     source.ErrorCode,
+		ErrorMessage:  source.ErrorMessage,
+		Stage:         source.Stage,
+		Retryable:     source.Retryable,
+	}, nil
+}
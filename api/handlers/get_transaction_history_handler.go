@@ -5,45 +5,55 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/gin-gonic/gin"
 	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/esquery"
+	"github.com/siddarth99/banking-ledger/pkg/money"
 )
 
 // TransactionHistoryItem represents a single transaction in the history
 type TransactionHistoryItem struct {
-	TransactionID           string    `json:"id"`
-	Amount                  float64   `json:"amount"`
-	TransactionType         string    `json:"type"`
-	Status                  string    `json:"status"`
-	Timestamp               time.Time `json:"timestamp"`
-	BalanceAfterTransaction float64   `json:"updatedBalance"`
-	Description             string    `json:"description,omitempty"`
+	TransactionID           string       `json:"id"`
+	Amount                  money.Amount `json:"amount"`
+	TransactionType         string       `json:"type"`
+	Status                  string       `json:"status"`
+	Timestamp               time.Time    `json:"timestamp"`
+	BalanceAfterTransaction money.Amount `json:"updatedBalance"`
+	Description             string       `json:"description,omitempty"`
 }
 
 // EsResponseItem represents a single document in the Elasticsearch response
 type EsResponseItem struct {
-	TransactionID           string    `json:"transaction_id"`
-	AccountNumber           string    `json:"account_number"`
-	Amount                  float64   `json:"amount"`
-	TransactionType         string    `json:"type"`
-	Status                  string    `json:"status"`
-	Description             string    `json:"description,omitempty"`
-	Timestamp               time.Time `json:"timestamp"`
-	BranchCode              string    `json:"branch_code"`
-	BalanceAfterTransaction float64   `json:"balance_after_transaction"`
+	TransactionID           string       `json:"transaction_id"`
+	AccountNumber           string       `json:"account_number"`
+	Amount                  money.Amount `json:"amount"`
+	TransactionType         string       `json:"type"`
+	Status                  string       `json:"status"`
+	Description             string       `json:"description,omitempty"`
+	Timestamp               time.Time    `json:"timestamp"`
+	BranchCode              string       `json:"branch_code"`
+	BalanceAfterTransaction money.Amount `json:"balance_after_transaction"`
 }
 
 // TransactionHistoryResponse represents the response structure for transaction history
 type TransactionHistoryResponse struct {
-	AccountNumber string                   `json:"accountNumber"`
-	Transactions  []TransactionHistoryItem `json:"transactions"`
-	TotalCount    int                      `json:"totalCount"`
-	CurrentPage   int                      `json:"currentPage"`
+	AccountNumber string                                  `json:"accountNumber"`
+	Transactions  []TransactionHistoryItem                `json:"transactions"`
+	NextCursor    []interface{}                           `json:"nextCursor,omitempty"`
+	Aggregations  *esquery.TransactionHistoryAggregations `json:"aggregations,omitempty"`
 }
 
-// GetTransactionHistoryHandler returns a handler for querying transaction history
+// GetTransactionHistoryHandler returns a handler for querying transaction
+// history: account_number plus optional from/to/type/status/min_amount/
+// max_amount filters, paginated with search_after (via the opaque
+// search_after query param "search_after=<timestamp>,<id>") rather than
+// from/size so deep pagination never runs into Elasticsearch's 10,000-hit
+// window. Passing aggregations=1 issues a second query and populates
+// TransactionHistoryResponse.Aggregations with daily credit/debit sums.
 func GetTransactionHistoryHandler(esClient internal.ElasticsearchClient) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		accountNumber := c.Param("accountNumber")
@@ -55,124 +65,152 @@ func GetTransactionHistoryHandler(esClient internal.ElasticsearchClient) gin.Han
 			return
 		}
 
-		// Parse query parameters
-		page := 1
-		if pageParam := c.Query("page"); pageParam != "" {
-			fmt.Sscanf(pageParam, "%d", &page)
-			if page < 1 {
-				page = 1
-			}
+		cursor, err := parseSearchAfterCursor(c.Query("search_after"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     err.Error(),
+			})
+			return
 		}
 
-		limit := 10
-
-		// Calculate offset
-		from := (page - 1) * limit
-
-		// Build Elasticsearch query
-		query := map[string]interface{}{
-			"query": map[string]interface{}{
-				"match": map[string]interface{}{
-					"account_number": accountNumber,
-				},
-			},
-			"sort": []map[string]interface{}{
-				{
-					"timestamp": map[string]interface{}{
-						"order": "desc", // Most recent transactions first
-					},
-				},
-			},
-			"from": from,
-			"size": limit,
+		filters := esquery.TransactionHistoryFilters{
+			AccountNumber: accountNumber,
+			From:          c.Query("from"),
+			To:            c.Query("to"),
+			Type:          c.Query("type"),
+			Status:        c.Query("status"),
+			MinAmount:     c.Query("min_amount"),
+			MaxAmount:     c.Query("max_amount"),
+			Cursor:        cursor,
 		}
 
-		// Convert query to JSON
-		var buf bytes.Buffer
-		if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		transactions, nextCursor, err := searchTransactionHistory(esClient, filters)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"errorCode": http.StatusInternalServerError,
-				"error":     "Failed to build search query",
+				"error":     err.Error(),
 			})
 			return
 		}
 
-		// Perform the search request
-		res, err := esClient.Search(
-			[]string{"bank-transactions-*"},
-			&buf,
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"errorCode": http.StatusInternalServerError,
-				"error":     "Failed to search transaction history: " + err.Error(),
-			})
-			return
+		response := TransactionHistoryResponse{
+			AccountNumber: accountNumber,
+			Transactions:  transactions,
+			NextCursor:    nextCursor,
 		}
-		defer res.Body.Close()
 
-		// Check for Elasticsearch errors
-		if res.IsError() {
-			var e map[string]interface{}
-			if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+		if c.Query("aggregations") == "1" {
+			aggregations, err := aggregateTransactionHistory(esClient, filters)
+			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"errorCode": http.StatusInternalServerError,
-					"error":     "Failed to parse error response from Elasticsearch",
+					"error":     err.Error(),
 				})
 				return
 			}
-			// Return the Elasticsearch error
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"errorCode": http.StatusInternalServerError,
-				"error":     fmt.Sprintf("[%s] %s: %s", res.Status(), e["error"].(map[string]interface{})["type"], e["error"].(map[string]interface{})["reason"]),
-			})
-			return
+			response.Aggregations = aggregations
 		}
 
-		// Parse the response
-		var esResponse struct {
-			Hits struct {
-				Total struct {
-					Value int `json:"value"`
-				} `json:"total"`
-				Hits []struct {
-					Source EsResponseItem `json:"_source"`
-				} `json:"hits"`
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// parseSearchAfterCursor parses a "search_after=<timestamp>,<id>" query
+// param into the two-element sort-value cursor BuildTransactionHistoryQuery
+// expects. An empty raw value is the first page, not an error.
+func parseSearchAfterCursor(raw string) ([]interface{}, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	timestamp, id, ok := strings.Cut(raw, ",")
+	if !ok || timestamp == "" || id == "" {
+		return nil, fmt.Errorf("search_after must be \"<timestamp>,<id>\"")
+	}
+	return []interface{}{timestamp, id}, nil
+}
+
+// searchTransactionHistory runs a BuildTransactionHistoryQuery against
+// esClient and decodes the hits, returning the last hit's sort values as
+// the next page's cursor.
+func searchTransactionHistory(esClient internal.ElasticsearchClient, filters esquery.TransactionHistoryFilters) ([]TransactionHistoryItem, []interface{}, error) {
+	query := esquery.BuildTransactionHistoryQuery(filters)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, nil, fmt.Errorf("failed to build search query: %w", err)
+	}
+
+	res, err := esClient.Search([]string{"bank-transactions-*"}, &buf)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to search transaction history: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, nil, esResponseError(res)
+	}
+
+	var esResponse struct {
+		Hits struct {
+			Hits []struct {
+				Source EsResponseItem `json:"_source"`
+				Sort   []interface{}  `json:"sort"`
 			} `json:"hits"`
-		}
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&esResponse); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse search results: %w", err)
+	}
 
-		if err := json.NewDecoder(res.Body).Decode(&esResponse); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"errorCode": http.StatusInternalServerError,
-				"error":     "Failed to parse search results",
-			})
-			return
-		}
+	transactions := make([]TransactionHistoryItem, 0, len(esResponse.Hits.Hits))
+	var nextCursor []interface{}
+	for _, hit := range esResponse.Hits.Hits {
+		transactions = append(transactions, TransactionHistoryItem{
+			TransactionID:           hit.Source.TransactionID,
+			Amount:                  hit.Source.Amount,
+			TransactionType:         hit.Source.TransactionType,
+			Status:                  hit.Source.Status,
+			Timestamp:               hit.Source.Timestamp,
+			BalanceAfterTransaction: hit.Source.BalanceAfterTransaction,
+			Description:             hit.Source.Description,
+		})
+		nextCursor = hit.Sort
+	}
 
-		// Extract transactions from the response
-		transactions := make([]TransactionHistoryItem, 0, len(esResponse.Hits.Hits))
-		for _, hit := range esResponse.Hits.Hits {
-			transactionHistoryItem := TransactionHistoryItem{
-				TransactionID:           hit.Source.TransactionID,
-				Amount:                  hit.Source.Amount,
-				TransactionType:         hit.Source.TransactionType,
-				Status:                  hit.Source.Status,
-				Timestamp:               hit.Source.Timestamp,
-				BalanceAfterTransaction: hit.Source.BalanceAfterTransaction,
-				Description:             hit.Source.Description,
-			}
+	return transactions, nextCursor, nil
+}
 
-			transactions = append(transactions, transactionHistoryItem)
-		}
+// aggregateTransactionHistory runs a
+// BuildTransactionHistoryAggregationQuery against esClient and parses the
+// result.
+func aggregateTransactionHistory(esClient internal.ElasticsearchClient, filters esquery.TransactionHistoryFilters) (*esquery.TransactionHistoryAggregations, error) {
+	query := esquery.BuildTransactionHistoryAggregationQuery(filters)
 
-		// Build the response
-		response := TransactionHistoryResponse{
-			AccountNumber: accountNumber,
-			Transactions:  transactions,
-			TotalCount:    esResponse.Hits.Total.Value,
-			CurrentPage:   page,
-		}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, fmt.Errorf("failed to build aggregation query: %w", err)
+	}
 
-		c.JSON(http.StatusOK, response)
+	res, err := esClient.Search([]string{"bank-transactions-*"}, &buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate transaction history: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, esResponseError(res)
+	}
+
+	return esquery.ParseTransactionHistoryAggregations(res.Body)
+}
+
+// esResponseError decodes an Elasticsearch error response body into a
+// descriptive error.
+func esResponseError(res *esapi.Response) error {
+	var e map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+		return fmt.Errorf("failed to parse error response from Elasticsearch")
 	}
+	return fmt.Errorf("[%s] %s: %s", res.Status(), e["error"].(map[string]interface{})["type"], e["error"].(map[string]interface{})["reason"])
 }
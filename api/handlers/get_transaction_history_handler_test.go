@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -39,6 +38,14 @@ func (m *MockElasticsearchClient) Index(index string, body io.Reader) (*esapi.Re
 	return args.Get(0).(*esapi.Response), args.Error(1)
 }
 
+func (m *MockElasticsearchClient) Bulk(body io.Reader) (*esapi.Response, error) {
+	args := m.Called(body)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*esapi.Response), args.Error(1)
+}
+
 func (m *MockElasticsearchClient) Info() (*esapi.Response, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
@@ -61,31 +68,30 @@ func TestGetTransactionHistoryHandler(t *testing.T) {
 		timestamp := time.Now()
 		responseBody := map[string]interface{}{
 			"hits": map[string]interface{}{
-				"total": map[string]interface{}{
-					"value": 2,
-				},
 				"hits": []map[string]interface{}{
 					{
 						"_source": map[string]interface{}{
 							"transaction_id":            "tx123",
-							"amount":                    100.50,
+							"amount":                    map[string]interface{}{"value": "100.50", "currency": "USD"},
 							"type":                      "credit",
 							"status":                    "completed",
 							"timestamp":                 timestamp,
-							"balance_after_transaction": 500.75,
+							"balance_after_transaction": map[string]interface{}{"value": "500.75", "currency": "USD"},
 							"description":               "Salary deposit",
 						},
+						"sort": []interface{}{timestamp.Format(time.RFC3339), "tx123"},
 					},
 					{
 						"_source": map[string]interface{}{
 							"transaction_id":            "tx456",
-							"amount":                    50.25,
+							"amount":                    map[string]interface{}{"value": "50.25", "currency": "USD"},
 							"type":                      "debit",
 							"status":                    "completed",
 							"timestamp":                 timestamp.Add(-24 * time.Hour),
-							"balance_after_transaction": 400.25,
+							"balance_after_transaction": map[string]interface{}{"value": "400.25", "currency": "USD"},
 							"description":               "ATM withdrawal",
 						},
+						"sort": []interface{}{timestamp.Add(-24 * time.Hour).Format(time.RFC3339), "tx456"},
 					},
 				},
 			},
@@ -106,8 +112,9 @@ func TestGetTransactionHistoryHandler(t *testing.T) {
 				if err := json.NewDecoder(buf).Decode(&query); err != nil {
 					return false
 				}
-				match, ok := query["query"].(map[string]interface{})["match"].(map[string]interface{})
-				return ok && match["account_number"] == "ACC123"
+				filters := query["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"].([]interface{})
+				match := filters[0].(map[string]interface{})["match"].(map[string]interface{})
+				return match["account_number"] == "ACC123"
 			})).Return(mockResponse, nil)
 
 		// Setup router
@@ -122,25 +129,21 @@ func TestGetTransactionHistoryHandler(t *testing.T) {
 		// Verify response
 		assert.Equal(t, http.StatusOK, w.Code)
 
-
 		var response TransactionHistoryResponse
 		err := json.Unmarshal(w.Body.Bytes(), &response)
 		assert.NoError(t, err)
-		
-		fmt.Println(response)
 
 		// Verify response data
 		assert.Equal(t, "ACC123", response.AccountNumber)
-		assert.Equal(t, 2, response.TotalCount)
-		assert.Equal(t, 1, response.CurrentPage)
 		assert.Len(t, response.Transactions, 2)
+		assert.Equal(t, []interface{}{timestamp.Add(-24 * time.Hour).Format(time.RFC3339), "tx456"}, response.NextCursor)
 
 		// Verify first transaction details
 		assert.Equal(t, "tx123", response.Transactions[0].TransactionID)
-		assert.Equal(t, 100.50, response.Transactions[0].Amount)
+		assert.Equal(t, "100.50", response.Transactions[0].Amount.String())
 		assert.Equal(t, "credit", response.Transactions[0].TransactionType)
 		assert.Equal(t, "completed", response.Transactions[0].Status)
-		assert.Equal(t, 500.75, response.Transactions[0].BalanceAfterTransaction)
+		assert.Equal(t, "500.75", response.Transactions[0].BalanceAfterTransaction.String())
 		assert.Equal(t, "Salary deposit", response.Transactions[0].Description)
 
 		// Verify second transaction details
@@ -244,18 +247,12 @@ func TestGetTransactionHistoryHandler(t *testing.T) {
 		mockClient.AssertExpectations(t)
 	})
 
-	// Test case: Pagination parameter
-	t.Run("Pagination works correctly", func(t *testing.T) {
+	// Test case: search_after cursor is forwarded to the query
+	t.Run("search_after cursor is forwarded to the query", func(t *testing.T) {
 		mockClient := new(MockElasticsearchClient)
 
-		// Create empty response
 		responseBody := map[string]interface{}{
-			"hits": map[string]interface{}{
-				"total": map[string]interface{}{
-					"value": 30, // Total of 30 items
-				},
-				"hits": []map[string]interface{}{}, // Empty list for this test
-			},
+			"hits": map[string]interface{}{"hits": []map[string]interface{}{}},
 		}
 		responseJSON, _ := json.Marshal(responseBody)
 		mockResponse := &esapi.Response{
@@ -263,35 +260,101 @@ func TestGetTransactionHistoryHandler(t *testing.T) {
 			Body:       io.NopCloser(bytes.NewReader(responseJSON)),
 		}
 
-		// Setup mock to verify pagination
 		mockClient.On("Search",
 			[]string{"bank-transactions-*"},
-			mock.MatchedBy(func(buf *bytes.Buffer) bool {
-				// Verify pagination parameters
+			mock.MatchedBy(func(buf io.Reader) bool {
 				var query map[string]interface{}
 				if err := json.NewDecoder(buf).Decode(&query); err != nil {
 					return false
 				}
-				return query["from"] == float64(20) && query["size"] == float64(10)
+				cursor, ok := query["search_after"].([]interface{})
+				return ok && cursor[0] == "2026-01-15T00:00:00Z" && cursor[1] == "tx99"
 			})).Return(mockResponse, nil)
 
 		router := gin.New()
 		router.GET("/account/:accountNumber/history", GetTransactionHistoryHandler(mockClient))
 
-		// Create request with page parameter
-		req, _ := http.NewRequest("GET", "/account/ACC123/history?page=3", nil)
+		req, _ := http.NewRequest("GET", "/account/ACC123/history?search_after=2026-01-15T00:00:00Z,tx99", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockClient.AssertExpectations(t)
+	})
+
+	// Test case: malformed search_after is rejected
+	t.Run("Malformed search_after is rejected", func(t *testing.T) {
+		mockClient := new(MockElasticsearchClient)
+
+		router := gin.New()
+		router.GET("/account/:accountNumber/history", GetTransactionHistoryHandler(mockClient))
+
+		req, _ := http.NewRequest("GET", "/account/ACC123/history?search_after=not-a-cursor", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockClient.AssertNotCalled(t, "Search")
+	})
+
+	// Test case: aggregations=1 issues a second query and populates Aggregations
+	t.Run("aggregations=1 issues a second query", func(t *testing.T) {
+		mockClient := new(MockElasticsearchClient)
+
+		historyResponseBody := map[string]interface{}{
+			"hits": map[string]interface{}{"hits": []map[string]interface{}{}},
+		}
+		historyJSON, _ := json.Marshal(historyResponseBody)
+
+		aggResponseBody := map[string]interface{}{
+			"aggregations": map[string]interface{}{
+				"credits_total": map[string]interface{}{"total": map[string]interface{}{"value": 300.0}},
+				"debits_total":  map[string]interface{}{"total": map[string]interface{}{"value": 100.0}},
+				"by_day":        map[string]interface{}{"buckets": []map[string]interface{}{}},
+			},
+		}
+		aggJSON, _ := json.Marshal(aggResponseBody)
+
+		mockClient.On("Search",
+			[]string{"bank-transactions-*"},
+			mock.MatchedBy(func(buf *bytes.Buffer) bool {
+				var query map[string]interface{}
+				// Decode a copy rather than draining buf: testify tries
+				// every matching .On() in order, and a second registration
+				// below needs to see the same bytes this one just read.
+				if err := json.Unmarshal(buf.Bytes(), &query); err != nil {
+					return false
+				}
+				_, hasAggs := query["aggs"]
+				return !hasAggs
+			})).Return(&esapi.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(historyJSON))}, nil)
+
+		mockClient.On("Search",
+			[]string{"bank-transactions-*"},
+			mock.MatchedBy(func(buf *bytes.Buffer) bool {
+				var query map[string]interface{}
+				if err := json.Unmarshal(buf.Bytes(), &query); err != nil {
+					return false
+				}
+				_, hasAggs := query["aggs"]
+				return hasAggs
+			})).Return(&esapi.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(aggJSON))}, nil)
+
+		router := gin.New()
+		router.GET("/account/:accountNumber/history", GetTransactionHistoryHandler(mockClient))
+
+		req, _ := http.NewRequest("GET", "/account/ACC123/history?aggregations=1", nil)
 		w := httptest.NewRecorder()
 		router.ServeHTTP(w, req)
 
-		// Verify response
 		assert.Equal(t, http.StatusOK, w.Code)
 
 		var response TransactionHistoryResponse
-		err := json.Unmarshal(w.Body.Bytes(), &response)
-		assert.NoError(t, err)
-		assert.Equal(t, 3, response.CurrentPage) // Should reflect page 3
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.NotNil(t, response.Aggregations)
+		assert.Equal(t, "300.00", response.Aggregations.CreditsTotal)
+		assert.Equal(t, "100.00", response.Aggregations.DebitsTotal)
 
-		// Verify expectations
 		mockClient.AssertExpectations(t)
 	})
 }
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/siddarth99/banking-ledger/pkg/errs"
+)
+
+// Problem is the RFC 7807 application/problem+json envelope ProblemJSON
+// renders for any *errs.E a handler attaches to the Gin context via c.Error.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+// problemStatus maps an errs.Kind to the HTTP status code its problem+json
+// response should carry.
+func problemStatus(kind errs.Kind) int {
+	switch kind {
+	case errs.KindNotFound:
+		return http.StatusNotFound
+	case errs.KindInvalidArgument:
+		return http.StatusBadRequest
+	case errs.KindUnavailable:
+		return http.StatusServiceUnavailable
+	case errs.KindConflict:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// problemTitle is the human-readable RFC 7807 "title" for a Kind - a short
+// summary of the problem type that doesn't change between occurrences.
+func problemTitle(kind errs.Kind) string {
+	switch kind {
+	case errs.KindNotFound:
+		return "Not Found"
+	case errs.KindInvalidArgument:
+		return "Invalid Argument"
+	case errs.KindUnavailable:
+		return "Service Unavailable"
+	case errs.KindConflict:
+		return "Conflict"
+	default:
+		return "Internal Server Error"
+	}
+}
+
+// ProblemJSON is Gin middleware that renders the last error a handler
+// attached via c.Error as an application/problem+json response, provided it
+// is (or wraps) an *errs.E. Handlers that want this behavior should call
+// c.Error(someErrsE) and return without writing a response themselves;
+// register this middleware ahead of those routes.
+func ProblemJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		var e *errs.E
+		if !errors.As(c.Errors.Last().Err, &e) {
+			return
+		}
+
+		status := problemStatus(e.Kind)
+		c.Header("Content-Type", "application/problem+json")
+		c.AbortWithStatusJSON(status, Problem{
+			Type:     "about:blank",
+			Title:    problemTitle(e.Kind),
+			Status:   status,
+			Detail:   e.Error(),
+			Instance: c.Request.URL.Path,
+			Code:     e.Code,
+		})
+	}
+}
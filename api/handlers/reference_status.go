@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// StageEnqueued is the reference-status stage CreateAccountHandler records
+// once an account-creation message is durably on the queue.
+// CreateAccountProcessor records the rest of the lifecycle - see
+// workers/processor/reference_status.go.
+const StageEnqueued = "ENQUEUED"
+
+// Outcome values for a ReferenceStatusStage.
+const (
+	stageStatusOK     = "OK"
+	stageStatusFailed = "FAILED"
+)
+
+// ReferenceStatusDeadLetterQueue is where a ReferenceStatusStage document is
+// published if Elasticsearch rejects or can't be reached for the index
+// call, so a stage update is never silently dropped; an operator can replay
+// it from there later.
+const ReferenceStatusDeadLetterQueue = "reference_status.dlq"
+
+// ReferenceStatusStage is a single point in a reference ID's processing
+// timeline, as recorded to bank-reference-status-*.
+type ReferenceStatusStage struct {
+	ReferenceID string    `json:"reference_id"`
+	Stage       string    `json:"stage"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// recordReferenceStage indexes a ReferenceStatusStage document for
+// referenceID/stage into Elasticsearch, falling back to publishing the same
+// document to ReferenceStatusDeadLetterQueue over amqpChannel if the index
+// call fails, so a stage update is never silently lost. It logs (rather than
+// failing the caller) on any error - a missing status update must never
+// fail the request it's reporting on. esClient may be nil to skip recording
+// entirely.
+func recordReferenceStage(ctx context.Context, esClient internal.ElasticsearchClient, amqpChannel internal.AMQPQueuePublisher, referenceID, stage string, stageErr error) {
+	if esClient == nil {
+		return
+	}
+
+	doc := ReferenceStatusStage{
+		ReferenceID: referenceID,
+		Stage:       stage,
+		Status:      stageStatusOK,
+		Timestamp:   time.Now(),
+	}
+	if stageErr != nil {
+		doc.Status = stageStatusFailed
+		doc.Error = stageErr.Error()
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		log.Printf("failed to marshal reference-status stage %s/%s: %s", referenceID, stage, err)
+		return
+	}
+
+	indexName := fmt.Sprintf("bank-reference-status-%s", doc.Timestamp.Format("2006-01-02"))
+	res, indexErr := esClient.Index(indexName, bytes.NewReader(body))
+	failed := indexErr != nil
+	if !failed {
+		defer res.Body.Close()
+		failed = res.IsError()
+	}
+	if !failed {
+		return
+	}
+
+	if amqpChannel == nil {
+		log.Printf("failed to index reference-status stage %s/%s and no dead-letter queue configured: %v", referenceID, stage, indexErr)
+		return
+	}
+	if pubErr := internal.PublishWithContext(ctx, body, amqpChannel, "", ReferenceStatusDeadLetterQueue, false, false); pubErr != nil {
+		log.Printf("failed to publish reference-status dead letter for %s/%s: %s", referenceID, stage, pubErr)
+	}
+}
+
+// lookupReferenceStages queries bank-reference-status-* for every stage
+// recorded against referenceID, oldest first. It returns (nil, nil) when no
+// stage documents exist.
+func lookupReferenceStages(esClient internal.ElasticsearchClient, referenceID string) ([]ReferenceStatusStage, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"match": map[string]interface{}{
+				"reference_id": referenceID,
+			},
+		},
+		"sort": []map[string]interface{}{
+			{"timestamp": map[string]interface{}{"order": "asc"}},
+		},
+		"size": 50,
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, err
+	}
+
+	res, err := esClient.Search([]string{"bank-reference-status-*"}, &buf)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, nil
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source ReferenceStatusStage `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Hits.Hits) == 0 {
+		return nil, nil
+	}
+
+	stages := make([]ReferenceStatusStage, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		stages = append(stages, hit.Source)
+	}
+	return stages, nil
+}
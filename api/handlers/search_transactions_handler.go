@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/gin-gonic/gin"
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+)
+
+// TransactionSearchRequest is the structured filter payload accepted by
+// SearchTransactionsHandler. Every field is optional; an empty request
+// matches every document in bank-transactions-*.
+type TransactionSearchRequest struct {
+	AccountNumbers []string `json:"accountNumbers,omitempty"`
+	BranchCodes    []string `json:"branchCodes,omitempty"`
+	Types          []string `json:"types,omitempty"`
+	Statuses       []string `json:"statuses,omitempty"`
+	// MinAmount and MaxAmount bound the transaction amount, inclusive. Both
+	// are optional and may be set independently.
+	MinAmount *money.Amount `json:"minAmount,omitempty"`
+	MaxAmount *money.Amount `json:"maxAmount,omitempty"`
+	// From and To bound Timestamp, inclusive, as RFC3339 strings.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Description is matched as free text against the transaction's
+	// description field.
+	Description string `json:"description,omitempty"`
+	// Cursor is the sort values of the last hit from a previous page, as
+	// returned in that page's NextCursor. Omit it to fetch the first page.
+	Cursor []interface{} `json:"cursor,omitempty"`
+	// Size caps how many hits to return. A zero or negative value falls
+	// back to DefaultSearchSize.
+	Size int `json:"size,omitempty"`
+}
+
+// DefaultSearchSize and MaxSearchSize bound TransactionSearchRequest.Size.
+const (
+	DefaultSearchSize = 20
+	MaxSearchSize     = 200
+)
+
+// TransactionSearchResponse is the result of a transaction search: the
+// matching hits (sorted newest first), a cursor for the next page, and,
+// if requested, aggregation buckets.
+type TransactionSearchResponse struct {
+	Transactions []TransactionSearchItem `json:"transactions"`
+	NextCursor   []interface{}           `json:"nextCursor,omitempty"`
+	Aggregations []TransactionAggBucket  `json:"aggregations,omitempty"`
+}
+
+// TransactionSearchItem is a single hit from SearchTransactionsHandler. It
+// carries AccountNumber and BranchCode (unlike TransactionHistoryItem, used
+// by GetTransactionHistoryHandler), since a search can span more than one
+// account and branch.
+type TransactionSearchItem struct {
+	TransactionID           string       `json:"id"`
+	AccountNumber           string       `json:"accountNumber"`
+	BranchCode              string       `json:"branchCode"`
+	Amount                  money.Amount `json:"amount"`
+	TransactionType         string       `json:"type"`
+	Status                  string       `json:"status"`
+	Timestamp               time.Time    `json:"timestamp"`
+	BalanceAfterTransaction money.Amount `json:"updatedBalance"`
+	Description             string       `json:"description,omitempty"`
+}
+
+// TransactionAggBucket is one group's rollup, as requested via
+// ?aggregate=true&aggregateBy=type|day.
+type TransactionAggBucket struct {
+	Key        string `json:"key"`
+	Count      int64  `json:"count"`
+	TotalValue string `json:"totalValue"`
+	AvgValue   string `json:"avgValue"`
+	Currency   string `json:"currency,omitempty"`
+}
+
+// searchSortFields are the fields every search query is sorted by, in
+// order. Sorting on transaction_id as well as timestamp gives search_after
+// a stable tiebreaker for documents sharing the same timestamp, which a
+// sort on timestamp alone would not.
+var searchSortFields = []string{"timestamp", "transaction_id"}
+
+// SearchTransactionsHandler answers POST /transactions/search: a structured
+// filter over bank-transactions-*, paginated with search_after rather than
+// from/size so deep pagination never runs into Elasticsearch's 10,000-hit
+// window, with optional aggregation buckets alongside the hits.
+func SearchTransactionsHandler(esClient internal.ElasticsearchClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req TransactionSearchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		aggregateBy := ""
+		if c.Query("aggregate") == "true" {
+			aggregateBy = c.DefaultQuery("aggregateBy", "type")
+			if aggregateBy != "type" && aggregateBy != "day" {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"errorCode": http.StatusBadRequest,
+					"error":     "aggregateBy must be \"type\" or \"day\"",
+				})
+				return
+			}
+		}
+
+		query, err := req.buildQuery(aggregateBy)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(query); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     "Failed to build search query",
+			})
+			return
+		}
+
+		res, err := esClient.Search([]string{"bank-transactions-*"}, &buf)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     "Failed to search transactions: " + err.Error(),
+			})
+			return
+		}
+		defer res.Body.Close()
+
+		if res.IsError() {
+			var e map[string]interface{}
+			if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"errorCode": http.StatusInternalServerError,
+					"error":     "Failed to parse error response from Elasticsearch",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     fmt.Sprintf("[%s] %s: %s", res.Status(), e["error"].(map[string]interface{})["type"], e["error"].(map[string]interface{})["reason"]),
+			})
+			return
+		}
+
+		response, err := parseSearchResponse(res)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     "Failed to parse search results: " + err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// buildQuery translates req into an Elasticsearch request body: term/range
+// filters in a bool query's filter clause (which don't affect scoring and
+// are cacheable), free-text description in must, sorted by
+// searchSortFields, paginated with search_after, and - if aggregateBy is
+// non-empty - a terms or date_histogram aggregation with sum/avg/count
+// sub-aggregations.
+func (req TransactionSearchRequest) buildQuery(aggregateBy string) (map[string]interface{}, error) {
+	var filters []map[string]interface{}
+
+	if len(req.AccountNumbers) > 0 {
+		filters = append(filters, map[string]interface{}{"terms": map[string]interface{}{"account_number": req.AccountNumbers}})
+	}
+	if len(req.BranchCodes) > 0 {
+		filters = append(filters, map[string]interface{}{"terms": map[string]interface{}{"branch_code": req.BranchCodes}})
+	}
+	if len(req.Types) > 0 {
+		filters = append(filters, map[string]interface{}{"terms": map[string]interface{}{"type": req.Types}})
+	}
+	if len(req.Statuses) > 0 {
+		filters = append(filters, map[string]interface{}{"terms": map[string]interface{}{"status": req.Statuses}})
+	}
+	if req.MinAmount != nil || req.MaxAmount != nil {
+		amountRange := map[string]interface{}{}
+		if req.MinAmount != nil {
+			amountRange["gte"] = req.MinAmount.String()
+		}
+		if req.MaxAmount != nil {
+			amountRange["lte"] = req.MaxAmount.String()
+		}
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"amount.value": amountRange}})
+	}
+	if req.From != "" || req.To != "" {
+		timestampRange := map[string]interface{}{}
+		if req.From != "" {
+			timestampRange["gte"] = req.From
+		}
+		if req.To != "" {
+			timestampRange["lte"] = req.To
+		}
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"timestamp": timestampRange}})
+	}
+
+	boolQuery := map[string]interface{}{}
+	if len(filters) > 0 {
+		boolQuery["filter"] = filters
+	}
+	if req.Description != "" {
+		boolQuery["must"] = []map[string]interface{}{
+			{"match": map[string]interface{}{"description": req.Description}},
+		}
+	}
+
+	query := map[string]interface{}{"bool": boolQuery}
+	if len(boolQuery) == 0 {
+		query = map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+
+	size := req.Size
+	if size <= 0 {
+		size = DefaultSearchSize
+	}
+	if size > MaxSearchSize {
+		size = MaxSearchSize
+	}
+
+	body := map[string]interface{}{
+		"query": query,
+		"sort": []map[string]interface{}{
+			{searchSortFields[0]: map[string]interface{}{"order": "desc"}},
+			{searchSortFields[1]: map[string]interface{}{"order": "desc"}},
+		},
+		"size": size,
+	}
+	if len(req.Cursor) > 0 {
+		body["search_after"] = req.Cursor
+	}
+	if aggregateBy != "" {
+		body["aggs"] = buildAggregations(aggregateBy)
+	}
+	return body, nil
+}
+
+// buildAggregations groups documents by type or by calendar day and, within
+// each bucket, sums and averages the transaction amount. amount is indexed
+// as a nested {value, currency} object rather than a plain number, so the
+// metric sub-aggregations parse amount.value.keyword with a script rather
+// than referencing it as a numeric field directly.
+func buildAggregations(aggregateBy string) map[string]interface{} {
+	var bucketAgg map[string]interface{}
+	switch aggregateBy {
+	case "day":
+		bucketAgg = map[string]interface{}{
+			"date_histogram": map[string]interface{}{
+				"field":             "timestamp",
+				"calendar_interval": "day",
+			},
+		}
+	default:
+		bucketAgg = map[string]interface{}{
+			"terms": map[string]interface{}{"field": "type"},
+		}
+	}
+
+	amountScript := map[string]interface{}{
+		"source": "Double.parseDouble(doc['amount.value.keyword'].value)",
+	}
+	bucketAgg["aggs"] = map[string]interface{}{
+		"total_amount": map[string]interface{}{"sum": map[string]interface{}{"script": amountScript}},
+		"avg_amount":   map[string]interface{}{"avg": map[string]interface{}{"script": amountScript}},
+	}
+
+	return map[string]interface{}{"by_group": bucketAgg}
+}
+
+// parseSearchResponse decodes an Elasticsearch search response into a
+// TransactionSearchResponse, deriving NextCursor from the last hit's sort
+// values and Aggregations (if present) from the by_group aggregation.
+func parseSearchResponse(res *esapi.Response) (*TransactionSearchResponse, error) {
+	var parsed struct {
+		Hits struct {
+			Hits []struct {
+				Source EsResponseItem `json:"_source"`
+				Sort   []interface{}  `json:"sort"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations struct {
+			ByGroup struct {
+				Buckets []struct {
+					Key         interface{} `json:"key"`
+					KeyAsString string      `json:"key_as_string"`
+					DocCount    int64       `json:"doc_count"`
+					TotalAmount struct {
+						Value float64 `json:"value"`
+					} `json:"total_amount"`
+					AvgAmount struct {
+						Value float64 `json:"value"`
+					} `json:"avg_amount"`
+				} `json:"buckets"`
+			} `json:"by_group"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	transactions := make([]TransactionSearchItem, 0, len(parsed.Hits.Hits))
+	var nextCursor []interface{}
+	for _, hit := range parsed.Hits.Hits {
+		transactions = append(transactions, TransactionSearchItem{
+			TransactionID:           hit.Source.TransactionID,
+			AccountNumber:           hit.Source.AccountNumber,
+			BranchCode:              hit.Source.BranchCode,
+			Amount:                  hit.Source.Amount,
+			TransactionType:         hit.Source.TransactionType,
+			Status:                  hit.Source.Status,
+			Timestamp:               hit.Source.Timestamp,
+			BalanceAfterTransaction: hit.Source.BalanceAfterTransaction,
+			Description:             hit.Source.Description,
+		})
+		nextCursor = hit.Sort
+	}
+
+	response := &TransactionSearchResponse{
+		Transactions: transactions,
+		NextCursor:   nextCursor,
+	}
+
+	for _, bucket := range parsed.Aggregations.ByGroup.Buckets {
+		key := bucket.KeyAsString
+		if key == "" {
+			key = fmt.Sprintf("%v", bucket.Key)
+		}
+		response.Aggregations = append(response.Aggregations, TransactionAggBucket{
+			Key:        key,
+			Count:      bucket.DocCount,
+			TotalValue: fmt.Sprintf("%.2f", bucket.TotalAmount.Value),
+			AvgValue:   fmt.Sprintf("%.2f", bucket.AvgAmount.Value),
+		})
+	}
+
+	return response, nil
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSearchTransactionsHandler_ReturnsHitsAndNextCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockClient := new(MockElasticsearchClient)
+
+	timestamp := time.Now()
+	responseBody := map[string]interface{}{
+		"hits": map[string]interface{}{
+			"hits": []map[string]interface{}{
+				{
+					"_source": map[string]interface{}{
+						"transaction_id":            "tx123",
+						"account_number":            "ACC123",
+						"branch_code":               "BR001",
+						"amount":                    map[string]interface{}{"value": "100.50", "currency": "USD"},
+						"type":                      "DEPOSIT",
+						"status":                    "COMPLETED",
+						"timestamp":                 timestamp,
+						"balance_after_transaction": map[string]interface{}{"value": "500.75", "currency": "USD"},
+					},
+					"sort": []interface{}{timestamp.UnixMilli(), "tx123"},
+				},
+			},
+		},
+	}
+	responseJSON, _ := json.Marshal(responseBody)
+
+	mockClient.On("Search",
+		[]string{"bank-transactions-*"},
+		mock.MatchedBy(func(buf io.Reader) bool {
+			var query map[string]interface{}
+			if err := json.NewDecoder(buf).Decode(&query); err != nil {
+				return false
+			}
+			boolQuery, ok := query["query"].(map[string]interface{})["bool"].(map[string]interface{})
+			if !ok {
+				return false
+			}
+			filters, ok := boolQuery["filter"].([]interface{})
+			return ok && len(filters) == 1
+		})).Return(&esapi.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(responseJSON))}, nil)
+
+	router := gin.New()
+	router.POST("/transactions/search", SearchTransactionsHandler(mockClient))
+
+	body, _ := json.Marshal(TransactionSearchRequest{AccountNumbers: []string{"ACC123"}})
+	req := httptest.NewRequest(http.MethodPost, "/transactions/search", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response TransactionSearchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Transactions, 1)
+	assert.Equal(t, "tx123", response.Transactions[0].TransactionID)
+	assert.Equal(t, []interface{}{float64(timestamp.UnixMilli()), "tx123"}, response.NextCursor)
+	mockClient.AssertExpectations(t)
+}
+
+func TestSearchTransactionsHandler_AggregateByTypeReturnsBuckets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockClient := new(MockElasticsearchClient)
+
+	responseBody := map[string]interface{}{
+		"hits": map[string]interface{}{"hits": []map[string]interface{}{}},
+		"aggregations": map[string]interface{}{
+			"by_group": map[string]interface{}{
+				"buckets": []map[string]interface{}{
+					{"key": "DEPOSIT", "doc_count": 3, "total_amount": map[string]interface{}{"value": 300.0}, "avg_amount": map[string]interface{}{"value": 100.0}},
+				},
+			},
+		},
+	}
+	responseJSON, _ := json.Marshal(responseBody)
+
+	mockClient.On("Search",
+		[]string{"bank-transactions-*"},
+		mock.MatchedBy(func(buf io.Reader) bool {
+			var query map[string]interface{}
+			if err := json.NewDecoder(buf).Decode(&query); err != nil {
+				return false
+			}
+			_, hasAggs := query["aggs"]
+			return hasAggs
+		})).Return(&esapi.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewReader(responseJSON))}, nil)
+
+	router := gin.New()
+	router.POST("/transactions/search", SearchTransactionsHandler(mockClient))
+
+	req := httptest.NewRequest(http.MethodPost, "/transactions/search?aggregate=true&aggregateBy=type", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response TransactionSearchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Aggregations, 1)
+	assert.Equal(t, "DEPOSIT", response.Aggregations[0].Key)
+	assert.EqualValues(t, 3, response.Aggregations[0].Count)
+	mockClient.AssertExpectations(t)
+}
+
+func TestSearchTransactionsHandler_RejectsInvalidAggregateBy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockClient := new(MockElasticsearchClient)
+
+	router := gin.New()
+	router.POST("/transactions/search", SearchTransactionsHandler(mockClient))
+
+	req := httptest.NewRequest(http.MethodPost, "/transactions/search?aggregate=true&aggregateBy=bogus", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockClient.AssertNotCalled(t, "Search", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/siddarth99/banking-ledger/pkg/webhook"
+)
+
+// SubscriptionRequest registers a webhook callback for the requested events
+// across every account-creation request, rather than one scoped to a single
+// ReferenceID the way AccountRequest.Callback is.
+type SubscriptionRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Secret string   `json:"secret" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+}
+
+// CreateSubscriptionHandler registers a global webhook subscription so a
+// client can receive every account.completed/account.failed notification
+// without registering a Callback on each individual createAccount request.
+func CreateSubscriptionHandler(webhookStore *webhook.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var subscriptionRequest SubscriptionRequest
+		if err := c.ShouldBindJSON(&subscriptionRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		sub, err := webhookStore.Create(c.Request.Context(), webhook.Subscription{
+			URL:    subscriptionRequest.URL,
+			Secret: subscriptionRequest.Secret,
+			Events: subscriptionRequest.Events,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"id":        sub.ID,
+			"url":       sub.URL,
+			"events":    sub.Events,
+			"createdAt": sub.CreatedAt,
+		})
+	}
+}
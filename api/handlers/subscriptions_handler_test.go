@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/webhook"
+)
+
+func TestCreateSubscriptionHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Valid request registers a subscription", func(t *testing.T) {
+		db := &internal.MockPgDBConnection{
+			ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+				return pgconn.NewCommandTag("INSERT 0 1"), nil
+			},
+		}
+		store := webhook.NewStore(db)
+
+		body, _ := json.Marshal(SubscriptionRequest{
+			URL:    "https://example.com/webhook",
+			Secret: "shh",
+			Events: []string{"account.completed", "account.failed"},
+		})
+		req, _ := http.NewRequest(http.MethodPost, "/subscriptions", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router := gin.New()
+		router.POST("/subscriptions", CreateSubscriptionHandler(store))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.NotEmpty(t, response["id"])
+	})
+
+	t.Run("Missing required fields returns 400", func(t *testing.T) {
+		store := webhook.NewStore(&internal.MockPgDBConnection{})
+
+		body, _ := json.Marshal(SubscriptionRequest{URL: "https://example.com/webhook"})
+		req, _ := http.NewRequest(http.MethodPost, "/subscriptions", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router := gin.New()
+		router.POST("/subscriptions", CreateSubscriptionHandler(store))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
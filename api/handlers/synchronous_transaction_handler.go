@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/errs"
+)
+
+// DefaultSynchronousReplyTimeout bounds how long SynchronousTransactionHandler
+// waits for the worker's reply before giving up.
+const DefaultSynchronousReplyTimeout = 10 * time.Second
+
+// SynchronousTransactionHandler publishes a transaction straight to
+// queueName and blocks for the worker's reply via
+// internal.PublishAndAwaitReply, instead of going through the
+// TransactionHandler/CompleteTransactionHandler two-phase approval flow. A
+// caller that doesn't need the review step gets the posted balance/ledger
+// entry back in this same HTTP response rather than only
+// {transactionID, createdAt}.
+//
+// amqpChannel is a raw internal.AMQPChannel rather than a
+// resilientamqp.Client, since this handler needs to consume from a reply
+// queue as well as publish - a capability resilientamqp.Client doesn't
+// expose.
+func SynchronousTransactionHandler(ctx context.Context, amqpChannel internal.AMQPChannel, queueName string, replyTimeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req TransactionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		// binding:"required" is a no-op on a struct-typed field, so Amount
+		// is checked by hand.
+		if req.Amount.IsZero() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     "amount is required",
+			})
+			return
+		}
+
+		if req.TransactionID == "" {
+			req.TransactionID = uuid.New().String()
+		}
+
+		body, err := json.Marshal(req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"errorCode": http.StatusInternalServerError,
+				"error":     err.Error(),
+			})
+			return
+		}
+
+		replyBody, err := internal.PublishAndAwaitReply(ctx, amqpChannel, "", queueName, body, replyTimeout)
+		if err != nil {
+			if errors.Is(err, internal.ErrReplyTimeout) {
+				c.Error(errs.Unavailable(err).WithCode("BANK.REPLY_TIMEOUT"))
+				return
+			}
+			c.Error(errs.Unavailable(err).WithCode("BANK.PUBLISH_FAILED"))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"transactionID": req.TransactionID,
+			"result":        json.RawMessage(replyBody),
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+func TestSynchronousTransactionHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Returns the worker's posted outcome", func(t *testing.T) {
+		mockChannel := new(internal.MockAMQPChannel)
+		mockChannel.On("QueueDeclare", "", false, true, true, false, amqp.Table(nil)).Return(nil)
+
+		deliveries := make(chan amqp.Delivery, 1)
+		mockChannel.On("Consume", "", "rpc-", true, true, false, false, nil).Return((<-chan amqp.Delivery)(deliveries), nil)
+
+		mockChannel.On("PublishWithContext", mock.Anything, "", "transaction_processor", false, false, mock.Anything).
+			Run(func(args mock.Arguments) {
+				msg := args.Get(5).(amqp.Publishing)
+				deliveries <- amqp.Delivery{
+					CorrelationId: msg.CorrelationId,
+					Body:          []byte(`{"transactionId":"TX1","status":"COMPLETED","balanceAfter":"150.00"}`),
+				}
+			}).
+			Return(nil)
+
+		body := bytes.NewBufferString(`{"accountNumber":"ACC123456","type":"DEPOSIT","amount":{"value":"50.00","currency":"USD"}}`)
+		req, _ := http.NewRequest(http.MethodPost, "/transact/sync", body)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(ProblemJSON())
+		router.POST("/transact/sync", SynchronousTransactionHandler(context.Background(), mockChannel, "transaction_processor", time.Second))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"COMPLETED"`)
+		mockChannel.AssertExpectations(t)
+	})
+
+	t.Run("Missing amount is rejected", func(t *testing.T) {
+		mockChannel := new(internal.MockAMQPChannel)
+
+		body := bytes.NewBufferString(`{"accountNumber":"ACC123456","type":"DEPOSIT"}`)
+		req, _ := http.NewRequest(http.MethodPost, "/transact/sync", body)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(ProblemJSON())
+		router.POST("/transact/sync", SynchronousTransactionHandler(context.Background(), mockChannel, "transaction_processor", time.Second))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockChannel.AssertNotCalled(t, "PublishWithContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("No reply within timeout surfaces as unavailable", func(t *testing.T) {
+		mockChannel := new(internal.MockAMQPChannel)
+		mockChannel.On("QueueDeclare", "", false, true, true, false, amqp.Table(nil)).Return(nil)
+
+		deliveries := make(chan amqp.Delivery)
+		mockChannel.On("Consume", "", "rpc-", true, true, false, false, nil).Return((<-chan amqp.Delivery)(deliveries), nil)
+		mockChannel.On("PublishWithContext", mock.Anything, "", "transaction_processor", false, false, mock.Anything).Return(nil)
+
+		body := bytes.NewBufferString(`{"accountNumber":"ACC123456","type":"DEPOSIT","amount":{"value":"50.00","currency":"USD"}}`)
+		req, _ := http.NewRequest(http.MethodPost, "/transact/sync", body)
+		w := httptest.NewRecorder()
+
+		router := gin.Default()
+		router.Use(ProblemJSON())
+		router.POST("/transact/sync", SynchronousTransactionHandler(context.Background(), mockChannel, "transaction_processor", 10*time.Millisecond))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
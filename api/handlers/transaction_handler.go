@@ -3,23 +3,32 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/idempotency"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+	"github.com/siddarth99/banking-ledger/pkg/pending"
 )
 
 // TransactionRequest represents the data structure for transaction requests
 // It contains all necessary fields required to process a transaction
 type TransactionRequest struct {
-	AccountNumber   string  `json:"accountNumber" binding:"required"` // Account number for the transaction
-	Amount          float64 `json:"amount" binding:"required"`        // Amount to debit or credit
-	TransactionType string  `json:"type" binding:"required"`          // Type of transaction (debit/credit)
-	Description     string  `json:"description"`                      // Optional description
-	TransactionID   string  `json:"transactionId"`                    // Unique identifier for tracking
+	AccountNumber      string       `json:"accountNumber"`           // Account number for DEPOSIT/WITHDRAWAL
+	SourceAccount      string       `json:"sourceAccount"`           // Debited account for TRANSFER
+	DestinationAccount string       `json:"destinationAccount"`      // Credited account for TRANSFER
+	Amount             money.Amount `json:"amount"`                  // Amount to debit or credit
+	TransactionType    string       `json:"type" binding:"required"` // DEPOSIT, WITHDRAWAL or TRANSFER
+	Description        string       `json:"description"`             // Optional description
+	TransactionID      string       `json:"transactionId"`           // Unique identifier for tracking
+	// IdempotencyKey mirrors the Idempotency-Key header onto the queued
+	// payload so the worker can stamp the indexed TransactionDocument with
+	// it; it is never read from the request body itself.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
 }
 
 // transactionResponse represents the response structure sent back to clients
@@ -29,10 +38,22 @@ type transactionResponse struct {
 	CreatedAt     time.Time `json:"createdAt"`     // Timestamp when the transaction request was processed
 }
 
-// TransactionHandler creates a new HTTP handler for transaction requests
-// It takes a context, an AMQP channel, and a queue name for message publishing
+// idempotencyKeyHeader is the client-supplied header used to de-duplicate
+// retried transaction requests.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// TransactionHandler creates a new HTTP handler for transaction requests.
+// Rather than publishing straight to the queue, it stores the request in the
+// pending store so a client can review it via POST /transactions/:id/complete
+// or /discard before it is actually processed.
+//
+// If the caller sends an Idempotency-Key header, idempotencyStore is used to
+// de-duplicate retries: a second request with the same key and the same
+// accountNumber/amount/type replays the original response instead of
+// queuing a second transaction. idempotencyStore may be nil to disable
+// de-duplication entirely.
 // Returns a gin.HandlerFunc that can be registered with the router
-func TransactionHandler(ctx context.Context, amqpChannel internal.AMQPChannel, queueName string) gin.HandlerFunc {
+func TransactionHandler(ctx context.Context, pendingRequests *pending.Requests, idempotencyStore *idempotency.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var transactionRequestJson TransactionRequest
 		// Parse and validate the incoming JSON request
@@ -44,10 +65,29 @@ func TransactionHandler(ctx context.Context, amqpChannel internal.AMQPChannel, q
 			return
 		}
 
-		// Process the transaction request
-		response, err := transactionRequestJson.createTransaction(ctx, amqpChannel, queueName)
+		// binding:"required" is a no-op on a struct-typed field, so Amount
+		// is checked by hand.
+		if transactionRequestJson.Amount.IsZero() {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"errorCode": http.StatusBadRequest,
+				"error":     "amount is required",
+			})
+			return
+		}
+
+		// Store the transaction request for client review
+		response, err := transactionRequestJson.createTransaction(ctx, pendingRequests, idempotencyStore, c.GetHeader(idempotencyKeyHeader))
 
 		if err != nil {
+			var conflict *idempotency.ConflictError
+			if errors.As(err, &conflict) {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"errorCode": http.StatusUnprocessableEntity,
+					"error":     conflict.Error(),
+					"diff":      conflict.Diff(),
+				})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"errorCode": http.StatusInternalServerError,
 				"error":     err.Error(),
@@ -64,15 +104,61 @@ func TransactionHandler(ctx context.Context, amqpChannel internal.AMQPChannel, q
 }
 
 // createTransaction processes a transaction request by:
-// 1. Generating unique reference and transaction IDs
-// 2. Publishing the request to a message queue for asynchronous processing
-// 3. Returning a response with tracking information
-func (t *TransactionRequest) createTransaction(ctx context.Context, amqpChannel internal.AMQPChannel, queueName string) (transactionResponse, error) {
+//  1. Honoring a client-supplied transactionId, or generating a unique one
+//  2. Replaying the original response if idempotencyKey was already used for
+//     an identical request, or rejecting it with ErrConflict if it wasn't
+//  3. Recording the request in the pending store for later approval
+//  4. Returning a response with tracking information
+func (t *TransactionRequest) createTransaction(ctx context.Context, pendingRequests *pending.Requests, idempotencyStore *idempotency.Store, idempotencyKey string) (transactionResponse, error) {
+
+	// Honor a client-supplied transaction ID so a retried call that changed
+	// its mind about the Idempotency-Key header still lines up with the
+	// original request; otherwise mint a fresh one.
+	if t.TransactionID == "" {
+		t.TransactionID = uuid.New().String()
+	}
+	transactionID := t.TransactionID
+	t.IdempotencyKey = idempotencyKey
+
+	// TRANSFER requests key the pending-request limit off the debited
+	// account, since AccountNumber is only populated for DEPOSIT/WITHDRAWAL.
+	accountNumber := t.AccountNumber
+	if accountNumber == "" {
+		accountNumber = t.SourceAccount
+	}
+
+	response := transactionResponse{
+		TransactionID: transactionID,
+		CreatedAt:     time.Now(),
+	}
 
-	// Generate a unique transaction ID
-	transactionID := uuid.New().String()
+	if idempotencyKey != "" && idempotencyStore != nil {
+		responseJSON, err := json.Marshal(response)
+		if err != nil {
+			fmt.Printf("Error while marshalling idempotent response: %s", err.Error())
+			return transactionResponse{}, err
+		}
 
-	t.TransactionID = transactionID
+		existing, err := idempotencyStore.Reserve(ctx, idempotency.Record{
+			Key:           idempotencyKey,
+			AccountNumber: accountNumber,
+			Amount:        t.Amount,
+			Type:          t.TransactionType,
+			TransactionID: transactionID,
+			ResponseBody:  responseJSON,
+			CreatedAt:     response.CreatedAt,
+		})
+		if err != nil {
+			return transactionResponse{}, err
+		}
+		if existing != nil {
+			var replay transactionResponse
+			if err := json.Unmarshal(existing.ResponseBody, &replay); err != nil {
+				return transactionResponse{}, err
+			}
+			return replay, nil
+		}
+	}
 
 	// Create JSON payload
 	requestByteArray, err := json.Marshal(t)
@@ -82,26 +168,11 @@ func (t *TransactionRequest) createTransaction(ctx context.Context, amqpChannel
 		return transactionResponse{}, err
 	}
 
-	// Publish message to RabbitMQ for asynchronous processing
-	err = internal.PublishWithContext(
-		ctx,
-		requestByteArray,
-		amqpChannel,
-		"",        // default exchange
-		queueName, // routing key = queue name
-		false,     // mandatory
-		false,     // immediate
-	)
-
-	if err != nil {
-		// Handle publishing error
-		fmt.Printf("Error while publishing transaction request to queue: %s", err.Error())
+	if _, err := pendingRequests.Add(ctx, transactionID, accountNumber, requestByteArray); err != nil {
+		fmt.Printf("Error while queuing transaction request for approval: %s", err.Error())
 		return transactionResponse{}, err
 	}
 
 	// Return response with tracking information
-	return transactionResponse{
-		TransactionID: transactionID,
-		CreatedAt:     time.Now(),
-	}, nil
+	return response, nil
 }
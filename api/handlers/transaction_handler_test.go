@@ -4,33 +4,51 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
+
 	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/idempotency"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+	"github.com/siddarth99/banking-ledger/pkg/pending"
 )
 
+func newTestPendingRequests() *pending.Requests {
+	db := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.CommandTag{}, nil
+		},
+		// No row queried for an ID means that ID was never Add()ed anywhere,
+		// which is what a genuinely unknown ID looks like to loadLocked.
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			return &internal.MockPgxRows{NextFunc: func() bool { return false }}, nil
+		},
+	}
+	return pending.NewRequests(db, time.Hour, 0)
+}
+
 // Test for TransactionHandler
 func TestTransactionHandler(t *testing.T) {
 	// Set Gin to test mode
 	gin.SetMode(gin.TestMode)
 
-	t.Run("Valid request should return 200", func(t *testing.T) {
-		// Setup mock channel
-		mockChannel := new(internal.MockAMQPChannel)
-		mockChannel.On("PublishWithContext",
-			mock.Anything, "", "transaction_queue", false, false, mock.Anything).Return(nil)
+	t.Run("Valid request should return 202 and store it as pending", func(t *testing.T) {
+		pendingRequests := newTestPendingRequests()
+
+		amount, err := money.FromString("100.50", "USD")
+		assert.NoError(t, err)
 
 		// Create a test request
 		validRequest := TransactionRequest{
 			AccountNumber:   "ACC123456",
-			Amount:          100.50,
+			Amount:          amount,
 			TransactionType: "credit",
 			Description:     "Test transaction",
 		}
@@ -43,26 +61,27 @@ func TestTransactionHandler(t *testing.T) {
 
 		// Setup router
 		router := gin.Default()
-		router.POST("/transactions", TransactionHandler(context.Background(), mockChannel, "transaction_queue"))
+		router.POST("/transactions", TransactionHandler(context.Background(), pendingRequests, nil))
 		router.ServeHTTP(w, req)
 
 		// Assert response
-		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusAccepted, w.Code)
 
 		// Verify response contains expected fields
 		var response map[string]interface{}
-		err := json.Unmarshal(w.Body.Bytes(), &response)
+		err = json.Unmarshal(w.Body.Bytes(), &response)
 		assert.NoError(t, err)
 		assert.Contains(t, response, "transactionID")
 		assert.Contains(t, response, "createdAt")
 
-		// Verify our mock was called as expected
-		mockChannel.AssertExpectations(t)
+		// Verify the request was stored as pending
+		stored, ok := pendingRequests.Get(context.Background(), response["transactionID"].(string))
+		assert.True(t, ok)
+		assert.Equal(t, pending.StatePending, stored.State)
 	})
 
 	t.Run("Invalid request should return 400", func(t *testing.T) {
-		// Setup mock channel
-		mockChannel := new(internal.MockAMQPChannel)
+		pendingRequests := newTestPendingRequests()
 
 		// Create an invalid request (missing required fields)
 		invalidRequest := TransactionRequest{
@@ -78,67 +97,91 @@ func TestTransactionHandler(t *testing.T) {
 
 		// Setup router
 		router := gin.Default()
-		router.POST("/transactions", TransactionHandler(context.Background(), mockChannel, "transaction_queue"))
+		router.POST("/transactions", TransactionHandler(context.Background(), pendingRequests, nil))
 		router.ServeHTTP(w, req)
 
 		// Assert response
 		assert.Equal(t, http.StatusBadRequest, w.Code)
-
-		// Verify our mock was never called
-		mockChannel.AssertNotCalled(t, "PublishWithContext")
 	})
 
-	t.Run("Publishing error should return 500", func(t *testing.T) {
-		// Setup mock channel that returns an error
-		mockChannel := new(internal.MockAMQPChannel)
-		mockChannel.On("PublishWithContext",
-			mock.Anything, "", "transaction_queue", false, false, mock.Anything).Return(errors.New("publish error"))
+	t.Run("Idempotency-Key reused with a different payload returns 422 with a diff", func(t *testing.T) {
+		pendingRequests := newTestPendingRequests()
+		storedAmount, err := money.FromString("100.50", "USD")
+		assert.NoError(t, err)
+		storedHash := idempotency.Fingerprint("retry-key", "ACC123456", storedAmount, "credit")
+
+		db := &internal.MockPgDBConnection{
+			BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+				return &internal.MockPgDBConnection{
+					ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+						return pgconn.NewCommandTag("INSERT 0 0"), nil
+					},
+					QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+						return &internal.MockPgxRow{
+							ScanFunc: func(dest ...interface{}) error {
+								*dest[0].(*string) = storedHash
+								*dest[1].(*string) = "ACC123456"
+								*dest[2].(*money.Amount) = storedAmount
+								*dest[3].(*string) = "USD"
+								*dest[4].(*string) = "credit"
+								*dest[5].(*string) = "original-id"
+								*dest[6].(*[]byte) = []byte(`{"transactionID":"original-id"}`)
+								*dest[7].(*time.Time) = time.Now()
+								*dest[8].(*time.Time) = time.Now().Add(time.Hour)
+								return nil
+							},
+						}
+					},
+				}, nil
+			},
+		}
+		idempotencyStore := idempotency.NewStore(db, time.Hour)
 
-		// Create a valid request
-		validRequest := TransactionRequest{
+		retriedAmount, err := money.FromString("250.00", "USD") // differs from the payload the key was first used with
+		assert.NoError(t, err)
+		retriedRequest := TransactionRequest{
 			AccountNumber:   "ACC123456",
-			Amount:          100.50,
-			TransactionType: "debit",
-			Description:     "Test transaction",
+			Amount:          retriedAmount,
+			TransactionType: "credit",
 		}
-		jsonValue, _ := json.Marshal(validRequest)
+		jsonValue, _ := json.Marshal(retriedRequest)
 		req, _ := http.NewRequest(http.MethodPost, "/transactions", bytes.NewBuffer(jsonValue))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "retry-key")
 
-		// Setup response recorder
 		w := httptest.NewRecorder()
-
-		// Setup router
 		router := gin.Default()
-		router.POST("/transactions", TransactionHandler(context.Background(), mockChannel, "transaction_queue"))
+		router.POST("/transactions", TransactionHandler(context.Background(), pendingRequests, idempotencyStore))
 		router.ServeHTTP(w, req)
 
-		// Assert response (note: transaction handler returns 500 for publishing errors, not 400)
-		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 
-		// Verify our mock was called as expected
-		mockChannel.AssertExpectations(t)
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		diff, ok := response["diff"].(map[string]interface{})
+		assert.True(t, ok)
+		assert.Contains(t, diff, "amount")
 	})
 }
 
 // Test for createTransaction method
 func TestCreateTransaction(t *testing.T) {
 	t.Run("Successful transaction creation", func(t *testing.T) {
-		// Setup mock channel
-		mockChannel := new(internal.MockAMQPChannel)
-		mockChannel.On("PublishWithContext",
-			mock.Anything, "", "test_queue", false, false, mock.Anything).Return(nil)
+		pendingRequests := newTestPendingRequests()
+
+		amount, err := money.FromString("200.75", "USD")
+		assert.NoError(t, err)
 
 		// Create request
 		req := &TransactionRequest{
 			AccountNumber:   "ACC123456",
-			Amount:          200.75,
+			Amount:          amount,
 			TransactionType: "credit",
 			Description:     "Test transaction",
 		}
 
 		// Call method
-		resp, err := req.createTransaction(context.Background(), mockChannel, "test_queue")
+		resp, err := req.createTransaction(context.Background(), pendingRequests, nil, "")
 
 		// Assert no error
 		assert.NoError(t, err)
@@ -147,37 +190,141 @@ func TestCreateTransaction(t *testing.T) {
 		assert.NotEmpty(t, resp.TransactionID)
 		assert.NotZero(t, resp.CreatedAt)
 		assert.WithinDuration(t, time.Now(), resp.CreatedAt, 2*time.Second)
+	})
+
+	t.Run("Per-account pending limit exceeded", func(t *testing.T) {
+		db := &internal.MockPgDBConnection{
+			ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+				return pgconn.CommandTag{}, nil
+			},
+		}
+		pendingRequests := pending.NewRequests(db, time.Hour, 1)
+
+		amount, err := money.FromString("200.75", "USD")
+		assert.NoError(t, err)
 
-		// Verify our mock was called as expected
-		mockChannel.AssertExpectations(t)
+		first := &TransactionRequest{
+			AccountNumber:   "ACC123456",
+			Amount:          amount,
+			TransactionType: "debit",
+		}
+		_, err = first.createTransaction(context.Background(), pendingRequests, nil, "")
+		assert.NoError(t, err)
+
+		secondAmount, err := money.FromString("50.00", "USD")
+		assert.NoError(t, err)
+		second := &TransactionRequest{
+			AccountNumber:   "ACC123456",
+			Amount:          secondAmount,
+			TransactionType: "debit",
+		}
+		_, err = second.createTransaction(context.Background(), pendingRequests, nil, "")
+		assert.ErrorIs(t, err, pending.ErrTooManyPending)
 	})
 
-	t.Run("Publishing error", func(t *testing.T) {
-		// Setup mock channel that returns an error
-		mockChannel := new(internal.MockAMQPChannel)
-		mockChannel.On("PublishWithContext",
-			mock.Anything, "", "test_queue", false, false, mock.Anything).Return(errors.New("publish error"))
+	t.Run("Retried request with the same Idempotency-Key replays the original response", func(t *testing.T) {
+		pendingRequests := newTestPendingRequests()
+
+		amount, err := money.FromString("200.75", "USD")
+		assert.NoError(t, err)
+
+		var storedHash string
+		var storedResponse []byte
+		db := &internal.MockPgDBConnection{
+			BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+				return &internal.MockPgDBConnection{
+					ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+						if storedHash == "" {
+							storedHash = arguments[1].(string)
+							storedResponse = arguments[7].([]byte)
+							return pgconn.NewCommandTag("INSERT 0 1"), nil
+						}
+						return pgconn.NewCommandTag("INSERT 0 0"), nil
+					},
+					QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+						return &internal.MockPgxRow{
+							ScanFunc: func(dest ...interface{}) error {
+								*dest[0].(*string) = storedHash
+								*dest[1].(*string) = "ACC123456"
+								*dest[2].(*money.Amount) = amount
+								*dest[3].(*string) = "USD"
+								*dest[4].(*string) = "credit"
+								*dest[5].(*string) = "keep-this-id"
+								*dest[6].(*[]byte) = storedResponse
+								*dest[7].(*time.Time) = time.Now()
+								*dest[8].(*time.Time) = time.Now().Add(time.Hour)
+								return nil
+							},
+						}
+					},
+				}, nil
+			},
+		}
+		idempotencyStore := idempotency.NewStore(db, time.Hour)
 
-		// Create request
 		req := &TransactionRequest{
 			AccountNumber:   "ACC123456",
-			Amount:          200.75,
-			TransactionType: "debit",
-			Description:     "Test transaction",
+			Amount:          amount,
+			TransactionType: "credit",
+			TransactionID:   "keep-this-id",
+		}
+		first, err := req.createTransaction(context.Background(), pendingRequests, idempotencyStore, "idem-key-1")
+		assert.NoError(t, err)
+
+		retry := &TransactionRequest{
+			AccountNumber:   "ACC123456",
+			Amount:          amount,
+			TransactionType: "credit",
 		}
+		second, err := retry.createTransaction(context.Background(), pendingRequests, idempotencyStore, "idem-key-1")
+		assert.NoError(t, err)
+		assert.Equal(t, first.TransactionID, second.TransactionID)
+	})
 
-		// Call method
-		resp, err := req.createTransaction(context.Background(), mockChannel, "test_queue")
+	t.Run("Reusing an Idempotency-Key with a different payload returns a conflict", func(t *testing.T) {
+		pendingRequests := newTestPendingRequests()
 
-		// Assert error
-		assert.Error(t, err)
-		assert.Equal(t, "publish error", err.Error())
+		storedAmount, err := money.FromString("200.75", "USD")
+		assert.NoError(t, err)
+		storedHash := idempotency.Fingerprint("idem-key-2", "ACC123456", storedAmount, "credit")
+
+		db := &internal.MockPgDBConnection{
+			BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+				return &internal.MockPgDBConnection{
+					ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+						return pgconn.NewCommandTag("INSERT 0 0"), nil
+					},
+					QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+						return &internal.MockPgxRow{
+							ScanFunc: func(dest ...interface{}) error {
+								*dest[0].(*string) = storedHash
+								*dest[1].(*string) = "ACC123456"
+								*dest[2].(*money.Amount) = storedAmount
+								*dest[3].(*string) = "USD"
+								*dest[4].(*string) = "credit"
+								*dest[5].(*string) = "original-id"
+								*dest[6].(*[]byte) = []byte(`{"transactionID":"original-id"}`)
+								*dest[7].(*time.Time) = time.Now()
+								*dest[8].(*time.Time) = time.Now().Add(time.Hour)
+								return nil
+							},
+						}
+					},
+				}, nil
+			},
+		}
+		idempotencyStore := idempotency.NewStore(db, time.Hour)
 
-		// Verify response is empty
-		assert.Empty(t, resp.TransactionID)
-		assert.True(t, resp.CreatedAt.IsZero())
+		differentAmount, err := money.FromString("999.00", "USD") // differs from the stored reservation
+		assert.NoError(t, err)
+		req := &TransactionRequest{
+			AccountNumber:   "ACC123456",
+			Amount:          differentAmount,
+			TransactionType: "credit",
+		}
+		_, err = req.createTransaction(context.Background(), pendingRequests, idempotencyStore, "idem-key-2")
 
-		// Verify our mock was called as expected
-		mockChannel.AssertExpectations(t)
+		var conflict *idempotency.ConflictError
+		assert.ErrorAs(t, err, &conflict)
 	})
 }
@@ -1,19 +1,66 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/siddarth99/banking-ledger/api/handlers"
 	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/admin"
+	"github.com/siddarth99/banking-ledger/pkg/cluster"
+	"github.com/siddarth99/banking-ledger/pkg/deadletter"
+	"github.com/siddarth99/banking-ledger/pkg/esresilience"
+	"github.com/siddarth99/banking-ledger/pkg/idempotency"
+	"github.com/siddarth99/banking-ledger/pkg/pending"
+	"github.com/siddarth99/banking-ledger/pkg/resilientamqp"
+	"github.com/siddarth99/banking-ledger/pkg/webhook"
 )
 
+// amqpConfigFromEnv builds an internal.AMQPConfig around amqpURL, layering
+// in TLS and SASL material from the optional RABBITMQ_TLS_* environment
+// variables. Leaving them all unset reproduces a plain, unencrypted
+// connection, so this is a no-op for the existing local/docker-compose
+// setup; setting them unblocks running against a managed broker
+// (CloudAMQP, AWS MQ) that mandates TLS and/or client-cert auth.
+func amqpConfigFromEnv(amqpURL string) (internal.AMQPConfig, error) {
+	cfg := internal.AMQPConfig{
+		URL:                amqpURL,
+		InsecureSkipVerify: os.Getenv("RABBITMQ_TLS_INSECURE_SKIP_VERIFY") == "true",
+		SASLMechanism:      internal.SASLMechanism(os.Getenv("RABBITMQ_TLS_SASL_MECHANISM")),
+	}
+
+	for envVar, dest := range map[string]*[]byte{
+		"RABBITMQ_TLS_CA_FILE":   &cfg.CACertPEM,
+		"RABBITMQ_TLS_CERT_FILE": &cfg.ClientCertPEM,
+		"RABBITMQ_TLS_KEY_FILE":  &cfg.ClientKeyPEM,
+	} {
+		path := os.Getenv(envVar)
+		if path == "" {
+			continue
+		}
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return internal.AMQPConfig{}, fmt.Errorf("failed to read %s: %w", envVar, err)
+		}
+		*dest = pem
+	}
+
+	return cfg, nil
+}
+
 func main() {
 	router := gin.Default()
 
+	// Render any *errs.E a handler attaches via c.Error as application/problem+json
+	router.Use(handlers.ProblemJSON())
+
 	// CORS middleware
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
@@ -23,13 +70,19 @@ func main() {
 		AllowCredentials: true,
 	}))
 
+	amqpURL := "amqp://" +
+		os.Getenv("RABBITMQ_USER") + ":" +
+		os.Getenv("RABBITMQ_PASSWORD") + "@" +
+		os.Getenv("RABBITMQ_HOST") + ":" +
+		os.Getenv("RABBITMQ_PORT") + "/"
+
+	amqpConfig, err := amqpConfigFromEnv(amqpURL)
+	if err != nil {
+		panic(err)
+	}
+
 	// Create RabbitMQ connection
-	aqmpConn, err := internal.CreateAMQPConnection(
-		"amqp://" +
-			os.Getenv("RABBITMQ_USER") + ":" +
-			os.Getenv("RABBITMQ_PASSWORD") + "@" +
-			os.Getenv("RABBITMQ_HOST") + ":" +
-			os.Getenv("RABBITMQ_PORT") + "/")
+	aqmpConn, err := internal.CreateAMQPConnectionWithConfig(amqpConfig)
 	if err != nil {
 		panic(err)
 	}
@@ -45,41 +98,122 @@ func main() {
 
 	defer internal.CloseAMQPChannel(amqpChannel)
 
+	// account_creator messages that a consumer Nacks without requeueing (a
+	// malformed payload, say) are routed to account_creator.dlq via this
+	// dead-letter exchange instead of being lost, so an operator can inspect
+	// them later.
+	const accountCreatorQueueName = "account_creator"
+	accountCreatorDLX := accountCreatorQueueName + ".dlx"
+	accountCreatorDLQ := accountCreatorQueueName + ".dlq"
+	if err := internal.ExchangeDeclare(amqpChannel, accountCreatorDLX, "direct", true, false); err != nil {
+		panic(err)
+	}
+	if _, err := internal.QueueDeclare(amqpChannel, accountCreatorDLQ, true, false, false, false, nil); err != nil {
+		panic(err)
+	}
+	if err := internal.QueueBind(amqpChannel, accountCreatorDLQ, accountCreatorQueueName, accountCreatorDLX); err != nil {
+		panic(err)
+	}
+
 	// Declare queue
-	createAccountQueue, err := internal.QueueDeclare(amqpChannel, "account_creator", true, false, false, false)
+	createAccountQueue, err := internal.QueueDeclare(amqpChannel, accountCreatorQueueName, true, false, false, false, amqp.Table{
+		"x-dead-letter-exchange": accountCreatorDLX,
+	})
 	if err != nil {
 		panic(err)
 	}
-	router.POST("/createAccount", handlers.CreateAccountHandler(&ctx, amqpChannel, createAccountQueue.Name))
 
 	// Declare queue
-	transactionQueue, err := internal.QueueDeclare(amqpChannel, "transaction_processor", true, false, false, false)
+	transactionQueue, err := internal.QueueDeclare(amqpChannel, "transaction_processor", true, false, false, false, nil)
 	if err != nil {
 		panic(err)
 	}
 
-	router.POST("/transact", handlers.TransactionHandler(&ctx, amqpChannel, transactionQueue.Name))
+	// transactionRetryTopology gives a worker that fails to process a
+	// transaction_processor delivery somewhere to route it other than
+	// straight back onto the queue: a ladder of delay/retry queues, and a
+	// terminal dead queue once every rung has been tried. RequeueTransactionHandler
+	// and GetDeadLetteredTransactionsHandler below share it with the worker.
+	transactionRetryTopology := deadletter.New(transactionQueue.Name, deadletter.DefaultDelays)
+	if err := transactionRetryTopology.Declare(amqpChannel); err != nil {
+		panic(err)
+	}
+
+	// resilientClient transparently redials and resumes publishing after a
+	// RabbitMQ restart, so CompleteTransactionHandler doesn't need the
+	// service itself restarted to recover.
+	resilientAMQPClient, err := resilientamqp.New(func() (internal.AMQPConnection, error) {
+		return internal.CreateAMQPConnectionWithConfig(amqpConfig)
+	}, resilientamqp.DefaultBaseBackoff, resilientamqp.DefaultMaxBackoff)
+	if err != nil {
+		panic(err)
+	}
+	defer resilientAMQPClient.Close()
+
+	// Connect to Postgres to back the pending-transaction approval store
+	pgConn, err := pgx.Connect(context.Background(), fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_NAME")))
+	if err != nil {
+		panic(fmt.Sprintf("Error connecting to database: %s", err))
+	}
+	defer pgConn.Close(context.Background())
+
+	pendingRequests := pending.NewRequests(pgConn, 15*time.Minute, 10)
+	idempotencyStore := idempotency.NewStore(pgConn, 24*time.Hour)
+	webhookStore := webhook.NewStore(pgConn)
 
 	// Initialize Elasticsearch client
 	esConfig := elasticsearch.Config{
 		Addresses: []string{os.Getenv("ELASTICSEARCH_URL")},
 	}
 
-	esClient, err := internal.NewElasticsearchClient(esConfig)
+	rawEsClient, err := internal.NewElasticsearchClient(esConfig)
 	if err != nil {
 		panic(fmt.Sprintf("Error creating Elasticsearch client: %s", err))
 	}
 
 	// Test the connection
-	res, err := esClient.Info()
+	res, err := rawEsClient.Info()
 	if err != nil {
 		panic(fmt.Sprintf("Error getting Elasticsearch info: %s", err))
 	}
 	defer res.Body.Close()
 
-	router.GET("/account/:accountNumber/transactionHistory", handlers.GetTransactionHistoryHandler(esClient))
+	// esresilience retries transient failures and trips a circuit breaker on
+	// a sustained outage, so a downed cluster fails fast instead of every
+	// handler blocking on the same timeout.
+	esClient := esresilience.New(rawEsClient, esresilience.Config{})
+
+	router.POST("/createAccount", handlers.CreateAccountHandler(&ctx, amqpChannel, createAccountQueue.Name, esClient, idempotencyStore, webhookStore, handlers.DefaultPublishConfirmTimeout))
+	router.POST("/subscriptions", handlers.CreateSubscriptionHandler(webhookStore))
+	router.POST("/transact", handlers.TransactionHandler(&ctx, pendingRequests, idempotencyStore))
+	router.POST("/transact/sync", handlers.SynchronousTransactionHandler(&ctx, amqpChannel, transactionQueue.Name, handlers.DefaultSynchronousReplyTimeout))
+	router.POST("/transactions/:id/complete", handlers.CompleteTransactionHandler(&ctx, pendingRequests, resilientAMQPClient, transactionQueue.Name, handlers.DefaultCompletePublishConfirmTimeout))
+	router.POST("/transactions/:id/discard", handlers.DiscardTransactionHandler(&ctx, pendingRequests))
 
-	router.GET("/account/status/:referenceId", handlers.GetAccountStatusHandler(esClient))
+	router.GET("/account/:accountNumber/transactionHistory", handlers.GetTransactionHistoryHandler(esClient))
+	router.POST("/transactions/search", handlers.SearchTransactionsHandler(esClient))
+	router.GET("/accounts/:accountNumber/ledger", handlers.GetAccountLedgerHandler(pgConn))
+	router.GET("/ledger/proof/:transactionId", handlers.GetLedgerProofHandler(pgConn))
+
+	statusCache := internal.NewLRUCache[string, handlers.AccountStatusResponse](handlers.DefaultStatusCacheSize)
+	router.GET("/account/status/:referenceId", handlers.GetAccountStatusHandler(esClient, statusCache))
+	router.GET("/transactions/:referenceId/error", handlers.GetTransactionErrorHandler(esClient))
+
+	heartbeats := admin.NewHeartbeats(pgConn)
+	adminRoutes := router.Group("/admin", handlers.AdminAuth(os.Getenv("ADMIN_API_TOKEN")))
+	adminRoutes.GET("/queues", handlers.GetQueueStatsHandler(amqpChannel, []string{createAccountQueue.Name, transactionQueue.Name}))
+	adminRoutes.GET("/workers", handlers.GetWorkerStatusHandler(heartbeats))
+	adminRoutes.POST("/transactions/:id/requeue", handlers.RequeueTransactionHandler(esClient, amqpChannel, transactionRetryTopology))
+	adminRoutes.GET("/transactions/dead", handlers.GetDeadLetteredTransactionsHandler(amqpChannel, transactionRetryTopology))
+	adminRoutes.POST("/workers/drain", handlers.DrainWorkersHandler(pgConn))
+
+	router.GET("/cluster/status", handlers.GetClusterStatusHandler(pgConn, cluster.DefaultLeaseTTL))
+	router.GET("/health/elasticsearch", handlers.GetElasticsearchHealthHandler(esClient))
 
 	router.Run(":8080")
 }
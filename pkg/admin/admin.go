@@ -0,0 +1,153 @@
+// Package admin implements the Postgres-backed bookkeeping behind the API
+// service's operator-facing endpoints: queue inspection, worker heartbeats,
+// and cooperative drain signaling.
+package admin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// QueueStats summarizes a single RabbitMQ queue's depth and consumer
+// activity, as surfaced by GET /admin/queues.
+type QueueStats struct {
+	Name      string `json:"name"`
+	Messages  int    `json:"messages"`
+	Consumers int    `json:"consumers"`
+}
+
+// InspectQueues reports depth and consumer count for each of queueNames
+// using the AMQP protocol's queue.declare(passive) semantics
+// (amqp.Channel.QueueInspect), so it requires no additional dependency on
+// the RabbitMQ management HTTP API.
+func InspectQueues(channel internal.AMQPQueueInspector, queueNames []string) ([]QueueStats, error) {
+	stats := make([]QueueStats, 0, len(queueNames))
+	for _, name := range queueNames {
+		q, err := internal.QueueInspect(channel, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect queue %q: %w", name, err)
+		}
+		stats = append(stats, QueueStats{
+			Name:      name,
+			Messages:  q.Messages,
+			Consumers: q.Consumers,
+		})
+	}
+	return stats, nil
+}
+
+// WorkerStatus is a single worker's last-known activity, as persisted to the
+// worker_status table and surfaced by GET /admin/workers.
+type WorkerStatus struct {
+	WorkerID              string    `json:"workerID"`
+	LastHeartbeat         time.Time `json:"lastHeartbeat"`
+	InFlightTransactionID string    `json:"inFlightTransactionID,omitempty"`
+	Processed             int64     `json:"processed"`
+	Failed                int64     `json:"failed"`
+}
+
+// Heartbeats is a Postgres-backed index of per-worker activity. Workers call
+// Heartbeat as they pick up and finish messages; the API service calls List
+// to serve GET /admin/workers.
+type Heartbeats struct {
+	db internal.PgDBConnection
+}
+
+// NewHeartbeats creates a Heartbeats store backed by db.
+func NewHeartbeats(db internal.PgDBConnection) *Heartbeats {
+	return &Heartbeats{db: db}
+}
+
+const heartbeatUpsertQuery = `
+	INSERT INTO worker_status (
+		worker_id, last_heartbeat, in_flight_transaction_id, processed, failed
+	) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (worker_id) DO UPDATE SET
+		last_heartbeat = excluded.last_heartbeat,
+		in_flight_transaction_id = excluded.in_flight_transaction_id,
+		processed = worker_status.processed + excluded.processed,
+		failed = worker_status.failed + excluded.failed
+`
+
+// Heartbeat records that workerID is alive, optionally working on
+// inFlightTransactionID (empty if idle), and adds processedDelta/failedDelta
+// to its running counters.
+func (h *Heartbeats) Heartbeat(ctx context.Context, workerID, inFlightTransactionID string, processedDelta, failedDelta int64) error {
+	_, err := h.db.Exec(ctx, heartbeatUpsertQuery, workerID, time.Now(), inFlightTransactionID, processedDelta, failedDelta)
+	return err
+}
+
+const listWorkerStatusQuery = `
+	SELECT worker_id, last_heartbeat, in_flight_transaction_id, processed, failed
+	FROM worker_status
+	ORDER BY worker_id
+`
+
+// List returns the last-known status of every worker that has ever called
+// Heartbeat.
+func (h *Heartbeats) List(ctx context.Context) ([]WorkerStatus, error) {
+	rows, err := h.db.Query(ctx, listWorkerStatusQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worker status: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := make([]WorkerStatus, 0)
+	for rows.Next() {
+		var s WorkerStatus
+		if err := rows.Scan(&s.WorkerID, &s.LastHeartbeat, &s.InFlightTransactionID, &s.Processed, &s.Failed); err != nil {
+			return nil, fmt.Errorf("failed to read worker status: %w", err)
+		}
+		statuses = append(statuses, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read worker status: %w", err)
+	}
+	return statuses, nil
+}
+
+// broadcastWorkerID marks a drain request that applies to every worker,
+// rather than a single worker_id, since operators typically want to drain
+// the whole fleet before a deploy.
+const broadcastWorkerID = "*"
+
+const requestDrainQuery = `
+	INSERT INTO worker_control (worker_id, drain_requested)
+	VALUES ($1, true)
+	ON CONFLICT (worker_id) DO UPDATE SET drain_requested = true
+`
+
+// RequestDrain asks every worker to stop consuming new messages once their
+// current in-flight message finishes. Workers observe this the next time
+// they call ShouldDrain.
+func RequestDrain(ctx context.Context, db internal.PgDBConnection) error {
+	_, err := db.Exec(ctx, requestDrainQuery, broadcastWorkerID)
+	return err
+}
+
+const shouldDrainQuery = `
+	SELECT drain_requested FROM worker_control WHERE worker_id IN ($1, $2)
+	ORDER BY drain_requested DESC LIMIT 1
+`
+
+// ShouldDrain reports whether workerID (or the whole fleet, via
+// RequestDrain) has been asked to stop consuming new messages.
+func ShouldDrain(ctx context.Context, db internal.PgDBConnection, workerID string) (bool, error) {
+	row, err := db.Query(ctx, shouldDrainQuery, workerID, broadcastWorkerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check drain status: %w", err)
+	}
+	defer row.Close()
+
+	if !row.Next() {
+		return false, row.Err()
+	}
+	var drainRequested bool
+	if err := row.Scan(&drainRequested); err != nil {
+		return false, fmt.Errorf("failed to read drain status: %w", err)
+	}
+	return drainRequested, row.Err()
+}
@@ -0,0 +1,133 @@
+package admin_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/admin"
+)
+
+func TestInspectQueues(t *testing.T) {
+	channel := &internal.MockAMQPChannel{}
+	channel.On("QueueInspect", "account_creator").Return(amqp.Queue{Name: "account_creator", Messages: 3, Consumers: 2}, nil)
+	channel.On("QueueInspect", "transaction_processor").Return(amqp.Queue{Name: "transaction_processor", Messages: 0, Consumers: 1}, nil)
+
+	stats, err := admin.InspectQueues(channel, []string{"account_creator", "transaction_processor"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []admin.QueueStats{
+		{Name: "account_creator", Messages: 3, Consumers: 2},
+		{Name: "transaction_processor", Messages: 0, Consumers: 1},
+	}, stats)
+}
+
+func TestInspectQueues_PropagatesError(t *testing.T) {
+	channel := &internal.MockAMQPChannel{}
+	channel.On("QueueInspect", "account_creator").Return(amqp.Queue{}, errors.New("channel closed"))
+
+	stats, err := admin.InspectQueues(channel, []string{"account_creator"})
+
+	assert.Error(t, err)
+	assert.Nil(t, stats)
+}
+
+func TestHeartbeats_HeartbeatAndList(t *testing.T) {
+	lastHeartbeat := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var execSQL string
+	var execArgs []any
+	db := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			execSQL = sql
+			execArgs = arguments
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			row := 0
+			return &internal.MockPgxRows{
+				NextFunc: func() bool {
+					row++
+					return row <= 1
+				},
+				ScanFunc: func(dest ...interface{}) error {
+					*dest[0].(*string) = "worker-0"
+					*dest[1].(*time.Time) = lastHeartbeat
+					*dest[2].(*string) = "TX1"
+					*dest[3].(*int64) = 5
+					*dest[4].(*int64) = 1
+					return nil
+				},
+			}, nil
+		},
+	}
+
+	heartbeats := admin.NewHeartbeats(db)
+
+	err := heartbeats.Heartbeat(context.Background(), "worker-0", "TX1", 1, 0)
+	assert.NoError(t, err)
+	assert.Contains(t, execSQL, "INSERT INTO worker_status")
+	assert.Equal(t, []any{"worker-0", "TX1", int64(1), int64(0)}, []any{execArgs[0], execArgs[2], execArgs[3], execArgs[4]})
+
+	statuses, err := heartbeats.List(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []admin.WorkerStatus{{
+		WorkerID:              "worker-0",
+		LastHeartbeat:         lastHeartbeat,
+		InFlightTransactionID: "TX1",
+		Processed:             5,
+		Failed:                1,
+	}}, statuses)
+}
+
+func TestRequestDrainAndShouldDrain(t *testing.T) {
+	var execArgs []any
+	db := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			execArgs = arguments
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			row := 0
+			return &internal.MockPgxRows{
+				NextFunc: func() bool {
+					row++
+					return row <= 1
+				},
+				ScanFunc: func(dest ...interface{}) error {
+					*dest[0].(*bool) = true
+					return nil
+				},
+			}, nil
+		},
+	}
+
+	err := admin.RequestDrain(context.Background(), db)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"*"}, execArgs)
+
+	shouldDrain, err := admin.ShouldDrain(context.Background(), db, "worker-0")
+	assert.NoError(t, err)
+	assert.True(t, shouldDrain)
+}
+
+func TestShouldDrain_NoRowsMeansNotDraining(t *testing.T) {
+	db := &internal.MockPgDBConnection{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			return &internal.MockPgxRows{
+				NextFunc: func() bool { return false },
+			}, nil
+		},
+	}
+
+	shouldDrain, err := admin.ShouldDrain(context.Background(), db, "worker-0")
+
+	assert.NoError(t, err)
+	assert.False(t, shouldDrain)
+}
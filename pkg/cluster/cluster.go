@@ -0,0 +1,287 @@
+// Package cluster implements leader election for a fleet of otherwise
+// identical queue-consumer workers. Only the elected leader should consume
+// from a given queue; the rest stand by and take over automatically once
+// the leader's lease expires, so a single account_queue/transaction_queue
+// is never processed by two instances at once.
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// DefaultLeaseTTL and DefaultRefreshInterval are sane defaults for the
+// lease algorithm: a lease outlives a single refresh by a wide enough
+// margin to absorb a couple of missed ticks before failover kicks in.
+const (
+	DefaultLeaseTTL        = 10 * time.Second
+	DefaultRefreshInterval = 3 * time.Second
+)
+
+// leaseRowID is the single row cluster_leader_lease ever holds; there is
+// exactly one leader per cluster, so there is no need to key it on
+// anything else.
+const leaseRowID = 1
+
+// HeartBeat is a single node's most recently published liveness record, as
+// stored in the cluster_nodes table.
+type HeartBeat struct {
+	NodeID   string
+	SiteURL  string
+	LastSeen time.Time
+	Epoch    int64
+}
+
+// NodeInfo is a cluster member as reported by Members, annotated with
+// whether it currently holds the leader lease.
+type NodeInfo struct {
+	NodeID   string
+	SiteURL  string
+	LastSeen time.Time
+	IsLeader bool
+}
+
+// Controller participates in leader election on behalf of a single node. It
+// heartbeats its own liveness and contends for the shared leader lease via
+// a TTL-fenced compare-and-set against Postgres: the candidate that
+// successfully moves cluster_leader_lease.node_id to its own NodeID, bumping
+// epoch, becomes leader until its lease expires or another node takes over.
+// Epoch fencing means a leader that stalls past the TTL and resumes later
+// can tell, by comparing the epoch it was elected under against the
+// lease's current epoch, that it is no longer current - it should not act
+// as leader even if it doesn't yet know it lost the lease.
+type Controller struct {
+	db       internal.PgDBConnection
+	nodeID   string
+	siteURL  string
+	leaseTTL time.Duration
+
+	mu       sync.Mutex
+	isLeader bool
+	epoch    int64
+	members  []NodeInfo
+	onChange []func(bool)
+}
+
+// NewController creates a Controller for nodeID, advertising siteURL to
+// peers. leaseTTL bounds how long a lease survives without being renewed by
+// Tick before another node may claim it.
+func NewController(db internal.PgDBConnection, nodeID, siteURL string, leaseTTL time.Duration) *Controller {
+	return &Controller{
+		db:       db,
+		nodeID:   nodeID,
+		siteURL:  siteURL,
+		leaseTTL: leaseTTL,
+	}
+}
+
+// OnLeaderChange registers fn to be called whenever Tick observes this
+// node's leadership status change. fn runs synchronously on the goroutine
+// that called Tick, so it should not block.
+func (c *Controller) OnLeaderChange(fn func(bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = append(c.onChange, fn)
+}
+
+// IsLeader reports whether this node currently holds the leader lease, as
+// of the last call to Tick.
+func (c *Controller) IsLeader(ctx context.Context) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.isLeader
+}
+
+// Epoch returns the fencing epoch this node was last elected (or observed
+// the current leader to be elected) under, as of the last call to Tick.
+func (c *Controller) Epoch() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.epoch
+}
+
+// Members lists every node that heartbeat within leaseTTL, as of the last
+// call to Tick, annotated with which one currently holds the leader lease.
+func (c *Controller) Members() []NodeInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	members := make([]NodeInfo, len(c.members))
+	copy(members, c.members)
+	return members
+}
+
+const upsertHeartbeatQuery = `
+	INSERT INTO cluster_nodes (node_id, site_url, last_seen, epoch)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (node_id) DO UPDATE SET
+		site_url = excluded.site_url,
+		last_seen = excluded.last_seen,
+		epoch = excluded.epoch
+`
+
+const selectLeaseForUpdateQuery = `
+	SELECT node_id, epoch, expires_at FROM cluster_leader_lease WHERE id = $1 FOR UPDATE
+`
+
+const insertLeaseQuery = `
+	INSERT INTO cluster_leader_lease (id, node_id, epoch, expires_at) VALUES ($1, $2, $3, $4)
+`
+
+const updateLeaseQuery = `
+	UPDATE cluster_leader_lease SET node_id = $2, epoch = $3, expires_at = $4 WHERE id = $1
+`
+
+const selectMembersQuery = `
+	SELECT node_id, site_url, last_seen, epoch FROM cluster_nodes WHERE last_seen > $1
+`
+
+const selectLeaseQuery = `
+	SELECT node_id FROM cluster_leader_lease WHERE id = $1 AND expires_at > $2
+`
+
+// Status reports every node that has heartbeat within leaseTTL and which
+// one currently holds the leader lease, without itself participating in
+// the election. It's meant for read-only observers like GET /cluster/status
+// that want to report on the cluster without becoming a candidate node.
+func Status(ctx context.Context, db internal.PgDBConnection, leaseTTL time.Duration) ([]NodeInfo, error) {
+	rows, err := db.Query(ctx, selectLeaseQuery, leaseRowID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leader lease: %w", err)
+	}
+	var leaderNodeID string
+	if rows.Next() {
+		if err := rows.Scan(&leaderNodeID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read leader lease: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("failed to read leader lease: %w", err)
+	}
+	rows.Close()
+
+	return queryMembers(ctx, db, leaderNodeID, leaseTTL)
+}
+
+// Tick runs a single round of the election protocol: it attempts to acquire
+// or renew the leader lease, heartbeats this node's liveness, and refreshes
+// the cached member list. It is safe to call concurrently from several
+// nodes racing for the same lease - the loser's attempt blocks on the
+// lease row's lock and then observes the winner's committed state.
+func (c *Controller) Tick(ctx context.Context) error {
+	isLeader, leaderNodeID, epoch, err := c.electLeader(ctx)
+	if err != nil {
+		return err
+	}
+
+	members, err := c.listMembers(ctx, leaderNodeID, epoch)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	leadershipChanged := c.isLeader != isLeader
+	c.isLeader = isLeader
+	c.epoch = epoch
+	c.members = members
+	callbacks := append([]func(bool){}, c.onChange...)
+	c.mu.Unlock()
+
+	if leadershipChanged {
+		for _, fn := range callbacks {
+			fn(isLeader)
+		}
+	}
+	return nil
+}
+
+// electLeader performs the lease compare-and-set and reports whether this
+// node holds it afterwards, which node does (itself, if isLeader), and the
+// epoch it was elected (or observed the current leader to be elected) under.
+func (c *Controller) electLeader(ctx context.Context) (isLeader bool, leaderNodeID string, epoch int64, err error) {
+	tx, err := c.db.Begin(ctx)
+	if err != nil {
+		return false, "", 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+
+	var currentNodeID string
+	var currentEpoch int64
+	var expiresAt time.Time
+	row := tx.QueryRow(ctx, selectLeaseForUpdateQuery, leaseRowID)
+	if scanErr := row.Scan(&currentNodeID, &currentEpoch, &expiresAt); scanErr != nil {
+		// No lease row yet: this node claims the very first lease.
+		if _, err := tx.Exec(ctx, insertLeaseQuery, leaseRowID, c.nodeID, 1, now.Add(c.leaseTTL)); err != nil {
+			return false, "", 0, fmt.Errorf("failed to create leader lease: %w", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return false, "", 0, fmt.Errorf("failed to commit election: %w", err)
+		}
+		return true, c.nodeID, 1, nil
+	}
+
+	if currentNodeID != c.nodeID && now.Before(expiresAt) {
+		// Someone else holds an unexpired lease - stand down.
+		if err := tx.Commit(ctx); err != nil {
+			return false, "", 0, fmt.Errorf("failed to commit election: %w", err)
+		}
+		return false, currentNodeID, currentEpoch, nil
+	}
+
+	newEpoch := currentEpoch
+	if currentNodeID != c.nodeID {
+		// The previous leader's lease expired: bump the epoch so any stale
+		// leader that resumes can tell it's been fenced out.
+		newEpoch = currentEpoch + 1
+	}
+
+	if _, err := tx.Exec(ctx, updateLeaseQuery, leaseRowID, c.nodeID, newEpoch, now.Add(c.leaseTTL)); err != nil {
+		return false, "", 0, fmt.Errorf("failed to acquire leader lease: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, "", 0, fmt.Errorf("failed to commit election: %w", err)
+	}
+	return true, c.nodeID, newEpoch, nil
+}
+
+// listMembers heartbeats this node's own liveness and reports every node
+// that has done the same within leaseTTL, marking whichever one holds the
+// lease as elected under epoch.
+func (c *Controller) listMembers(ctx context.Context, leaderNodeID string, epoch int64) ([]NodeInfo, error) {
+	if _, err := c.db.Exec(ctx, upsertHeartbeatQuery, c.nodeID, c.siteURL, time.Now(), epoch); err != nil {
+		return nil, fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+	return queryMembers(ctx, c.db, leaderNodeID, c.leaseTTL)
+}
+
+// queryMembers reports every node in cluster_nodes that has heartbeat
+// within leaseTTL, marking whichever one matches leaderNodeID as leader.
+func queryMembers(ctx context.Context, db internal.PgDBConnection, leaderNodeID string, leaseTTL time.Duration) ([]NodeInfo, error) {
+	rows, err := db.Query(ctx, selectMembersQuery, time.Now().Add(-leaseTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]NodeInfo, 0)
+	for rows.Next() {
+		var hb HeartBeat
+		if err := rows.Scan(&hb.NodeID, &hb.SiteURL, &hb.LastSeen, &hb.Epoch); err != nil {
+			return nil, fmt.Errorf("failed to read cluster member: %w", err)
+		}
+		members = append(members, NodeInfo{
+			NodeID:   hb.NodeID,
+			SiteURL:  hb.SiteURL,
+			LastSeen: hb.LastSeen,
+			IsLeader: hb.NodeID == leaderNodeID,
+		})
+	}
+	return members, rows.Err()
+}
@@ -0,0 +1,220 @@
+package cluster_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/cluster"
+)
+
+// noLeaseYet simulates an empty cluster_leader_lease table: the first
+// candidate to Tick should claim the lease outright.
+func noLeaseYet() *internal.MockPgDBConnection {
+	return &internal.MockPgDBConnection{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			return &internal.MockPgxRow{
+				ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows },
+			}
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
+	}
+}
+
+// heldBy simulates an existing lease row held by nodeID under epoch,
+// expiring at expiresAt.
+func heldBy(nodeID string, epoch int64, expiresAt time.Time) *internal.MockPgDBConnection {
+	return &internal.MockPgDBConnection{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			return &internal.MockPgxRow{
+				ScanFunc: func(dest ...interface{}) error {
+					*dest[0].(*string) = nodeID
+					*dest[1].(*int64) = epoch
+					*dest[2].(*time.Time) = expiresAt
+					return nil
+				},
+			}
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("UPDATE 1"), nil
+		},
+	}
+}
+
+func emptyMembers() *internal.MockPgxRows {
+	return &internal.MockPgxRows{NextFunc: func() bool { return false }}
+}
+
+func newController(db *internal.MockPgDBConnection, nodeID string, tx *internal.MockPgDBConnection) *cluster.Controller {
+	db.BeginFunc = func(ctx context.Context) (pgx.Tx, error) { return tx, nil }
+	if db.QueryFunc == nil {
+		db.QueryFunc = func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			return emptyMembers(), nil
+		}
+	}
+	return cluster.NewController(db, nodeID, "http://"+nodeID, cluster.DefaultLeaseTTL)
+}
+
+func TestController_FirstTickClaimsLease(t *testing.T) {
+	db := &internal.MockPgDBConnection{}
+	tx := noLeaseYet()
+	c := newController(db, "node-1", tx)
+
+	var transitions []bool
+	c.OnLeaderChange(func(isLeader bool) { transitions = append(transitions, isLeader) })
+
+	assert.NoError(t, c.Tick(context.Background()))
+
+	assert.True(t, c.IsLeader(context.Background()))
+	assert.Equal(t, int64(1), c.Epoch())
+	assert.Equal(t, []bool{true}, transitions, "OnLeaderChange should fire exactly once, on promotion")
+}
+
+func TestController_FollowerStandsDownWhileLeaseIsLive(t *testing.T) {
+	db := &internal.MockPgDBConnection{}
+	tx := heldBy("node-1", 4, time.Now().Add(cluster.DefaultLeaseTTL))
+	c := newController(db, "node-2", tx)
+
+	var consumeCalled bool
+	c.OnLeaderChange(func(isLeader bool) { consumeCalled = isLeader })
+
+	assert.NoError(t, c.Tick(context.Background()))
+
+	assert.False(t, c.IsLeader(context.Background()), "a follower must not act as leader while another node's lease is live")
+	assert.Equal(t, int64(4), c.Epoch())
+	assert.False(t, consumeCalled, "followers must not be told to start consuming until promoted")
+}
+
+func TestController_TakesOverAfterLeaseExpiresAndBumpsEpoch(t *testing.T) {
+	db := &internal.MockPgDBConnection{}
+	tx := heldBy("node-1", 4, time.Now().Add(-time.Second)) // expired
+	c := newController(db, "node-2", tx)
+
+	assert.NoError(t, c.Tick(context.Background()))
+
+	assert.True(t, c.IsLeader(context.Background()))
+	assert.Equal(t, int64(5), c.Epoch(), "taking over from an expired lease must bump the epoch to fence out the stale leader")
+}
+
+func TestController_RenewingLeaseKeepsTheSameEpoch(t *testing.T) {
+	db := &internal.MockPgDBConnection{}
+	tx := heldBy("node-1", 4, time.Now().Add(cluster.DefaultLeaseTTL))
+	c := newController(db, "node-1", tx)
+
+	assert.NoError(t, c.Tick(context.Background()))
+
+	assert.True(t, c.IsLeader(context.Background()))
+	assert.Equal(t, int64(4), c.Epoch(), "renewing its own lease must not bump the epoch")
+}
+
+func TestController_MembersReflectsCurrentLeader(t *testing.T) {
+	db := &internal.MockPgDBConnection{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			row := 0
+			nodes := []string{"node-1", "node-2"}
+			return &internal.MockPgxRows{
+				NextFunc: func() bool {
+					row++
+					return row <= len(nodes)
+				},
+				ScanFunc: func(dest ...interface{}) error {
+					*dest[0].(*string) = nodes[row-1]
+					*dest[1].(*string) = "http://" + nodes[row-1]
+					*dest[2].(*time.Time) = time.Now()
+					*dest[3].(*int64) = 1
+					return nil
+				},
+			}, nil
+		},
+	}
+	tx := noLeaseYet()
+	c := newController(db, "node-1", tx)
+
+	assert.NoError(t, c.Tick(context.Background()))
+
+	members := c.Members()
+	assert.Len(t, members, 2)
+	for _, m := range members {
+		assert.Equal(t, m.NodeID == "node-1", m.IsLeader)
+	}
+}
+
+func TestStatus_ReportsLeaderWithoutParticipating(t *testing.T) {
+	leaseQueried := false
+	db := &internal.MockPgDBConnection{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			if !leaseQueried {
+				leaseQueried = true
+				return &internal.MockPgxRows{
+					NextFunc: func() bool { return true },
+					ScanFunc: func(dest ...interface{}) error {
+						*dest[0].(*string) = "node-1"
+						return nil
+					},
+				}, nil
+			}
+			row := 0
+			nodes := []string{"node-1", "node-2"}
+			return &internal.MockPgxRows{
+				NextFunc: func() bool {
+					row++
+					return row <= len(nodes)
+				},
+				ScanFunc: func(dest ...interface{}) error {
+					*dest[0].(*string) = nodes[row-1]
+					*dest[1].(*string) = "http://" + nodes[row-1]
+					*dest[2].(*time.Time) = time.Now()
+					*dest[3].(*int64) = 1
+					return nil
+				},
+			}, nil
+		},
+	}
+
+	members, err := cluster.Status(context.Background(), db, cluster.DefaultLeaseTTL)
+
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+	for _, m := range members {
+		assert.Equal(t, m.NodeID == "node-1", m.IsLeader)
+	}
+}
+
+func TestStatus_NoLeaseMeansNoLeader(t *testing.T) {
+	db := &internal.MockPgDBConnection{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			return &internal.MockPgxRows{NextFunc: func() bool { return false }}, nil
+		},
+	}
+
+	members, err := cluster.Status(context.Background(), db, cluster.DefaultLeaseTTL)
+
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+}
+
+func TestController_ElectionFailurePropagatesError(t *testing.T) {
+	db := &internal.MockPgDBConnection{}
+	tx := &internal.MockPgDBConnection{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.CommandTag{}, errors.New("connection reset")
+		},
+	}
+	c := newController(db, "node-1", tx)
+
+	err := c.Tick(context.Background())
+
+	assert.Error(t, err)
+	assert.False(t, c.IsLeader(context.Background()))
+}
@@ -0,0 +1,122 @@
+// Package consumer wraps AMQPChannel.Consume with a bounded-prefetch,
+// manual-ack delivery loop: it caps in-flight deliveries via channel.Qos,
+// ACKs a delivery when its handler returns nil, NACKs-and-requeues on a
+// transient error, and NACKs-without-requeue (letting any dead-letter
+// exchange catch it, see pkg/deadletter) when the handler returns
+// ErrPoison. Run honors ctx cancellation by cancelling its consumer tag,
+// draining whatever deliveries are already in flight, and returning -
+// giving a worker process clean shutdown behavior under a Kubernetes
+// SIGTERM/rollout instead of dropping messages mid-processing.
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// ErrPoison is returned by a Run handler to signal that a delivery is
+// malformed or otherwise can never succeed, however many times it's
+// redelivered - e.g. a JSON body that fails to unmarshal. Run NACKs such a
+// delivery without requeueing it, so a queue's dead-letter exchange routes
+// it somewhere for inspection instead of it being retried forever.
+var ErrPoison = errors.New("consumer: poison message")
+
+// ErrHandled is returned by a Run handler that has already ACKed or NACKed
+// the delivery itself - e.g. by routing it onto a deadletter.Topology retry
+// ladder, which ACKs the original delivery once it has published the next
+// rung. Run leaves such a delivery alone rather than ACKing it a second
+// time, which a real broker would reject with a channel-closing exception.
+var ErrHandled = errors.New("consumer: delivery already acknowledged by handler")
+
+// DefaultPrefetchCount caps how many unacknowledged deliveries the broker
+// will hand a Consumer at once, so one slow handler can't starve every
+// other consumer on the queue of its fair share of messages.
+const DefaultPrefetchCount = 10
+
+// Consumer drives a manual-ack delivery loop over a single queue.
+type Consumer struct {
+	// Channel is the AMQP channel to consume from.
+	Channel internal.AMQPChannel
+	// Queue is the name of the queue to consume.
+	Queue string
+	// ConsumerTag identifies this consumer so Run can Cancel it on
+	// shutdown.
+	ConsumerTag string
+	// PrefetchCount caps in-flight unacknowledged deliveries.
+	PrefetchCount int
+}
+
+// New builds a Consumer for queue on channel with the given prefetch
+// count. A zero or negative prefetchCount falls back to
+// DefaultPrefetchCount. The consumer tag defaults to "consumer-<queue>",
+// which is unique enough to Cancel reliably as long as a process doesn't
+// run two Consumers against the same queue on the same channel.
+func New(channel internal.AMQPChannel, queue string, prefetchCount int) *Consumer {
+	if prefetchCount <= 0 {
+		prefetchCount = DefaultPrefetchCount
+	}
+	return &Consumer{
+		Channel:       channel,
+		Queue:         queue,
+		ConsumerTag:   "consumer-" + queue,
+		PrefetchCount: prefetchCount,
+	}
+}
+
+// Run sets the channel's prefetch count, registers a manual-ack consumer,
+// and dispatches every delivery to handler until ctx is cancelled. A nil
+// return ACKs the delivery; ErrPoison NACKs it without requeueing; ErrHandled
+// leaves it alone because handler already ACKed or NACKed it itself; any
+// other error NACKs it with requeue so the broker redelivers it. On
+// ctx.Done, Run cancels its consumer tag so the broker stops sending new
+// deliveries, dispatches whatever is already in flight on the deliveries
+// channel, then returns once the broker closes it.
+func (c *Consumer) Run(ctx context.Context, handler func(amqp.Delivery) error) error {
+	if err := c.Channel.Qos(c.PrefetchCount, 0, false); err != nil {
+		return fmt.Errorf("failed to set QoS: %w", err)
+	}
+
+	deliveries, err := c.Channel.Consume(c.Queue, c.ConsumerTag, false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to register consumer %s on %s: %w", c.ConsumerTag, c.Queue, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := c.Channel.Cancel(c.ConsumerTag, false); err != nil {
+				return fmt.Errorf("failed to cancel consumer %s: %w", c.ConsumerTag, err)
+			}
+			for delivery := range deliveries {
+				dispatch(delivery, handler)
+			}
+			return nil
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return nil
+			}
+			dispatch(delivery, handler)
+		}
+	}
+}
+
+// dispatch runs handler against delivery and ACKs/NACKs it according to
+// the outcome.
+func dispatch(delivery amqp.Delivery, handler func(amqp.Delivery) error) {
+	err := handler(delivery)
+	switch {
+	case err == nil:
+		delivery.Ack(false)
+	case errors.Is(err, ErrHandled):
+		// handler already settled this delivery.
+	case errors.Is(err, ErrPoison):
+		delivery.Nack(false, false)
+	default:
+		delivery.Nack(false, true)
+	}
+}
@@ -0,0 +1,122 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// fakeAcknowledger records every Ack/Nack call a test delivery receives,
+// since amqp.Delivery.Ack/Nack need a non-nil Acknowledger to avoid erroring.
+type fakeAcknowledger struct {
+	acked    []uint64
+	nacked   []uint64
+	requeued []bool
+}
+
+func (f *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	f.acked = append(f.acked, tag)
+	return nil
+}
+
+func (f *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	f.nacked = append(f.nacked, tag)
+	f.requeued = append(f.requeued, requeue)
+	return nil
+}
+
+func (f *fakeAcknowledger) Reject(tag uint64, requeue bool) error { return nil }
+
+func TestConsumer_Run(t *testing.T) {
+	testCases := []struct {
+		name         string
+		handlerErr   error
+		wantAcked    bool
+		wantNacked   bool
+		wantRequeued bool
+	}{
+		{name: "nil error ACKs the delivery", handlerErr: nil, wantAcked: true},
+		{name: "ErrPoison NACKs without requeue", handlerErr: ErrPoison, wantNacked: true, wantRequeued: false},
+		{name: "wrapped ErrPoison NACKs without requeue", handlerErr: fmt.Errorf("unmarshal failed: %w", ErrPoison), wantNacked: true, wantRequeued: false},
+		{name: "transient error NACKs with requeue", handlerErr: errors.New("db unavailable"), wantNacked: true, wantRequeued: true},
+		{name: "ErrHandled leaves the delivery alone", handlerErr: ErrHandled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			channel := &internal.MockAMQPChannel{}
+			channel.On("Qos", DefaultPrefetchCount, 0, false).Return(nil)
+			channel.On("Cancel", "consumer-test_queue", false).Return(nil)
+
+			deliveries := make(chan amqp.Delivery, 1)
+			channel.On("Consume", "test_queue", "consumer-test_queue", false, false, false, false, nil).
+				Return((<-chan amqp.Delivery)(deliveries), nil)
+
+			ack := &fakeAcknowledger{}
+			deliveries <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 1}
+			close(deliveries)
+
+			c := New(channel, "test_queue", 0)
+			err := c.Run(context.Background(), func(amqp.Delivery) error {
+				return tc.handlerErr
+			})
+			assert.NoError(t, err)
+
+			switch {
+			case tc.wantAcked:
+				assert.Equal(t, []uint64{1}, ack.acked)
+				assert.Empty(t, ack.nacked)
+			case tc.wantNacked:
+				assert.Equal(t, []uint64{1}, ack.nacked)
+				assert.Empty(t, ack.acked)
+				assert.Equal(t, []bool{tc.wantRequeued}, ack.requeued)
+			default:
+				assert.Empty(t, ack.acked)
+				assert.Empty(t, ack.nacked)
+			}
+		})
+	}
+}
+
+func TestConsumer_Run_CancelsOnContextDone(t *testing.T) {
+	channel := &internal.MockAMQPChannel{}
+	channel.On("Qos", DefaultPrefetchCount, 0, false).Return(nil)
+	channel.On("Cancel", "consumer-test_queue", false).Return(nil)
+
+	deliveries := make(chan amqp.Delivery)
+	channel.On("Consume", "test_queue", "consumer-test_queue", false, false, false, false, nil).
+		Return((<-chan amqp.Delivery)(deliveries), nil)
+
+	ack := &fakeAcknowledger{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	c := New(channel, "test_queue", 0)
+	go func() {
+		done <- c.Run(ctx, func(amqp.Delivery) error { return nil })
+	}()
+
+	// In-flight delivery sent before cancellation must still be processed
+	// once Run notices ctx is done and starts draining.
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	deliveries <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 7}
+	close(deliveries)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after ctx cancellation and channel close")
+	}
+
+	assert.Equal(t, []uint64{7}, ack.acked)
+	channel.AssertExpectations(t)
+}
@@ -0,0 +1,164 @@
+// Package deadletter implements a delay-ladder retry topology on top of
+// internal.AMQPChannel: a worker that fails to process a delivery calls
+// Retry instead of Nacking it, which republishes the message onto the next
+// rung of per-delay queues (e.g. "<queue>.retry.1s"); each rung's
+// x-message-ttl/x-dead-letter-exchange arguments dead-letter it back onto
+// the main queue once its delay elapses. Once every rung has been tried,
+// Retry routes the message to a terminal "<queue>.dead" queue instead, for
+// operator inspection via ListDeadLettered.
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// DefaultDelays is the retry ladder TransactionHandler and the worker
+// register for transaction_processor: three rungs at 1s, 10s and 60s
+// before a message is given up on as terminally dead.
+var DefaultDelays = []time.Duration{time.Second, 10 * time.Second, 60 * time.Second}
+
+// Topology describes a delay-ladder retry policy for a single queue.
+type Topology struct {
+	// Queue is the main queue being retried, e.g. "transaction_processor".
+	Queue string
+	// Delays is the retry ladder, in order: the Nth retry waits Delays[n-1]
+	// before redelivery. A message still failing after every rung lands in
+	// DeadQueueName() instead.
+	Delays []time.Duration
+}
+
+// New builds a Topology for queue with the given retry ladder. A nil or
+// empty delays defaults to DefaultDelays.
+func New(queue string, delays []time.Duration) *Topology {
+	if len(delays) == 0 {
+		delays = DefaultDelays
+	}
+	return &Topology{Queue: queue, Delays: delays}
+}
+
+// DeadQueueName is the terminal queue a message lands in once it has
+// exhausted every rung of the retry ladder.
+func (t *Topology) DeadQueueName() string {
+	return t.Queue + ".dead"
+}
+
+// RetryQueueName is the name of the queue backing rung (0-indexed into
+// Delays).
+func (t *Topology) RetryQueueName(rung int) string {
+	return fmt.Sprintf("%s.retry.%s", t.Queue, t.Delays[rung])
+}
+
+// Declare creates every rung of the retry ladder and the terminal dead
+// queue against channel. Each rung dead-letters back onto t.Queue via the
+// default exchange once its x-message-ttl elapses, so redelivery needs no
+// exchange of its own; Retry is what actually routes a failed delivery onto
+// the right rung to begin with.
+func (t *Topology) Declare(channel internal.AMQPChannel) error {
+	for rung, delay := range t.Delays {
+		_, err := internal.QueueDeclare(channel, t.RetryQueueName(rung), true, false, false, false, amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": t.Queue,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue %s: %w", t.RetryQueueName(rung), err)
+		}
+	}
+	if _, err := internal.QueueDeclare(channel, t.DeadQueueName(), true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead queue %s: %w", t.DeadQueueName(), err)
+	}
+	return nil
+}
+
+// Attempts reports how many rungs d has already been retried through, by
+// counting the x-death entries the broker stamps onto a message each time a
+// distinct queue dead-letters it - one entry per rung this message has
+// passed through, since every rung is a differently-named queue.
+func Attempts(d amqp.Delivery) int {
+	deaths, _ := d.Headers["x-death"].([]interface{})
+	return len(deaths)
+}
+
+// Retry routes d onto the next rung of t's retry ladder, or onto the
+// terminal dead queue if Attempts(d) has already exhausted every rung. It
+// acks the original delivery either way, since a republish is a fresh
+// message and RabbitMQ has no way to move a delivery between queues
+// in-place.
+//
+// The republish carries d.Headers forward, in particular the broker-stamped
+// x-death history Attempts counts. Without that, every rung's TTL-expiry
+// dead-letter only ever stamps a single-entry x-death of its own (the
+// broker has no memory of earlier rungs on what looks to it like a brand
+// new message), so Attempts would never read past 1 and Retry would loop on
+// the same rung forever instead of escalating.
+func (t *Topology) Retry(ctx context.Context, publisher internal.AMQPQueuePublisher, d amqp.Delivery) error {
+	target := t.DeadQueueName()
+	if rung := Attempts(d); rung < len(t.Delays) {
+		target = t.RetryQueueName(rung)
+	}
+	if err := internal.PublishWithHeaders(ctx, d.Body, d.Headers, publisher, "", target, false, false); err != nil {
+		return fmt.Errorf("failed to route delivery to %s: %w", target, err)
+	}
+	return d.Ack(false)
+}
+
+// ConsumeWithRetryTopology declares t against channel and starts consuming
+// t.Queue, pairing Declare with Consume the way CreateAccountHandler pairs
+// QueueDeclare with Consume.
+func ConsumeWithRetryTopology(channel internal.AMQPChannel, t *Topology, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	if err := t.Declare(channel); err != nil {
+		return nil, err
+	}
+	return internal.Consume(channel, t.Queue, consumer, autoAck, exclusive, noLocal, noWait, args)
+}
+
+// PublishWithRetryTopology declares t against channel, then publishes body
+// to t.Queue via the default exchange. It pairs Declare with Publish for
+// callers that only ever publish onto the queue, such as
+// CompleteTransactionHandler, so the retry ladder exists before the first
+// message can fail into it.
+func PublishWithRetryTopology(ctx context.Context, channel internal.AMQPChannel, t *Topology, body []byte) error {
+	if err := t.Declare(channel); err != nil {
+		return err
+	}
+	return internal.PublishWithContext(ctx, body, channel, "", t.Queue, false, false)
+}
+
+// DeadLetteredTransaction is a single message peeked off a Topology's
+// terminal dead queue for operator inspection.
+type DeadLetteredTransaction struct {
+	Body     json.RawMessage `json:"body"`
+	Attempts int             `json:"attempts"`
+}
+
+// ListDeadLettered peeks up to limit messages off t's terminal dead queue
+// without removing them: each is fetched with autoAck false and then
+// Nacked with requeue true, so an operator can inspect the queue
+// repeatedly without draining it.
+func ListDeadLettered(channel internal.AMQPQueueGetter, t *Topology, limit int) ([]DeadLetteredTransaction, error) {
+	messages := make([]DeadLetteredTransaction, 0, limit)
+	for i := 0; i < limit; i++ {
+		d, ok, err := channel.Get(t.DeadQueueName(), false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get message from %s: %w", t.DeadQueueName(), err)
+		}
+		if !ok {
+			break
+		}
+		messages = append(messages, DeadLetteredTransaction{
+			Body:     json.RawMessage(d.Body),
+			Attempts: Attempts(d),
+		})
+		if err := d.Nack(false, true); err != nil {
+			return nil, fmt.Errorf("failed to requeue peeked message: %w", err)
+		}
+	}
+	return messages, nil
+}
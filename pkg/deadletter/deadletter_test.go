@@ -0,0 +1,194 @@
+package deadletter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/deadletter"
+)
+
+// fakeAcknowledger records every Ack/Nack call a test delivery receives,
+// since amqp.Delivery.Ack/Nack need a non-nil Acknowledger to avoid erroring.
+type fakeAcknowledger struct {
+	acked    []uint64
+	nacked   []uint64
+	requeued bool
+}
+
+func (f *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	f.acked = append(f.acked, tag)
+	return nil
+}
+
+func (f *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	f.nacked = append(f.nacked, tag)
+	f.requeued = requeue
+	return nil
+}
+
+func (f *fakeAcknowledger) Reject(tag uint64, requeue bool) error { return nil }
+
+func TestTopology_Declare(t *testing.T) {
+	channel := &internal.MockAMQPChannel{}
+	channel.On("QueueDeclare", "transaction_processor.retry.1s", true, false, false, false, amqp.Table{
+		"x-message-ttl":             int64(1000),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": "transaction_processor",
+	}).Return(nil)
+	channel.On("QueueDeclare", "transaction_processor.retry.10s", true, false, false, false, amqp.Table{
+		"x-message-ttl":             int64(10000),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": "transaction_processor",
+	}).Return(nil)
+	channel.On("QueueDeclare", "transaction_processor.retry.1m0s", true, false, false, false, amqp.Table{
+		"x-message-ttl":             int64(60000),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": "transaction_processor",
+	}).Return(nil)
+	channel.On("QueueDeclare", "transaction_processor.dead", true, false, false, false, amqp.Table(nil)).Return(nil)
+
+	topology := deadletter.New("transaction_processor", nil)
+	assert.NoError(t, topology.Declare(channel))
+	channel.AssertExpectations(t)
+}
+
+func TestAttempts_CountsXDeathEntries(t *testing.T) {
+	d := amqp.Delivery{Headers: amqp.Table{
+		"x-death": []interface{}{
+			amqp.Table{"queue": "transaction_processor.retry.1s"},
+			amqp.Table{"queue": "transaction_processor.retry.10s"},
+		},
+	}}
+	assert.Equal(t, 2, deadletter.Attempts(d))
+	assert.Equal(t, 0, deadletter.Attempts(amqp.Delivery{}))
+}
+
+func TestTopology_Retry_RoutesOntoNextRung(t *testing.T) {
+	channel := &internal.MockAMQPChannel{}
+	channel.On("PublishWithContext", mock.Anything, "", "transaction_processor.retry.1s", false, false, mock.Anything).Return(nil)
+
+	topology := deadletter.New("transaction_processor", nil)
+	ack := &fakeAcknowledger{}
+	d := amqp.Delivery{Acknowledger: ack, DeliveryTag: 7, Body: []byte(`{"id":"TX1"}`)}
+
+	assert.NoError(t, topology.Retry(context.Background(), channel, d))
+	assert.Equal(t, []uint64{7}, ack.acked)
+	channel.AssertExpectations(t)
+}
+
+func TestTopology_Retry_RoutesOntoDeadQueueAfterExhaustingRungs(t *testing.T) {
+	channel := &internal.MockAMQPChannel{}
+	channel.On("PublishWithContext", mock.Anything, "", "transaction_processor.dead", false, false, mock.Anything).Return(nil)
+
+	topology := deadletter.New("transaction_processor", nil)
+	ack := &fakeAcknowledger{}
+	d := amqp.Delivery{
+		Acknowledger: ack,
+		DeliveryTag:  9,
+		Body:         []byte(`{"id":"TX1"}`),
+		Headers: amqp.Table{
+			"x-death": []interface{}{
+				amqp.Table{"queue": "transaction_processor.retry.1s"},
+				amqp.Table{"queue": "transaction_processor.retry.10s"},
+				amqp.Table{"queue": "transaction_processor.retry.1m0s"},
+			},
+		},
+	}
+
+	assert.NoError(t, topology.Retry(context.Background(), channel, d))
+	assert.Equal(t, []uint64{9}, ack.acked)
+	channel.AssertExpectations(t)
+}
+
+// TestTopology_Retry_EscalatesAcrossSimulatedBrokerRoundTrips exercises the
+// actual escalation path instead of fabricating a delivery with a
+// pre-populated x-death array: each loop iteration feeds Retry's republished
+// headers back in as the next delivery, the way the broker's TTL-expiry
+// dead-lettering would - onto a message that only ever carries forward
+// whatever headers Retry itself set, there's no other source of x-death
+// history in this simulation. If Retry ever drops those headers (the bug
+// this test guards against), x-death never accumulates and every iteration
+// after the first routes back onto rung 0 instead of escalating.
+func TestTopology_Retry_EscalatesAcrossSimulatedBrokerRoundTrips(t *testing.T) {
+	topology := deadletter.New("transaction_processor", nil)
+	wantRungs := []string{
+		topology.RetryQueueName(0),
+		topology.RetryQueueName(1),
+		topology.RetryQueueName(2),
+		topology.DeadQueueName(),
+	}
+
+	var delivered []string
+	var lastHeaders amqp.Table
+	channel := &internal.MockAMQPChannel{}
+	for _, target := range wantRungs {
+		target := target
+		channel.On("PublishWithContext", mock.Anything, "", target, false, false, mock.Anything).
+			Run(func(args mock.Arguments) {
+				delivered = append(delivered, target)
+				lastHeaders = args.Get(5).(amqp.Publishing).Headers
+			}).Return(nil)
+	}
+
+	d := amqp.Delivery{Acknowledger: &fakeAcknowledger{}, Body: []byte(`{"id":"TX1"}`)}
+	for hop, rungQueue := range wantRungs {
+		assert.NoError(t, topology.Retry(context.Background(), channel, d))
+
+		if hop == len(wantRungs)-1 {
+			break // landed on the terminal dead queue; no further TTL hop
+		}
+
+		// Simulate the broker's TTL-expiry dead-letter: it stamps a new
+		// x-death entry for the rung queue the message just spent its TTL
+		// in, then redelivers it onto the main queue - this becomes the
+		// next delivery Retry sees.
+		deaths, _ := lastHeaders["x-death"].([]interface{})
+		deaths = append(deaths, amqp.Table{"queue": rungQueue})
+		d = amqp.Delivery{
+			Acknowledger: &fakeAcknowledger{},
+			Body:         []byte(`{"id":"TX1"}`),
+			Headers:      amqp.Table{"x-death": deaths},
+		}
+	}
+
+	assert.Equal(t, wantRungs, delivered)
+	channel.AssertExpectations(t)
+}
+
+func TestTopology_Retry_PropagatesPublishError(t *testing.T) {
+	channel := &internal.MockAMQPChannel{}
+	boom := errors.New("channel closed")
+	channel.On("PublishWithContext", mock.Anything, "", "transaction_processor.retry.1s", false, false, mock.Anything).Return(boom)
+
+	topology := deadletter.New("transaction_processor", nil)
+	ack := &fakeAcknowledger{}
+	d := amqp.Delivery{Acknowledger: ack, DeliveryTag: 1}
+
+	err := topology.Retry(context.Background(), channel, d)
+	assert.ErrorIs(t, err, boom)
+	assert.Empty(t, ack.acked)
+}
+
+func TestListDeadLettered_PeeksAndRequeues(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	channel := &internal.MockAMQPChannel{}
+	channel.On("Get", "transaction_processor.dead", false).Return(
+		amqp.Delivery{Acknowledger: ack, Body: []byte(`{"id":"TX1"}`), DeliveryTag: 1}, true, nil).Once()
+	channel.On("Get", "transaction_processor.dead", false).Return(
+		amqp.Delivery{}, false, nil).Once()
+
+	topology := deadletter.New("transaction_processor", nil)
+	messages, err := deadletter.ListDeadLettered(channel, topology, 10)
+
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.JSONEq(t, `{"id":"TX1"}`, string(messages[0].Body))
+	assert.Equal(t, []uint64{1}, ack.nacked)
+	assert.True(t, ack.requeued)
+}
@@ -14,6 +14,11 @@ type ElasticsearchClient interface {
 	Index(index string, body io.Reader) (*esapi.Response, error)
 	// Search searches the index in Elasticsearch
 	Search(indices []string, body io.Reader) (*esapi.Response, error)
+	// Bulk submits a newline-delimited _bulk request body. OutboxRelay
+	// (workers/processor/outbox.go) is the batching bulk-indexing pipeline
+	// built on this method; it supersedes the separate bulk-indexer asked
+	// for independently of the outbox.
+	Bulk(body io.Reader) (*esapi.Response, error)
 	// Info returns info about the cluster
 	Info() (*esapi.Response, error)
 }
@@ -57,7 +62,12 @@ func (e *ElasticsearchWrapper) Get(index, id string) (*esapi.Response, error) {
 	)
 }
 
+// Bulk submits a newline-delimited _bulk request body
+func (e *ElasticsearchWrapper) Bulk(body io.Reader) (*esapi.Response, error) {
+	return e.client.Bulk(body)
+}
+
 // Info returns info about the cluster
 func (e *ElasticsearchWrapper) Info() (*esapi.Response, error) {
 	return e.client.Info()
-}
\ No newline at end of file
+}
@@ -0,0 +1,121 @@
+// Package errs provides a small, Docker errdefs-style error taxonomy so
+// handlers can classify failures ("this account doesn't exist" vs. "the
+// search index is unreachable") without callers resorting to string
+// matching on error messages. api/handlers builds on top of it (see
+// ProblemJSON) to render the classification as an RFC 7807 problem+json
+// response.
+package errs
+
+import "errors"
+
+// Kind is the small, fixed set of failure classes a handler needs to pick
+// an HTTP status code. It intentionally doesn't try to cover every case -
+// anything that isn't one of these is just an internal error.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindInvalidArgument
+	KindUnavailable
+	KindConflict
+)
+
+// E is a classified error: Kind drives the HTTP status a problem+json
+// response renders, Code is a stable, dotted identifier (e.g.
+// "BANK.ACCOUNT_NOT_FOUND") callers can safely switch on, and cause is the
+// underlying error that actually explains what went wrong.
+type E struct {
+	Kind  Kind
+	Code  string
+	cause error
+}
+
+func (e *E) Error() string {
+	if e.cause == nil {
+		return e.Code
+	}
+	return e.cause.Error()
+}
+
+// Unwrap exposes cause so errors.Is/errors.As see through an *E to whatever
+// it wraps.
+func (e *E) Unwrap() error {
+	return e.cause
+}
+
+// WithCode attaches a stable, dotted machine-readable code and returns e for
+// chaining, e.g. errs.NotFound(err).WithCode("BANK.ACCOUNT_NOT_FOUND").
+func (e *E) WithCode(code string) *E {
+	e.Code = code
+	return e
+}
+
+// NotFound classifies err as "the thing the caller asked for doesn't exist".
+func NotFound(err error) *E {
+	return &E{Kind: KindNotFound, cause: err}
+}
+
+// InvalidArgument classifies err as "the caller's request was malformed".
+func InvalidArgument(err error) *E {
+	return &E{Kind: KindInvalidArgument, cause: err}
+}
+
+// Unavailable classifies err as "a dependency the caller needs is
+// unreachable or degraded"; retrying later may succeed.
+func Unavailable(err error) *E {
+	return &E{Kind: KindUnavailable, cause: err}
+}
+
+// Conflict classifies err as "the request conflicts with the current
+// state of the resource".
+func Conflict(err error) *E {
+	return &E{Kind: KindConflict, cause: err}
+}
+
+// Internal classifies err as "something went wrong on our side that the
+// caller can't do anything about".
+func Internal(err error) *E {
+	return &E{Kind: KindInternal, cause: err}
+}
+
+func kindOf(err error) (Kind, bool) {
+	var e *E
+	if errors.As(err, &e) {
+		return e.Kind, true
+	}
+	return KindInternal, false
+}
+
+// IsNotFound reports whether err is (or wraps) an *E classified NotFound.
+func IsNotFound(err error) bool {
+	kind, ok := kindOf(err)
+	return ok && kind == KindNotFound
+}
+
+// IsInvalidArgument reports whether err is (or wraps) an *E classified
+// InvalidArgument.
+func IsInvalidArgument(err error) bool {
+	kind, ok := kindOf(err)
+	return ok && kind == KindInvalidArgument
+}
+
+// IsUnavailable reports whether err is (or wraps) an *E classified
+// Unavailable.
+func IsUnavailable(err error) bool {
+	kind, ok := kindOf(err)
+	return ok && kind == KindUnavailable
+}
+
+// IsConflict reports whether err is (or wraps) an *E classified Conflict.
+func IsConflict(err error) bool {
+	kind, ok := kindOf(err)
+	return ok && kind == KindConflict
+}
+
+// IsInternal reports whether err is (or wraps) an *E classified Internal,
+// or isn't an *E at all (the default classification).
+func IsInternal(err error) bool {
+	kind, _ := kindOf(err)
+	return kind == KindInternal
+}
@@ -0,0 +1,69 @@
+package errs_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/siddarth99/banking-ledger/pkg/errs"
+)
+
+func TestConstructors_ClassifyAndUnwrap(t *testing.T) {
+	cause := errors.New("account ACC1 does not exist")
+
+	cases := []struct {
+		name      string
+		build     func(error) *errs.E
+		predicate func(error) bool
+	}{
+		{"NotFound", errs.NotFound, errs.IsNotFound},
+		{"InvalidArgument", errs.InvalidArgument, errs.IsInvalidArgument},
+		{"Unavailable", errs.Unavailable, errs.IsUnavailable},
+		{"Conflict", errs.Conflict, errs.IsConflict},
+		{"Internal", errs.Internal, errs.IsInternal},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.build(cause)
+
+			assert.True(t, tc.predicate(err))
+			assert.ErrorIs(t, err, cause)
+			assert.Equal(t, cause, errors.Unwrap(err))
+			assert.Equal(t, cause.Error(), err.Error())
+		})
+	}
+}
+
+func TestIsInternal_DefaultsUnclassifiedErrorsToInternal(t *testing.T) {
+	err := errors.New("some plain error")
+
+	assert.True(t, errs.IsInternal(err))
+	assert.False(t, errs.IsNotFound(err))
+	assert.False(t, errs.IsInvalidArgument(err))
+	assert.False(t, errs.IsUnavailable(err))
+	assert.False(t, errs.IsConflict(err))
+}
+
+func TestWithCode_ChainsAndIsPreservedThroughWrapping(t *testing.T) {
+	err := errs.NotFound(errors.New("account not found")).WithCode("BANK.ACCOUNT_NOT_FOUND")
+
+	assert.Equal(t, "BANK.ACCOUNT_NOT_FOUND", err.Code)
+	assert.True(t, errs.IsNotFound(err))
+
+	wrapped := fmt.Errorf("lookup account: %w", err)
+	assert.True(t, errs.IsNotFound(wrapped))
+
+	var e *errs.E
+	assert.True(t, errors.As(wrapped, &e))
+	assert.Equal(t, "BANK.ACCOUNT_NOT_FOUND", e.Code)
+}
+
+func TestError_FallsBackToCodeWhenCauseIsNil(t *testing.T) {
+	err := errs.Internal(nil).WithCode("BANK.UNKNOWN")
+
+	assert.Equal(t, "BANK.UNKNOWN", err.Error())
+	assert.Nil(t, errors.Unwrap(err))
+}
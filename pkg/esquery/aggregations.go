@@ -0,0 +1,70 @@
+package esquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DailyTotals is one day's credit/debit sums, as returned inside
+// TransactionHistoryAggregations.ByDay.
+type DailyTotals struct {
+	Date         string `json:"date"`
+	CreditsTotal string `json:"creditsTotal"`
+	DebitsTotal  string `json:"debitsTotal"`
+}
+
+// TransactionHistoryAggregations is the parsed result of a query built by
+// BuildTransactionHistoryAggregationQuery.
+type TransactionHistoryAggregations struct {
+	CreditsTotal string        `json:"creditsTotal"`
+	DebitsTotal  string        `json:"debitsTotal"`
+	ByDay        []DailyTotals `json:"byDay"`
+}
+
+// sumAggResult mirrors the {filter: {doc_count, total: {value}}} shape
+// sumAgg produces in the response body.
+type sumAggResult struct {
+	Total struct {
+		Value float64 `json:"value"`
+	} `json:"total"`
+}
+
+// ParseTransactionHistoryAggregations decodes an Elasticsearch response
+// body produced by a BuildTransactionHistoryAggregationQuery search into a
+// TransactionHistoryAggregations.
+func ParseTransactionHistoryAggregations(body io.Reader) (*TransactionHistoryAggregations, error) {
+	var parsed struct {
+		Aggregations struct {
+			CreditsTotal sumAggResult `json:"credits_total"`
+			DebitsTotal  sumAggResult `json:"debits_total"`
+			ByDay        struct {
+				Buckets []struct {
+					KeyAsString  string       `json:"key_as_string"`
+					CreditsTotal sumAggResult `json:"credits_total"`
+					DebitsTotal  sumAggResult `json:"debits_total"`
+				} `json:"buckets"`
+			} `json:"by_day"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	result := &TransactionHistoryAggregations{
+		CreditsTotal: formatTotal(parsed.Aggregations.CreditsTotal.Total.Value),
+		DebitsTotal:  formatTotal(parsed.Aggregations.DebitsTotal.Total.Value),
+	}
+	for _, bucket := range parsed.Aggregations.ByDay.Buckets {
+		result.ByDay = append(result.ByDay, DailyTotals{
+			Date:         bucket.KeyAsString,
+			CreditsTotal: formatTotal(bucket.CreditsTotal.Total.Value),
+			DebitsTotal:  formatTotal(bucket.DebitsTotal.Total.Value),
+		})
+	}
+	return result, nil
+}
+
+func formatTotal(value float64) string {
+	return fmt.Sprintf("%.2f", value)
+}
@@ -0,0 +1,45 @@
+package esquery
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTransactionHistoryAggregations(t *testing.T) {
+	raw := map[string]interface{}{
+		"aggregations": map[string]interface{}{
+			"credits_total": map[string]interface{}{"total": map[string]interface{}{"value": 300.0}},
+			"debits_total":  map[string]interface{}{"total": map[string]interface{}{"value": 125.5}},
+			"by_day": map[string]interface{}{
+				"buckets": []map[string]interface{}{
+					{
+						"key_as_string": "2026-01-15T00:00:00.000Z",
+						"credits_total": map[string]interface{}{"total": map[string]interface{}{"value": 200.0}},
+						"debits_total":  map[string]interface{}{"total": map[string]interface{}{"value": 0.0}},
+					},
+					{
+						"key_as_string": "2026-01-16T00:00:00.000Z",
+						"credits_total": map[string]interface{}{"total": map[string]interface{}{"value": 100.0}},
+						"debits_total":  map[string]interface{}{"total": map[string]interface{}{"value": 125.5}},
+					},
+				},
+			},
+		},
+	}
+	body, err := json.Marshal(raw)
+	assert.NoError(t, err)
+
+	result, err := ParseTransactionHistoryAggregations(strings.NewReader(string(body)))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "300.00", result.CreditsTotal)
+	assert.Equal(t, "125.50", result.DebitsTotal)
+	assert.Len(t, result.ByDay, 2)
+	assert.Equal(t, "2026-01-15T00:00:00.000Z", result.ByDay[0].Date)
+	assert.Equal(t, "200.00", result.ByDay[0].CreditsTotal)
+	assert.Equal(t, "0.00", result.ByDay[0].DebitsTotal)
+	assert.Equal(t, "125.50", result.ByDay[1].DebitsTotal)
+}
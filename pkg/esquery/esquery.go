@@ -0,0 +1,173 @@
+// Package esquery builds the Elasticsearch request bodies the API handlers
+// search bank-transactions-* with. Pulling the map[string]interface{}
+// construction out of the handlers lets it be unit-tested without a gin
+// router or a live cluster, and keeps the query shape (field names, the
+// search_after tiebreaker, the aggregation names) in one place shared by
+// every caller.
+package esquery
+
+// TransactionHistoryFilters narrows a transaction-history query. Every
+// field is optional; a zero-value TransactionHistoryFilters matches every
+// document for AccountNumber.
+type TransactionHistoryFilters struct {
+	// AccountNumber is required: every query is scoped to one account.
+	AccountNumber string
+	// From and To bound Timestamp, inclusive, as RFC3339 strings.
+	From string
+	To   string
+	// Type is "credit" or "debit". Empty matches both.
+	Type string
+	// Status filters on the document's status field, e.g. "completed".
+	Status string
+	// MinAmount and MaxAmount bound the transaction amount, inclusive, as
+	// decimal strings (e.g. "10.00"). They come straight from query
+	// parameters, which carry no currency, so unlike
+	// TransactionSearchRequest's money.Amount fields these are compared
+	// against amount.value alone.
+	MinAmount string
+	MaxAmount string
+	// Cursor is the sort values of the last hit from a previous page
+	// (timestamp, transaction_id), as returned in that page's NextCursor.
+	// A nil Cursor fetches the first page.
+	Cursor []interface{}
+	// Size caps how many hits to return. A zero or negative value falls
+	// back to DefaultHistorySize.
+	Size int
+}
+
+// DefaultHistorySize and MaxHistorySize bound TransactionHistoryFilters.Size.
+const (
+	DefaultHistorySize = 10
+	MaxHistorySize     = 200
+)
+
+// historySortFields are the fields a transaction-history query is sorted
+// by, in order. Sorting on transaction_id.keyword as well as timestamp
+// gives search_after a stable tiebreaker for documents sharing the same
+// timestamp, which a sort on timestamp alone would not.
+var historySortFields = []string{"timestamp", "transaction_id.keyword"}
+
+// BuildTransactionHistoryQuery translates filters into an Elasticsearch
+// search request body: a bool query with account_number, type, status and
+// amount/timestamp ranges in filter (which don't affect scoring and are
+// cacheable), sorted by historySortFields, and paginated with search_after
+// rather than from/size so deep pagination never runs into Elasticsearch's
+// 10,000-hit window.
+func BuildTransactionHistoryQuery(filters TransactionHistoryFilters) map[string]interface{} {
+	clauses := append([]map[string]interface{}{
+		{"match": map[string]interface{}{"account_number": filters.AccountNumber}},
+	}, rangeAndTermFilters(filters)...)
+
+	size := filters.Size
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	if size > MaxHistorySize {
+		size = MaxHistorySize
+	}
+
+	body := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": clauses},
+		},
+		"sort": []map[string]interface{}{
+			{historySortFields[0]: map[string]interface{}{"order": "desc"}},
+			{historySortFields[1]: map[string]interface{}{"order": "desc"}},
+		},
+		"size": size,
+	}
+	if len(filters.Cursor) > 0 {
+		body["search_after"] = filters.Cursor
+	}
+	return body
+}
+
+// rangeAndTermFilters builds the shared term/range clauses used by both
+// BuildTransactionHistoryQuery and BuildTransactionHistoryAggregationQuery.
+func rangeAndTermFilters(filters TransactionHistoryFilters) []map[string]interface{} {
+	var clauses []map[string]interface{}
+
+	if filters.Type != "" {
+		clauses = append(clauses, map[string]interface{}{"term": map[string]interface{}{"type": filters.Type}})
+	}
+	if filters.Status != "" {
+		clauses = append(clauses, map[string]interface{}{"term": map[string]interface{}{"status": filters.Status}})
+	}
+	if filters.MinAmount != "" || filters.MaxAmount != "" {
+		amountRange := map[string]interface{}{}
+		if filters.MinAmount != "" {
+			amountRange["gte"] = filters.MinAmount
+		}
+		if filters.MaxAmount != "" {
+			amountRange["lte"] = filters.MaxAmount
+		}
+		clauses = append(clauses, map[string]interface{}{"range": map[string]interface{}{"amount.value": amountRange}})
+	}
+	if filters.From != "" || filters.To != "" {
+		timestampRange := map[string]interface{}{}
+		if filters.From != "" {
+			timestampRange["gte"] = filters.From
+		}
+		if filters.To != "" {
+			timestampRange["lte"] = filters.To
+		}
+		clauses = append(clauses, map[string]interface{}{"range": map[string]interface{}{"timestamp": timestampRange}})
+	}
+
+	return clauses
+}
+
+// BuildTransactionHistoryAggregationQuery builds the second query issued
+// when a caller opts into aggregations: the sum of credit and debit
+// amounts, plus a daily date_histogram of the same two sums, scoped by the
+// same filters as BuildTransactionHistoryQuery (minus pagination, which is
+// meaningless for an aggregation-only query: size is set to 0 so only
+// aggregations come back).
+func BuildTransactionHistoryAggregationQuery(filters TransactionHistoryFilters) map[string]interface{} {
+	clauses := append([]map[string]interface{}{
+		{"match": map[string]interface{}{"account_number": filters.AccountNumber}},
+	}, rangeAndTermFilters(filters)...)
+
+	return map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": clauses},
+		},
+		"size": 0,
+		"aggs": map[string]interface{}{
+			"credits_total": sumAgg("credit"),
+			"debits_total":  sumAgg("debit"),
+			"by_day": map[string]interface{}{
+				"date_histogram": map[string]interface{}{
+					"field":             "timestamp",
+					"calendar_interval": "day",
+				},
+				"aggs": map[string]interface{}{
+					"credits_total": sumAgg("credit"),
+					"debits_total":  sumAgg("debit"),
+				},
+			},
+		},
+	}
+}
+
+// sumAgg sums amount.value for documents of transactionType, via a
+// filter-aggregation wrapping the sum: amount is indexed as a nested
+// {value, currency} object rather than a plain number, so the sum
+// sub-aggregation parses amount.value.keyword with a script rather than
+// referencing it as a numeric field directly.
+func sumAgg(transactionType string) map[string]interface{} {
+	return map[string]interface{}{
+		"filter": map[string]interface{}{
+			"term": map[string]interface{}{"type": transactionType},
+		},
+		"aggs": map[string]interface{}{
+			"total": map[string]interface{}{
+				"sum": map[string]interface{}{
+					"script": map[string]interface{}{
+						"source": "Double.parseDouble(doc['amount.value.keyword'].value)",
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package esquery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTransactionHistoryQuery(t *testing.T) {
+	t.Run("Account number alone produces a single filter clause", func(t *testing.T) {
+		query := BuildTransactionHistoryQuery(TransactionHistoryFilters{AccountNumber: "ACC123456"})
+
+		boolQuery := query["query"].(map[string]interface{})["bool"].(map[string]interface{})
+		filters := boolQuery["filter"].([]map[string]interface{})
+		assert.Len(t, filters, 1)
+		assert.Equal(t, "ACC123456", filters[0]["match"].(map[string]interface{})["account_number"])
+		assert.Equal(t, DefaultHistorySize, query["size"])
+		assert.NotContains(t, query, "search_after")
+	})
+
+	t.Run("Type, status and amount range add filter clauses", func(t *testing.T) {
+		query := BuildTransactionHistoryQuery(TransactionHistoryFilters{
+			AccountNumber: "ACC123456",
+			Type:          "credit",
+			Status:        "completed",
+			MinAmount:     "10.00",
+			MaxAmount:     "500.00",
+		})
+
+		boolQuery := query["query"].(map[string]interface{})["bool"].(map[string]interface{})
+		filters := boolQuery["filter"].([]map[string]interface{})
+		assert.Len(t, filters, 4)
+		assert.Equal(t, "credit", filters[1]["term"].(map[string]interface{})["type"])
+		assert.Equal(t, "completed", filters[2]["term"].(map[string]interface{})["status"])
+		amountRange := filters[3]["range"].(map[string]interface{})["amount.value"].(map[string]interface{})
+		assert.Equal(t, "10.00", amountRange["gte"])
+		assert.Equal(t, "500.00", amountRange["lte"])
+	})
+
+	t.Run("From and to produce a timestamp range filter", func(t *testing.T) {
+		query := BuildTransactionHistoryQuery(TransactionHistoryFilters{
+			AccountNumber: "ACC123456",
+			From:          "2026-01-01T00:00:00Z",
+			To:            "2026-01-31T23:59:59Z",
+		})
+
+		boolQuery := query["query"].(map[string]interface{})["bool"].(map[string]interface{})
+		filters := boolQuery["filter"].([]map[string]interface{})
+		timestampRange := filters[1]["range"].(map[string]interface{})["timestamp"].(map[string]interface{})
+		assert.Equal(t, "2026-01-01T00:00:00Z", timestampRange["gte"])
+		assert.Equal(t, "2026-01-31T23:59:59Z", timestampRange["lte"])
+	})
+
+	t.Run("Cursor sets search_after", func(t *testing.T) {
+		query := BuildTransactionHistoryQuery(TransactionHistoryFilters{
+			AccountNumber: "ACC123456",
+			Cursor:        []interface{}{"2026-01-15T00:00:00Z", "TX99"},
+		})
+		assert.Equal(t, []interface{}{"2026-01-15T00:00:00Z", "TX99"}, query["search_after"])
+	})
+
+	t.Run("Sort is timestamp then transaction_id.keyword, both descending", func(t *testing.T) {
+		query := BuildTransactionHistoryQuery(TransactionHistoryFilters{AccountNumber: "ACC123456"})
+		sort := query["sort"].([]map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"order": "desc"}, sort[0]["timestamp"])
+		assert.Equal(t, map[string]interface{}{"order": "desc"}, sort[1]["transaction_id.keyword"])
+	})
+
+	t.Run("Size is clamped to MaxHistorySize", func(t *testing.T) {
+		query := BuildTransactionHistoryQuery(TransactionHistoryFilters{AccountNumber: "ACC123456", Size: 10000})
+		assert.Equal(t, MaxHistorySize, query["size"])
+	})
+}
+
+func TestBuildTransactionHistoryAggregationQuery(t *testing.T) {
+	query := BuildTransactionHistoryAggregationQuery(TransactionHistoryFilters{AccountNumber: "ACC123456", Type: "credit"})
+
+	assert.Equal(t, 0, query["size"])
+	boolQuery := query["query"].(map[string]interface{})["bool"].(map[string]interface{})
+	filters := boolQuery["filter"].([]map[string]interface{})
+	assert.Len(t, filters, 2)
+
+	aggs := query["aggs"].(map[string]interface{})
+	assert.Contains(t, aggs, "credits_total")
+	assert.Contains(t, aggs, "debits_total")
+	assert.Contains(t, aggs, "by_day")
+
+	byDay := aggs["by_day"].(map[string]interface{})
+	dateHistogram := byDay["date_histogram"].(map[string]interface{})
+	assert.Equal(t, "timestamp", dateHistogram["field"])
+	assert.Equal(t, "day", dateHistogram["calendar_interval"])
+}
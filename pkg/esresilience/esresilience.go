@@ -0,0 +1,318 @@
+// Package esresilience wraps an internal.ElasticsearchClient with per-method
+// retry (exponential backoff, full jitter) and a circuit breaker with
+// half-open probing, so a downed Elasticsearch cluster fails fast instead of
+// blocking every caller on the same timeout over and over.
+package esresilience
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// RetryPolicy configures retry behavior for a single ElasticsearchClient
+// method.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first. A zero
+	// value disables retrying (one attempt only).
+	MaxAttempts int
+	// BaseBackoff is the jittered delay before the second attempt, doubling
+	// every attempt after that, capped at MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Default retry policies. Index and Bulk retry more aggressively than
+// Search: a delayed write is cheap, but a slow search is usually serving a
+// request a caller is already blocked on, so it gives up sooner. Info uses
+// the same conservative policy as Search since it's mostly used for
+// connectivity checks at startup.
+var (
+	DefaultIndexPolicy  = RetryPolicy{MaxAttempts: 5, BaseBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second}
+	DefaultBulkPolicy   = RetryPolicy{MaxAttempts: 5, BaseBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second}
+	DefaultSearchPolicy = RetryPolicy{MaxAttempts: 2, BaseBackoff: 200 * time.Millisecond, MaxBackoff: 2 * time.Second}
+	DefaultInfoPolicy   = RetryPolicy{MaxAttempts: 2, BaseBackoff: 200 * time.Millisecond, MaxBackoff: 2 * time.Second}
+)
+
+// DefaultBreakerThreshold and DefaultBreakerCooldown are sane defaults for
+// Config.
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// Config bundles the per-method retry policies and circuit-breaker tuning
+// for New. A zero Config is filled in with the Default* values above.
+type Config struct {
+	IndexPolicy  RetryPolicy
+	SearchPolicy RetryPolicy
+	BulkPolicy   RetryPolicy
+	InfoPolicy   RetryPolicy
+	// BreakerThreshold consecutive failed calls (across every method, since
+	// they all share the same downstream cluster) opens the breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before letting a
+	// single half-open probe through.
+	BreakerCooldown time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.IndexPolicy.MaxAttempts == 0 {
+		c.IndexPolicy = DefaultIndexPolicy
+	}
+	if c.SearchPolicy.MaxAttempts == 0 {
+		c.SearchPolicy = DefaultSearchPolicy
+	}
+	if c.BulkPolicy.MaxAttempts == 0 {
+		c.BulkPolicy = DefaultBulkPolicy
+	}
+	if c.InfoPolicy.MaxAttempts == 0 {
+		c.InfoPolicy = DefaultInfoPolicy
+	}
+	if c.BreakerThreshold == 0 {
+		c.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if c.BreakerCooldown == 0 {
+		c.BreakerCooldown = DefaultBreakerCooldown
+	}
+	return c
+}
+
+// Breaker states, as reported by Health.
+const (
+	StateClosed   = "CLOSED"
+	StateOpen     = "OPEN"
+	StateHalfOpen = "HALF_OPEN"
+)
+
+// Health is the observable circuit-breaker state, returned by Client.Health
+// and served at GET /health/elasticsearch.
+type Health struct {
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	OpenUntil           time.Time `json:"openUntil,omitempty"`
+	LastError           string    `json:"lastError,omitempty"`
+}
+
+// ErrCircuitOpen is returned (wrapped) by every method while the breaker is
+// open and not due for a half-open probe yet.
+var ErrCircuitOpen = errors.New("elasticsearch circuit breaker is open")
+
+// Client wraps an internal.ElasticsearchClient with retry and circuit
+// breaking, and itself implements internal.ElasticsearchClient - so it's a
+// drop-in replacement anywhere the interface is accepted.
+type Client struct {
+	inner internal.ElasticsearchClient
+	cfg   Config
+
+	mu                  sync.Mutex
+	state               string
+	consecutiveFailures int
+	openUntil           time.Time
+	// probing is set while the one call admitted during StateHalfOpen is in
+	// flight, so concurrent callers are still rejected until it resolves.
+	probing bool
+	lastErr error
+}
+
+// New wraps inner with the retry policies and circuit-breaker tuning in cfg.
+// A zero Config uses the package's Default* tuning.
+func New(inner internal.ElasticsearchClient, cfg Config) *Client {
+	return &Client{inner: inner, cfg: cfg.withDefaults(), state: StateClosed}
+}
+
+// Health reports the breaker's current state for GET /health/elasticsearch.
+func (c *Client) Health() Health {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h := Health{
+		State:               c.state,
+		ConsecutiveFailures: c.consecutiveFailures,
+		OpenUntil:           c.openUntil,
+	}
+	if c.lastErr != nil {
+		h.LastError = c.lastErr.Error()
+	}
+	return h
+}
+
+// Index indexes a document, retrying DefaultIndexPolicy's schedule on
+// transient failures.
+func (c *Client) Index(index string, body io.Reader) (*esapi.Response, error) {
+	return withRetry(c, c.cfg.IndexPolicy, body, func(r io.Reader) (*esapi.Response, error) {
+		return c.inner.Index(index, r)
+	})
+}
+
+// Search searches indices, retrying SearchPolicy's (conservative) schedule
+// on transient failures.
+func (c *Client) Search(indices []string, body io.Reader) (*esapi.Response, error) {
+	return withRetry(c, c.cfg.SearchPolicy, body, func(r io.Reader) (*esapi.Response, error) {
+		return c.inner.Search(indices, r)
+	})
+}
+
+// Bulk submits a newline-delimited _bulk request body, retrying BulkPolicy's
+// schedule on transient failures.
+func (c *Client) Bulk(body io.Reader) (*esapi.Response, error) {
+	return withRetry(c, c.cfg.BulkPolicy, body, c.inner.Bulk)
+}
+
+// Info returns info about the cluster, retrying InfoPolicy's schedule on
+// transient failures.
+func (c *Client) Info() (*esapi.Response, error) {
+	return withRetry(c, c.cfg.InfoPolicy, nil, func(io.Reader) (*esapi.Response, error) {
+		return c.inner.Info()
+	})
+}
+
+// withRetry runs call up to policy.MaxAttempts times against the circuit
+// breaker tracked by c, backing off between transient failures. body is
+// buffered up front (if non-nil) so it can be replayed across attempts, the
+// same way an *http.Request retry would need to re-seek its body.
+func withRetry(c *Client, policy RetryPolicy, body io.Reader, call func(io.Reader) (*esapi.Response, error)) (*esapi.Response, error) {
+	if blocked, err := c.admit(); blocked {
+		return nil, err
+	}
+
+	var buf []byte
+	if body != nil {
+		var err error
+		buf, err = io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to buffer elasticsearch request body: %w", err)
+		}
+	}
+
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var reqBody io.Reader
+		if buf != nil {
+			reqBody = bytes.NewReader(buf)
+		}
+
+		res, err := call(reqBody)
+		if !retryable(res, err) {
+			if err != nil {
+				c.recordFailure(err)
+			} else {
+				c.recordSuccess()
+			}
+			return res, err
+		}
+
+		lastErr = classifyErr(res, err)
+		if res != nil {
+			res.Body.Close()
+		}
+		if attempt < maxAttempts {
+			time.Sleep(backoff(policy, attempt))
+		}
+	}
+
+	c.recordFailure(lastErr)
+	return nil, lastErr
+}
+
+// retryable reports whether a failed call is worth retrying: a transport
+// error, a 429 (rate limited), or a 502/503/504 (the cluster itself is
+// unhealthy). Anything else - including a successful response - is final.
+func retryable(res *esapi.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case 429, 502, 503, 504:
+		return true
+	default:
+		return false
+	}
+}
+
+func classifyErr(res *esapi.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("elasticsearch returned %d", res.StatusCode)
+}
+
+// backoff computes the exponential delay before attempt+1, with full jitter
+// to avoid every retry landing on the same schedule, capped at
+// policy.MaxBackoff.
+func backoff(policy RetryPolicy, attempt int) time.Duration {
+	ceiling := policy.BaseBackoff * time.Duration(uint64(1)<<uint(attempt-1))
+	if policy.MaxBackoff > 0 && ceiling > policy.MaxBackoff {
+		ceiling = policy.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// admit reports whether a call should be rejected outright because the
+// breaker is open - true, plus the rejection error to return, if so. It
+// also handles the OPEN -> HALF_OPEN transition: once openUntil has passed,
+// exactly one caller is admitted as a probe while every other caller is
+// still rejected, so a flood of retries doesn't all hit a still-struggling
+// cluster at once.
+func (c *Client) admit() (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case StateClosed:
+		return false, nil
+	case StateOpen:
+		if time.Now().Before(c.openUntil) {
+			return true, fmt.Errorf("%w: open until %s", ErrCircuitOpen, c.openUntil.Format(time.RFC3339))
+		}
+		c.state = StateHalfOpen
+		c.probing = true
+		return false, nil
+	case StateHalfOpen:
+		if c.probing {
+			return true, fmt.Errorf("%w: half-open probe already in flight", ErrCircuitOpen)
+		}
+		c.probing = true
+		return false, nil
+	default:
+		return false, nil
+	}
+}
+
+func (c *Client) recordSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = StateClosed
+	c.consecutiveFailures = 0
+	c.openUntil = time.Time{}
+	c.probing = false
+	c.lastErr = nil
+}
+
+func (c *Client) recordFailure(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consecutiveFailures++
+	c.lastErr = err
+	c.probing = false
+	if c.state == StateHalfOpen || c.consecutiveFailures >= c.cfg.BreakerThreshold {
+		c.state = StateOpen
+		c.openUntil = time.Now().Add(c.cfg.BreakerCooldown)
+	}
+}
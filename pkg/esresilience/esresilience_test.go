@@ -0,0 +1,109 @@
+package esresilience_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/esresilience"
+)
+
+func esResponse(statusCode int) (*esapi.Response, error) {
+	return &esapi.Response{StatusCode: statusCode, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+}
+
+func TestClient_IndexRetriesThenSucceeds(t *testing.T) {
+	var attempts int
+	es := &internal.MockElasticsearchClient{
+		IndexFunc: func(index string, body io.Reader) (*esapi.Response, error) {
+			attempts++
+			if attempts < 3 {
+				return esResponse(503)
+			}
+			return esResponse(201)
+		},
+	}
+	client := esresilience.New(es, esresilience.Config{
+		IndexPolicy: esresilience.RetryPolicy{MaxAttempts: 5, BaseBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond},
+	})
+
+	res, err := client.Index("bank-transactions-2026-01-01", strings.NewReader(`{}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 201, res.StatusCode)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestClient_SearchGivesUpOnNonRetryableStatus(t *testing.T) {
+	var attempts int
+	es := &internal.MockElasticsearchClient{
+		SearchFunc: func(indices []string, body io.Reader) (*esapi.Response, error) {
+			attempts++
+			return esResponse(400)
+		},
+	}
+	client := esresilience.New(es, esresilience.Config{})
+
+	res, err := client.Search([]string{"bank-transactions-*"}, strings.NewReader(`{}`))
+	assert.NoError(t, err)
+	assert.True(t, res.IsError())
+	assert.Equal(t, 1, attempts, "a non-retryable 4xx must not be retried")
+}
+
+func TestClient_BreakerOpensAfterConsecutiveFailuresAndRejectsFast(t *testing.T) {
+	var attempts int
+	es := &internal.MockElasticsearchClient{
+		BulkFunc: func(body io.Reader) (*esapi.Response, error) {
+			attempts++
+			return esResponse(503)
+		},
+	}
+	client := esresilience.New(es, esresilience.Config{
+		BulkPolicy:       esresilience.RetryPolicy{MaxAttempts: 1},
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+	})
+
+	_, err := client.Bulk(strings.NewReader(`{}`))
+	assert.Error(t, err)
+	_, err = client.Bulk(strings.NewReader(`{}`))
+	assert.Error(t, err)
+	assert.Equal(t, esresilience.StateOpen, client.Health().State)
+
+	attemptsBeforeOpen := attempts
+	_, err = client.Bulk(strings.NewReader(`{}`))
+	assert.ErrorIs(t, err, esresilience.ErrCircuitOpen)
+	assert.Equal(t, attemptsBeforeOpen, attempts, "an open breaker must not call the inner client at all")
+}
+
+func TestClient_BreakerHalfOpenProbeRecoversToClosed(t *testing.T) {
+	var fail bool
+	es := &internal.MockElasticsearchClient{
+		InfoFunc: func() (*esapi.Response, error) {
+			if fail {
+				return esResponse(503)
+			}
+			return esResponse(200)
+		},
+	}
+	client := esresilience.New(es, esresilience.Config{
+		InfoPolicy:       esresilience.RetryPolicy{MaxAttempts: 1},
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Millisecond,
+	})
+
+	fail = true
+	_, err := client.Info()
+	assert.Error(t, err)
+	assert.Equal(t, esresilience.StateOpen, client.Health().State)
+
+	time.Sleep(5 * time.Millisecond)
+	fail = false
+	_, err = client.Info()
+	assert.NoError(t, err)
+	assert.Equal(t, esresilience.StateClosed, client.Health().State)
+}
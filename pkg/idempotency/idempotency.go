@@ -0,0 +1,156 @@
+// Package idempotency implements a Postgres-backed store that lets HTTP
+// handlers de-duplicate retried requests carrying an Idempotency-Key header.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+)
+
+// ErrConflict's concrete type, ConflictError, is returned by Reserve when a
+// key is reused with a fingerprint that doesn't match the original request.
+var ErrConflict = errors.New("idempotency key reused with a different request")
+
+// Record is the request fingerprint and response stored against a single
+// Idempotency-Key.
+type Record struct {
+	Key           string
+	AccountNumber string
+	Amount        money.Amount
+	Type          string
+	TransactionID string
+	ResponseBody  []byte
+	CreatedAt     time.Time
+}
+
+// ConflictError reports that a key was already used with a different
+// request, along with enough detail to explain the mismatch to the caller.
+type ConflictError struct {
+	Key       string
+	Existing  Record
+	Attempted Record
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("idempotency key %q was already used with a different request", e.Key)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// Diff describes which fingerprint fields differ between the original
+// request and the one that just reused the key.
+func (e *ConflictError) Diff() map[string]map[string]any {
+	diff := make(map[string]map[string]any)
+	if e.Existing.AccountNumber != e.Attempted.AccountNumber {
+		diff["accountNumber"] = map[string]any{"original": e.Existing.AccountNumber, "attempted": e.Attempted.AccountNumber}
+	}
+	if e.Existing.Amount != e.Attempted.Amount {
+		diff["amount"] = map[string]any{"original": e.Existing.Amount, "attempted": e.Attempted.Amount}
+	}
+	if e.Existing.Type != e.Attempted.Type {
+		diff["type"] = map[string]any{"original": e.Existing.Type, "attempted": e.Attempted.Type}
+	}
+	return diff
+}
+
+// Store persists idempotency reservations to the idempotency_keys table.
+// Unlike pending.Requests, it keeps no in-memory index: the row-level lock
+// taken by Reserve is what serializes concurrent requests, including ones
+// racing across separate API processes.
+type Store struct {
+	db  internal.PgDBConnection
+	ttl time.Duration
+}
+
+// NewStore creates a Store. ttl bounds how long a reservation blocks reuse of
+// its key; once expired, the key may be claimed again.
+func NewStore(db internal.PgDBConnection, ttl time.Duration) *Store {
+	return &Store{db: db, ttl: ttl}
+}
+
+// Fingerprint computes the canonical hash of the fields that must match for
+// a retried request to be considered identical: the idempotency key itself,
+// the account acted on, the amount and the transaction type.
+func Fingerprint(key, accountNumber string, amount money.Amount, transactionType string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s|%s", key, accountNumber, amount, amount.Currency(), transactionType)))
+	return hex.EncodeToString(sum[:])
+}
+
+const selectForUpdateQuery = `SELECT payload_hash, account_number, amount, currency, type, transaction_id, response_body, created_at, expires_at
+	FROM idempotency_keys WHERE idempotency_key = $1 FOR UPDATE`
+
+const insertQuery = `INSERT INTO idempotency_keys (
+		idempotency_key, payload_hash, account_number, amount, currency, type, transaction_id, response_body, created_at, expires_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (idempotency_key) DO NOTHING`
+
+const updateQuery = `UPDATE idempotency_keys SET
+		payload_hash = $2, account_number = $3, amount = $4, currency = $5, type = $6, transaction_id = $7, response_body = $8, created_at = $9, expires_at = $10
+	WHERE idempotency_key = $1`
+
+// Reserve atomically claims record.Key for the request described by record.
+// If the key is unused (or its previous reservation has expired), Reserve
+// stores record and returns (nil, nil): the caller's request is the
+// canonical one and should proceed as normal. If the key was already
+// reserved with an identical fingerprint, Reserve returns the stored record
+// so the caller can replay its response verbatim. If the key was reused with
+// a different fingerprint, Reserve returns a *ConflictError.
+//
+// Concurrent callers racing on the same key serialize on the row lock taken
+// here: the loser's INSERT hits the unique index and blocks until the
+// winner's transaction commits, so it always observes the winner's finished
+// reservation rather than a half-written one.
+func (s *Store) Reserve(ctx context.Context, record Record) (*Record, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	payloadHash := Fingerprint(record.Key, record.AccountNumber, record.Amount, record.Type)
+	expiresAt := record.CreatedAt.Add(s.ttl)
+
+	tag, err := tx.Exec(ctx, insertQuery,
+		record.Key, payloadHash, record.AccountNumber, record.Amount, record.Amount.Currency(), record.Type, record.TransactionID, record.ResponseBody, record.CreatedAt, expiresAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, tx.Commit(ctx)
+	}
+
+	var existing Record
+	var existingHash, existingCurrency string
+	var existingExpiresAt time.Time
+	existing.Key = record.Key
+	row := tx.QueryRow(ctx, selectForUpdateQuery, record.Key)
+	if err := row.Scan(&existingHash, &existing.AccountNumber, &existing.Amount, &existingCurrency, &existing.Type, &existing.TransactionID, &existing.ResponseBody, &existing.CreatedAt, &existingExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to read existing idempotency reservation: %w", err)
+	}
+	existing.Amount = existing.Amount.WithCurrency(existingCurrency)
+
+	if time.Now().After(existingExpiresAt) {
+		if _, err := tx.Exec(ctx, updateQuery,
+			record.Key, payloadHash, record.AccountNumber, record.Amount, record.Amount.Currency(), record.Type, record.TransactionID, record.ResponseBody, record.CreatedAt, expiresAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to reclaim expired idempotency key: %w", err)
+		}
+		return nil, tx.Commit(ctx)
+	}
+
+	if existingHash != payloadHash {
+		return nil, &ConflictError{Key: record.Key, Existing: existing, Attempted: record}
+	}
+
+	return &existing, tx.Commit(ctx)
+}
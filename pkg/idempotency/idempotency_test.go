@@ -0,0 +1,149 @@
+package idempotency_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/idempotency"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+)
+
+func TestStore_ReserveWins(t *testing.T) {
+	mockTx := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
+	}
+	db := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+	store := idempotency.NewStore(db, time.Minute)
+
+	amount, err := money.FromString("100.00", "USD")
+	assert.NoError(t, err)
+
+	existing, err := store.Reserve(context.Background(), idempotency.Record{
+		Key:           "key-1",
+		AccountNumber: "ACC1",
+		Amount:        amount,
+		Type:          "DEPOSIT",
+		TransactionID: "TX1",
+		ResponseBody:  []byte(`{"transactionID":"TX1"}`),
+		CreatedAt:     time.Now(),
+	})
+
+	assert.NoError(t, err)
+	assert.Nil(t, existing, "the first reservation for a key should win and have no existing record")
+}
+
+func TestStore_ReserveReplaysIdenticalRequest(t *testing.T) {
+	createdAt := time.Now()
+	amount, err := money.FromString("100.00", "USD")
+	assert.NoError(t, err)
+
+	mockTx := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("INSERT 0 0"), nil
+		},
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			return &internal.MockPgxRow{
+				ScanFunc: func(dest ...interface{}) error {
+					*dest[0].(*string) = idempotency.Fingerprint("key-1", "ACC1", amount, "DEPOSIT")
+					*dest[1].(*string) = "ACC1"
+					*dest[2].(*money.Amount) = amount
+					*dest[3].(*string) = "USD"
+					*dest[4].(*string) = "DEPOSIT"
+					*dest[5].(*string) = "TX1"
+					*dest[6].(*[]byte) = []byte(`{"transactionID":"TX1"}`)
+					*dest[7].(*time.Time) = createdAt
+					*dest[8].(*time.Time) = createdAt.Add(time.Minute)
+					return nil
+				},
+			}
+		},
+	}
+	db := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+	store := idempotency.NewStore(db, time.Minute)
+
+	existing, err := store.Reserve(context.Background(), idempotency.Record{
+		Key:           "key-1",
+		AccountNumber: "ACC1",
+		Amount:        amount,
+		Type:          "DEPOSIT",
+		TransactionID: "TX2",
+		ResponseBody:  []byte(`{"transactionID":"TX2"}`),
+		CreatedAt:     time.Now(),
+	})
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, existing) {
+		assert.Equal(t, "TX1", existing.TransactionID)
+		assert.Equal(t, []byte(`{"transactionID":"TX1"}`), existing.ResponseBody)
+	}
+}
+
+func TestStore_ReserveConflictsOnDifferentPayload(t *testing.T) {
+	createdAt := time.Now()
+	storedAmount, err := money.FromString("100.00", "USD")
+	assert.NoError(t, err)
+
+	mockTx := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("INSERT 0 0"), nil
+		},
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			return &internal.MockPgxRow{
+				ScanFunc: func(dest ...interface{}) error {
+					*dest[0].(*string) = idempotency.Fingerprint("key-1", "ACC1", storedAmount, "DEPOSIT")
+					*dest[1].(*string) = "ACC1"
+					*dest[2].(*money.Amount) = storedAmount
+					*dest[3].(*string) = "USD"
+					*dest[4].(*string) = "DEPOSIT"
+					*dest[5].(*string) = "TX1"
+					*dest[6].(*[]byte) = []byte(`{"transactionID":"TX1"}`)
+					*dest[7].(*time.Time) = createdAt
+					*dest[8].(*time.Time) = createdAt.Add(time.Minute)
+					return nil
+				},
+			}
+		},
+	}
+	db := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+	store := idempotency.NewStore(db, time.Minute)
+
+	differentAmount, err := money.FromString("250.00", "USD")
+	assert.NoError(t, err)
+
+	_, err = store.Reserve(context.Background(), idempotency.Record{
+		Key:           "key-1",
+		AccountNumber: "ACC1",
+		Amount:        differentAmount, // different amount than the stored reservation
+		Type:          "DEPOSIT",
+		TransactionID: "TX2",
+		ResponseBody:  []byte(`{"transactionID":"TX2"}`),
+		CreatedAt:     time.Now(),
+	})
+
+	assert.ErrorIs(t, err, idempotency.ErrConflict)
+	var conflict *idempotency.ConflictError
+	if assert.ErrorAs(t, err, &conflict) {
+		diff := conflict.Diff()
+		assert.Contains(t, diff, "amount")
+	}
+}
@@ -0,0 +1,63 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const reserveProcessedMessageQuery = `
+	INSERT INTO processed_messages (message_key, created_at)
+	VALUES ($1, $2)
+	ON CONFLICT (message_key) DO NOTHING
+`
+
+const selectProcessedMessageForUpdateQuery = `
+	SELECT result FROM processed_messages WHERE message_key = $1 FOR UPDATE
+`
+
+const recordProcessedMessageResultQuery = `
+	UPDATE processed_messages SET result = $2 WHERE message_key = $1
+`
+
+// WithIdempotency runs fn at most once for key: a second call with the same
+// key, whether a retry within the same process or a redelivered AMQP message
+// after a crash, observes the first call's stored result and never invokes
+// fn again.
+//
+// It differs from Store.Reserve, which fingerprints a client-supplied
+// Idempotency-Key header against a full request body and manages its own
+// transaction. WithIdempotency instead runs inside a transaction the caller
+// already holds open for its own business logic (an account insert, a
+// balance update, ...): fn's side effects and the record that they ran
+// commit or roll back together, and a concurrent attempt on the same key
+// blocks on the row lock taken here until the first attempt's transaction
+// commits or rolls back.
+func WithIdempotency(ctx context.Context, tx pgx.Tx, key string, fn func() (json.RawMessage, error)) (json.RawMessage, error) {
+	tag, err := tx.Exec(ctx, reserveProcessedMessageQuery, key, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve processed-message key %q: %w", key, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		var existing json.RawMessage
+		row := tx.QueryRow(ctx, selectProcessedMessageForUpdateQuery, key)
+		if err := row.Scan(&existing); err != nil {
+			return nil, fmt.Errorf("failed to read processed-message result for key %q: %w", key, err)
+		}
+		return existing, nil
+	}
+
+	result, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, recordProcessedMessageResultQuery, key, result); err != nil {
+		return nil, fmt.Errorf("failed to record processed-message result for key %q: %w", key, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,128 @@
+// Package ledger appends every transaction's posted TransactionDocument to
+// transaction_log, a SHA-256 hash chain: each row's hash commits to its own
+// canonicalized payload and the previous row's hash, so an auditor can
+// detect a row that was altered, deleted or reordered after the fact
+// without trusting anything but the chain itself.
+package ledger
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// CanonicalJSON re-marshals payload with its object keys sorted, so the hash
+// chain is stable across re-encodings that preserve meaning but not byte
+// layout - notably Postgres's jsonb, which reformats (and may reorder) the
+// value it stores. encoding/json sorts map keys when marshaling, so
+// unmarshaling into interface{} and marshaling back is sufficient; it
+// recurses into nested objects the same way.
+func CanonicalJSON(payload json.RawMessage) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload for canonicalization: %w", err)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal canonical payload: %w", err)
+	}
+	return canonical, nil
+}
+
+// ComputeHash is the chain's linking function: SHA256(prevHash ||
+// canonicalPayload). It is exported so callers outside this package (an
+// audit endpoint, say) can recompute and compare a row's hash without
+// duplicating the algorithm.
+func ComputeHash(prevHash, canonicalPayload []byte) []byte {
+	sum := sha256.Sum256(append(prevHash, canonicalPayload...))
+	return sum[:]
+}
+
+const selectChainTailForUpdateQuery = `SELECT hash FROM transaction_log ORDER BY seq DESC LIMIT 1 FOR UPDATE`
+
+const insertChainLinkQuery = `
+	INSERT INTO transaction_log (transaction_id, payload, prev_hash, hash)
+	VALUES ($1, $2, $3, $4)
+`
+
+// Append adds payload to the end of the hash chain inside tx, linking it to
+// the current tail via SHA256(prevHash || CanonicalJSON(payload)). The
+// SELECT ... FOR UPDATE on the tail serializes concurrent appends so two
+// transactions never compute their hash against the same prior link.
+func Append(ctx context.Context, tx pgx.Tx, transactionID string, payload json.RawMessage) error {
+	var prevHash []byte
+	err := tx.QueryRow(ctx, selectChainTailForUpdateQuery).Scan(&prevHash)
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("failed to lock transaction log tail: %w", err)
+	}
+
+	canonical, err := CanonicalJSON(payload)
+	if err != nil {
+		return err
+	}
+
+	hash := ComputeHash(prevHash, canonical)
+
+	if _, err := tx.Exec(ctx, insertChainLinkQuery, transactionID, payload, prevHash, hash); err != nil {
+		return fmt.Errorf("failed to append transaction log entry: %w", err)
+	}
+	return nil
+}
+
+const selectChainQuery = `SELECT seq, transaction_id, payload, prev_hash, hash FROM transaction_log ORDER BY seq ASC`
+
+// BrokenLink identifies the first transaction_log row whose hash does not
+// match its recorded payload and predecessor, and why.
+type BrokenLink struct {
+	Seq           int64
+	TransactionID string
+	Reason        string
+}
+
+// Verify walks transaction_log in seq order, recomputing each row's hash
+// from its own payload and the previous row's hash, and returns the first
+// row where that doesn't match - either because payload was altered, or
+// because prev_hash no longer points at the row actually preceding it (a
+// deleted or reordered row). A nil result means the chain is intact.
+func Verify(ctx context.Context, conn internal.PgDBConnection) (*BrokenLink, error) {
+	rows, err := conn.Query(ctx, selectChainQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transaction log: %w", err)
+	}
+	defer rows.Close()
+
+	var expectedPrevHash []byte
+	for rows.Next() {
+		var seq int64
+		var transactionID string
+		var payload, prevHash, hash []byte
+		if err := rows.Scan(&seq, &transactionID, &payload, &prevHash, &hash); err != nil {
+			return nil, fmt.Errorf("failed to read transaction log row: %w", err)
+		}
+
+		if !bytes.Equal(prevHash, expectedPrevHash) {
+			return &BrokenLink{Seq: seq, TransactionID: transactionID, Reason: "prev_hash does not match the preceding row's hash"}, nil
+		}
+
+		canonical, err := CanonicalJSON(payload)
+		if err != nil {
+			return &BrokenLink{Seq: seq, TransactionID: transactionID, Reason: err.Error()}, nil
+		}
+		if !bytes.Equal(hash, ComputeHash(prevHash, canonical)) {
+			return &BrokenLink{Seq: seq, TransactionID: transactionID, Reason: "hash does not match recomputed SHA-256 of prev_hash and payload"}, nil
+		}
+
+		expectedPrevHash = hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transaction log: %w", err)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,114 @@
+package ledger_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/ledger"
+)
+
+func TestCanonicalJSON_SortsKeysRegardlessOfInputOrder(t *testing.T) {
+	a, err := ledger.CanonicalJSON(json.RawMessage(`{"b":1,"a":{"d":2,"c":3}}`))
+	assert.NoError(t, err)
+
+	b, err := ledger.CanonicalJSON(json.RawMessage(`{"a":{"c":3,"d":2},"b":1}`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, string(a), string(b))
+	assert.JSONEq(t, `{"a":{"c":3,"d":2},"b":1}`, string(a))
+}
+
+// chainRow is one entry of a fake transaction_log used to drive Verify.
+type chainRow struct {
+	seq           int64
+	transactionID string
+	payload       []byte
+	prevHash      []byte
+	hash          []byte
+}
+
+func link(prevHash []byte, transactionID string, payload []byte) chainRow {
+	canonical, err := ledger.CanonicalJSON(payload)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(append(prevHash, canonical...))
+	return chainRow{transactionID: transactionID, payload: payload, prevHash: prevHash, hash: sum[:]}
+}
+
+func mockChain(rows []chainRow) *internal.MockPgDBConnection {
+	return &internal.MockPgDBConnection{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			i := 0
+			return &internal.MockPgxRows{
+				NextFunc: func() bool {
+					i++
+					return i <= len(rows)
+				},
+				ScanFunc: func(dest ...interface{}) error {
+					r := rows[i-1]
+					*dest[0].(*int64) = r.seq
+					*dest[1].(*string) = r.transactionID
+					*dest[2].(*[]byte) = r.payload
+					*dest[3].(*[]byte) = r.prevHash
+					*dest[4].(*[]byte) = r.hash
+					return nil
+				},
+			}, nil
+		},
+	}
+}
+
+func TestVerify_IntactChainReturnsNoBrokenLink(t *testing.T) {
+	first := link(nil, "TX1", []byte(`{"transaction_id":"TX1"}`))
+	first.seq = 1
+	second := link(first.hash, "TX2", []byte(`{"transaction_id":"TX2"}`))
+	second.seq = 2
+
+	broken, err := ledger.Verify(context.Background(), mockChain([]chainRow{first, second}))
+
+	assert.NoError(t, err)
+	assert.Nil(t, broken)
+}
+
+func TestVerify_TamperedPayloadReturnsBrokenLink(t *testing.T) {
+	first := link(nil, "TX1", []byte(`{"transaction_id":"TX1"}`))
+	first.seq = 1
+	second := link(first.hash, "TX2", []byte(`{"transaction_id":"TX2"}`))
+	second.seq = 2
+	// Tamper with the stored payload after the hash was computed; the row's
+	// own recomputed hash should no longer match.
+	second.payload = []byte(`{"transaction_id":"TX2","amount":"999999.00"}`)
+
+	broken, err := ledger.Verify(context.Background(), mockChain([]chainRow{first, second}))
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, broken) {
+		assert.Equal(t, int64(2), broken.Seq)
+		assert.Equal(t, "TX2", broken.TransactionID)
+	}
+}
+
+func TestVerify_MissingLinkBreaksPrevHashChain(t *testing.T) {
+	first := link(nil, "TX1", []byte(`{"transaction_id":"TX1"}`))
+	first.seq = 1
+	second := link(first.hash, "TX2", []byte(`{"transaction_id":"TX2"}`))
+	second.seq = 2
+	third := link(second.hash, "TX3", []byte(`{"transaction_id":"TX3"}`))
+	third.seq = 3
+
+	// Drop TX2: TX3's prev_hash now points at a hash that's no longer the
+	// preceding row in the result set.
+	broken, err := ledger.Verify(context.Background(), mockChain([]chainRow{first, third}))
+
+	assert.NoError(t, err)
+	if assert.NotNil(t, broken) {
+		assert.Equal(t, int64(3), broken.Seq)
+	}
+}
@@ -0,0 +1,43 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+func TestLRUCache_GetPut(t *testing.T) {
+	cache := internal.NewLRUCache[string, int](2)
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	_, ok = cache.Get("missing")
+	assert.False(t, ok)
+}
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := internal.NewLRUCache[string, int](2)
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	cache.Get("a")    // "a" is now the most recently used
+	cache.Put("c", 3) // evicts "b"
+
+	_, ok := cache.Get("b")
+	assert.False(t, ok)
+
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	value, ok = cache.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+}
@@ -0,0 +1,193 @@
+// Package money implements a fixed-point monetary amount so the ledger never
+// loses cents to binary-float rounding the way float64 does.
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// minorUnitsPerCurrency is the number of decimal places Amount keeps for
+// every currency. Real ISO-4217 exponents vary (JPY is 0, BHD is 3), but this
+// ledger only ever deals in two-decimal currencies today, so a single scale
+// keeps Add/Sub/Cmp trivial; a per-currency exponent table is future work if
+// that changes.
+const minorUnitsPerCurrency = 100
+
+// Amount is a monetary value stored as an integer number of minor units
+// (cents) of currency, so arithmetic never rounds the way float64 does.
+type Amount struct {
+	minorUnits int64
+	currency   string
+}
+
+// Zero returns a zero-value Amount in currency.
+func Zero(currency string) Amount {
+	return Amount{currency: currency}
+}
+
+// FromString parses a decimal string such as "1234.56" into an Amount in
+// currency. It rejects more than two decimal places rather than silently
+// truncating them.
+func FromString(s, currency string) (Amount, error) {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" {
+		return Amount{}, fmt.Errorf("money: invalid amount %q", s)
+	}
+	if hasFrac && len(frac) > 2 {
+		return Amount{}, fmt.Errorf("money: %q has more than 2 decimal places", s)
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	wholeUnits, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return Amount{}, fmt.Errorf("money: invalid amount %q: %w", s, err)
+	}
+	fracUnits, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return Amount{}, fmt.Errorf("money: invalid amount %q: %w", s, err)
+	}
+
+	minorUnits := wholeUnits*minorUnitsPerCurrency + fracUnits
+	if neg {
+		minorUnits = -minorUnits
+	}
+	return Amount{minorUnits: minorUnits, currency: currency}, nil
+}
+
+// FromFloat64 converts a float64 dollar amount into an Amount in currency.
+// It exists only to bridge legacy call sites during migration; prefer
+// FromString wherever the original value is already textual.
+func FromFloat64(f float64, currency string) Amount {
+	return Amount{minorUnits: int64(f*minorUnitsPerCurrency + 0.5*sign(f)), currency: currency}
+}
+
+func sign(f float64) float64 {
+	if f < 0 {
+		return -1
+	}
+	return 1
+}
+
+// Currency returns the ISO-4217 currency code the amount is denominated in.
+func (a Amount) Currency() string { return a.currency }
+
+// IsZero reports whether the amount is exactly zero, regardless of currency.
+func (a Amount) IsZero() bool { return a.minorUnits == 0 }
+
+// Add returns a + b. It panics if a and b are in different currencies,
+// since adding across currencies without a conversion rate is a bug at the
+// call site, not a runtime condition to recover from.
+func (a Amount) Add(b Amount) Amount {
+	a.mustMatchCurrency(b)
+	return Amount{minorUnits: a.minorUnits + b.minorUnits, currency: a.currency}
+}
+
+// Sub returns a - b. It panics if a and b are in different currencies; see Add.
+func (a Amount) Sub(b Amount) Amount {
+	a.mustMatchCurrency(b)
+	return Amount{minorUnits: a.minorUnits - b.minorUnits, currency: a.currency}
+}
+
+// Cmp returns -1, 0, or 1 if a is less than, equal to, or greater than b. It
+// panics if a and b are in different currencies; see Add.
+func (a Amount) Cmp(b Amount) int {
+	a.mustMatchCurrency(b)
+	switch {
+	case a.minorUnits < b.minorUnits:
+		return -1
+	case a.minorUnits > b.minorUnits:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (a Amount) mustMatchCurrency(b Amount) {
+	if a.currency != b.currency {
+		panic(fmt.Sprintf("money: currency mismatch: %s vs %s", a.currency, b.currency))
+	}
+}
+
+// String renders the amount as a plain decimal string, e.g. "1234.56".
+func (a Amount) String() string {
+	whole := a.minorUnits / minorUnitsPerCurrency
+	frac := a.minorUnits % minorUnitsPerCurrency
+	if frac < 0 {
+		frac = -frac
+	}
+	return fmt.Sprintf("%d.%02d", whole, frac)
+}
+
+// jsonAmount is the wire representation of an Amount.
+type jsonAmount struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as {"value": "1234.56", "currency": "USD"}.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAmount{Value: a.String(), Currency: a.currency})
+}
+
+// UnmarshalJSON decodes an amount encoded by MarshalJSON.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var j jsonAmount
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	parsed, err := FromString(j.Value, j.Currency)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Scan implements database/sql.Scanner, so pgx can read a NUMERIC column
+// straight into an Amount. Since NUMERIC carries no currency, the column it
+// is read from must be paired with a currency column read separately and
+// attached via WithCurrency.
+func (a *Amount) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+		*a = Amount{}
+		return nil
+	default:
+		return fmt.Errorf("money: cannot scan %T into Amount", src)
+	}
+	parsed, err := FromString(s, a.currency)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Value implements database/sql/driver.Valuer, so pgx can write an Amount
+// into a NUMERIC column. The currency is not part of the encoded value; it
+// must be written to its own column alongside it.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// WithCurrency returns a copy of a attributed to currency, used after
+// Scan-ing a bare NUMERIC value to attach the currency read from its sibling
+// column.
+func (a Amount) WithCurrency(currency string) Amount {
+	a.currency = currency
+	return a
+}
@@ -0,0 +1,126 @@
+package money_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/siddarth99/banking-ledger/pkg/money"
+)
+
+func TestFromString(t *testing.T) {
+	t.Run("parses a plain decimal amount", func(t *testing.T) {
+		amount, err := money.FromString("1234.56", "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, "1234.56", amount.String())
+		assert.Equal(t, "USD", amount.Currency())
+	})
+
+	t.Run("parses a negative amount", func(t *testing.T) {
+		amount, err := money.FromString("-100.00", "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, "-100.00", amount.String())
+	})
+
+	t.Run("pads a missing fractional part", func(t *testing.T) {
+		amount, err := money.FromString("50", "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, "50.00", amount.String())
+	})
+
+	t.Run("rejects more than two decimal places", func(t *testing.T) {
+		_, err := money.FromString("1.234", "USD")
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects a malformed amount", func(t *testing.T) {
+		_, err := money.FromString("not-a-number", "USD")
+		assert.Error(t, err)
+	})
+}
+
+func TestAmount_IsZero(t *testing.T) {
+	assert.True(t, money.Zero("USD").IsZero())
+
+	nonZero, err := money.FromString("0.01", "USD")
+	assert.NoError(t, err)
+	assert.False(t, nonZero.IsZero())
+}
+
+func TestAmount_AddSubCmp(t *testing.T) {
+	a, err := money.FromString("100.00", "USD")
+	assert.NoError(t, err)
+	b, err := money.FromString("25.50", "USD")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "125.50", a.Add(b).String())
+	assert.Equal(t, "74.50", a.Sub(b).String())
+	assert.Equal(t, 1, a.Cmp(b))
+	assert.Equal(t, -1, b.Cmp(a))
+	assert.Equal(t, 0, a.Cmp(a))
+}
+
+func TestAmount_CurrencyMismatchPanics(t *testing.T) {
+	usd, err := money.FromString("100.00", "USD")
+	assert.NoError(t, err)
+	eur, err := money.FromString("100.00", "EUR")
+	assert.NoError(t, err)
+
+	assert.Panics(t, func() { usd.Add(eur) })
+	assert.Panics(t, func() { usd.Sub(eur) })
+	assert.Panics(t, func() { usd.Cmp(eur) })
+}
+
+func TestAmount_JSONRoundTrip(t *testing.T) {
+	amount, err := money.FromString("1234.56", "USD")
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(amount)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"value":"1234.56","currency":"USD"}`, string(data))
+
+	var decoded money.Amount
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, amount, decoded)
+}
+
+func TestAmount_ScanAndValue(t *testing.T) {
+	t.Run("Scan from string", func(t *testing.T) {
+		var amount money.Amount
+		assert.NoError(t, amount.Scan("42.10"))
+		assert.Equal(t, "42.10", amount.String())
+	})
+
+	t.Run("Scan from bytes", func(t *testing.T) {
+		var amount money.Amount
+		assert.NoError(t, amount.Scan([]byte("42.10")))
+		assert.Equal(t, "42.10", amount.String())
+	})
+
+	t.Run("Scan from nil", func(t *testing.T) {
+		var amount money.Amount
+		assert.NoError(t, amount.Scan(nil))
+		assert.True(t, amount.IsZero())
+	})
+
+	t.Run("Scan rejects unsupported types", func(t *testing.T) {
+		var amount money.Amount
+		assert.Error(t, amount.Scan(42))
+	})
+
+	t.Run("Value encodes the plain decimal string", func(t *testing.T) {
+		amount, err := money.FromString("42.10", "USD")
+		assert.NoError(t, err)
+		v, err := amount.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "42.10", v)
+	})
+
+	t.Run("WithCurrency attaches the currency read from a sibling column", func(t *testing.T) {
+		var amount money.Amount
+		assert.NoError(t, amount.Scan("42.10"))
+		amount = amount.WithCurrency("USD")
+		assert.Equal(t, "USD", amount.Currency())
+	})
+}
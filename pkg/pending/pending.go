@@ -0,0 +1,378 @@
+// Package pending implements a store for transaction requests that are
+// awaiting client approval before they are published for asynchronous
+// processing.
+package pending
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// State represents the lifecycle stage of a pending transaction request.
+type State string
+
+const (
+	StatePending   State = "PENDING"
+	StateApproved  State = "APPROVED"
+	StateDiscarded State = "DISCARDED"
+	StateInFlight  State = "IN_FLIGHT"
+	StateCompleted State = "COMPLETED"
+	StateFailed    State = "FAILED"
+)
+
+// ErrNotFound is returned when a pending request does not exist or has expired.
+var ErrNotFound = errors.New("pending request not found")
+
+// ErrAlreadyResolved is returned when a caller tries to approve or discard a
+// request that already left the PENDING state.
+var ErrAlreadyResolved = errors.New("pending request is no longer pending")
+
+// ErrTooManyPending is returned when an account already has the maximum
+// number of concurrently pending requests.
+var ErrTooManyPending = errors.New("account has too many pending requests")
+
+// Request is a transaction request held for client review before it is
+// published to the processing queue.
+type Request struct {
+	ID            string
+	AccountNumber string
+	Payload       []byte
+	State         State
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+type entry struct {
+	request Request
+	subs    []chan State
+}
+
+// Requests is an in-memory, per-account-bounded index of pending transaction
+// requests, persisted to the pending_requests table so state survives a
+// process restart.
+type Requests struct {
+	mu            sync.Mutex
+	byID          map[string]*entry
+	perAccount    map[string]int
+	ttl           time.Duration
+	maxPerAccount int
+	db            internal.PgDBConnection
+}
+
+// NewRequests creates a Requests store. ttl bounds how long a request may sit
+// in PENDING before it is treated as expired; maxPerAccount bounds how many
+// requests a single account may have outstanding at once.
+func NewRequests(db internal.PgDBConnection, ttl time.Duration, maxPerAccount int) *Requests {
+	return &Requests{
+		byID:          make(map[string]*entry),
+		perAccount:    make(map[string]int),
+		ttl:           ttl,
+		maxPerAccount: maxPerAccount,
+		db:            db,
+	}
+}
+
+// Add registers a new pending request for the given transaction ID and
+// account, persisting it to Postgres and indexing it in memory.
+func (r *Requests) Add(ctx context.Context, id, accountNumber string, payload []byte) (*Request, error) {
+	var expired []expiredEntry
+	defer func() {
+		// Runs after the r.mu.Unlock below, since defers run LIFO: persisting
+		// expiry to Postgres and notifying Wait()ers must happen with the
+		// lock released, the same order transition and Get use.
+		for _, e := range expired {
+			r.persistExpiry(ctx, e)
+		}
+	}()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expired = r.expireLocked(accountNumber)
+
+	if r.maxPerAccount > 0 && r.perAccount[accountNumber] >= r.maxPerAccount {
+		return nil, ErrTooManyPending
+	}
+
+	now := time.Now()
+	req := Request{
+		ID:            id,
+		AccountNumber: accountNumber,
+		Payload:       payload,
+		State:         StatePending,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(r.ttl),
+	}
+
+	insertQuery := `
+		INSERT INTO pending_requests (
+			transaction_id, account_number, payload, state, created_at, expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	if _, err := r.db.Exec(ctx, insertQuery, req.ID, req.AccountNumber, req.Payload, req.State, req.CreatedAt, req.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	r.byID[id] = &entry{request: req}
+	r.perAccount[accountNumber]++
+
+	return &req, nil
+}
+
+// Get returns the current state of a pending request. A PENDING request
+// whose TTL has already passed is reported as not found - the same outcome
+// Add's own expiry sweep would eventually produce for it - and is expired
+// in Postgres before Get returns, rather than left to silently read back as
+// still PENDING forever.
+func (r *Requests) Get(ctx context.Context, id string) (*Request, bool) {
+	r.mu.Lock()
+	e, ok := r.byID[id]
+	if !ok {
+		r.mu.Unlock()
+		return nil, false
+	}
+	req := e.request
+	expired := req.State == StatePending && pastExpiry(req.ExpiresAt)
+	r.mu.Unlock()
+
+	if expired {
+		r.expirePending(ctx, id, e)
+		return nil, false
+	}
+	return &req, true
+}
+
+// Approve transitions a request from PENDING to APPROVED so the caller can
+// publish it for processing.
+func (r *Requests) Approve(ctx context.Context, id string) (*Request, error) {
+	return r.transition(ctx, id, StatePending, StateApproved)
+}
+
+// Discard transitions a request from PENDING to DISCARDED. A discarded
+// request is terminal and is never published to the processing queue.
+func (r *Requests) Discard(ctx context.Context, id string) (*Request, error) {
+	return r.transition(ctx, id, StatePending, StateDiscarded)
+}
+
+// MarkInFlight records that the approved request has been published and is
+// now being worked on asynchronously.
+func (r *Requests) MarkInFlight(ctx context.Context, id string) (*Request, error) {
+	return r.transition(ctx, id, StateApproved, StateInFlight)
+}
+
+// MarkCompleted records the terminal success state once the worker has
+// finished processing and indexing the transaction. It is called from the
+// worker process after the Elasticsearch write lands.
+func (r *Requests) MarkCompleted(ctx context.Context, id string) (*Request, error) {
+	return r.transition(ctx, id, StateInFlight, StateCompleted)
+}
+
+// MarkFailed records the terminal failure state.
+func (r *Requests) MarkFailed(ctx context.Context, id string) (*Request, error) {
+	return r.transition(ctx, id, StateInFlight, StateFailed)
+}
+
+const selectPendingRequestQuery = `
+	SELECT account_number, payload, state, created_at, expires_at
+	FROM pending_requests
+	WHERE transaction_id = $1
+`
+
+// loadLocked reads id's current row from Postgres and caches it in byID, for
+// when transition is called in a process whose byID never had Add populate
+// it for this ID. Callers must hold r.mu.
+func (r *Requests) loadLocked(ctx context.Context, id string) (*entry, error) {
+	rows, err := r.db.Query(ctx, selectPendingRequestQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending request %s: %w", id, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, ErrNotFound
+	}
+
+	req := Request{ID: id}
+	var state string
+	if err := rows.Scan(&req.AccountNumber, &req.Payload, &state, &req.CreatedAt, &req.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to read pending request %s: %w", id, err)
+	}
+	req.State = State(state)
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	e := &entry{request: req}
+	r.byID[id] = e
+	return e, nil
+}
+
+func (r *Requests) transition(ctx context.Context, id string, from, to State) (*Request, error) {
+	r.mu.Lock()
+
+	e, ok := r.byID[id]
+	if !ok {
+		// Add runs in the API process; MarkInFlight/MarkCompleted/MarkFailed
+		// can run in a worker process that never saw that Add call, so
+		// byID here is only a cache - read the row back from Postgres
+		// rather than treating a cache miss as "doesn't exist".
+		loaded, err := r.loadLocked(ctx, id)
+		if err != nil {
+			r.mu.Unlock()
+			return nil, err
+		}
+		e = loaded
+	}
+
+	if e.request.State == StatePending && pastExpiry(e.request.ExpiresAt) {
+		r.mu.Unlock()
+		r.expirePending(ctx, id, e)
+		return nil, ErrNotFound
+	}
+
+	if e.request.State != from {
+		r.mu.Unlock()
+		return nil, ErrAlreadyResolved
+	}
+
+	e.request.State = to
+	req := e.request
+	subs := e.subs
+	e.subs = nil
+	r.mu.Unlock()
+
+	updateQuery := `UPDATE pending_requests SET state = $1 WHERE transaction_id = $2`
+	if _, err := r.db.Exec(ctx, updateQuery, to, id); err != nil {
+		// Postgres never recorded this transition - roll the in-memory
+		// state back to `from` and give the subscriber channels back to
+		// the entry, so a retried call sees `from` again instead of
+		// ErrAlreadyResolved masking this error, and no Wait()er is told
+		// the transition happened when it didn't.
+		r.mu.Lock()
+		e.request.State = from
+		e.subs = append(subs, e.subs...)
+		r.mu.Unlock()
+		return nil, err
+	}
+
+	for _, ch := range subs {
+		ch <- to
+		close(ch)
+	}
+
+	return &req, nil
+}
+
+// Wait blocks until the request reaches a terminal state (DISCARDED,
+// COMPLETED, or FAILED), the context is cancelled, or the request's TTL
+// expires. It lets UIs subscribe to state transitions that happen within
+// this process without polling.
+func (r *Requests) Wait(ctx context.Context, id string) (State, error) {
+	r.mu.Lock()
+	e, ok := r.byID[id]
+	if !ok {
+		r.mu.Unlock()
+		return "", ErrNotFound
+	}
+	if isTerminal(e.request.State) {
+		state := e.request.State
+		r.mu.Unlock()
+		return state, nil
+	}
+	ch := make(chan State, 1)
+	e.subs = append(e.subs, ch)
+	r.mu.Unlock()
+
+	select {
+	case state := <-ch:
+		return state, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func isTerminal(s State) bool {
+	switch s {
+	case StateDiscarded, StateCompleted, StateFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// pastExpiry reports whether a PENDING request's ExpiresAt has passed.
+func pastExpiry(expiresAt time.Time) bool {
+	return !time.Now().Before(expiresAt)
+}
+
+// expiredEntry is a PENDING request expireLocked has already moved to
+// DISCARDED in memory, pending the Postgres write and subscriber
+// notification its caller still owes it once r.mu is released.
+type expiredEntry struct {
+	id   string
+	subs []chan State
+}
+
+// expireLocked discards any PENDING requests for accountNumber whose TTL has
+// passed, freeing their slot against maxPerAccount, and returns one
+// expiredEntry per request expired this way. Callers must hold r.mu, and
+// must persistExpiry each returned entry after releasing it.
+func (r *Requests) expireLocked(accountNumber string) []expiredEntry {
+	var expired []expiredEntry
+	for id, e := range r.byID {
+		if e.request.AccountNumber != accountNumber || e.request.State != StatePending {
+			continue
+		}
+		if !pastExpiry(e.request.ExpiresAt) {
+			continue
+		}
+		e.request.State = StateDiscarded
+		r.perAccount[accountNumber]--
+		expired = append(expired, expiredEntry{id: id, subs: e.subs})
+		e.subs = nil
+	}
+	return expired
+}
+
+// persistExpiry writes ex's new DISCARDED state to Postgres and notifies any
+// Wait()ers. Callers must not hold r.mu. The Postgres write is best-effort:
+// a failure is logged rather than returned, since the in-memory expiry (and
+// this function's callers, who already report the request as gone) has
+// already happened and can't be un-done.
+func (r *Requests) persistExpiry(ctx context.Context, ex expiredEntry) {
+	updateQuery := `UPDATE pending_requests SET state = $1 WHERE transaction_id = $2`
+	if _, err := r.db.Exec(ctx, updateQuery, StateDiscarded, ex.id); err != nil {
+		log.Printf("failed to persist expiry for pending request %s: %s", ex.id, err)
+	}
+
+	for _, ch := range ex.subs {
+		ch <- StateDiscarded
+		close(ch)
+	}
+}
+
+// expirePending discards e, a PENDING request whose TTL has passed, the same
+// way expireLocked does for Add's own account-scoped sweep - used by Get and
+// transition when they find an expired request outside of that sweep.
+// Callers must not hold r.mu. e's state is re-checked under the lock in case
+// another goroutine already expired or resolved it first.
+func (r *Requests) expirePending(ctx context.Context, id string, e *entry) {
+	r.mu.Lock()
+	if e.request.State != StatePending {
+		r.mu.Unlock()
+		return
+	}
+	e.request.State = StateDiscarded
+	r.perAccount[e.request.AccountNumber]--
+	subs := e.subs
+	e.subs = nil
+	r.mu.Unlock()
+
+	r.persistExpiry(ctx, expiredEntry{id: id, subs: subs})
+}
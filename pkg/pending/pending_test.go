@@ -0,0 +1,241 @@
+package pending_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/pending"
+)
+
+func newTestStore(ttl time.Duration, maxPerAccount int) *pending.Requests {
+	db := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.CommandTag{}, nil
+		},
+		// No row queried for an ID means that ID was never Add()ed anywhere,
+		// which is what a genuinely unknown ID looks like to loadLocked.
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			return &internal.MockPgxRows{NextFunc: func() bool { return false }}, nil
+		},
+	}
+	return pending.NewRequests(db, ttl, maxPerAccount)
+}
+
+func TestRequests_AddAndApprove(t *testing.T) {
+	store := newTestStore(time.Minute, 5)
+
+	req, err := store.Add(context.Background(), "TX1", "ACC1", []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Equal(t, pending.StatePending, req.State)
+
+	approved, err := store.Approve(context.Background(), "TX1")
+	assert.NoError(t, err)
+	assert.Equal(t, pending.StateApproved, approved.State)
+
+	// Re-approving a non-pending request is rejected.
+	_, err = store.Approve(context.Background(), "TX1")
+	assert.ErrorIs(t, err, pending.ErrAlreadyResolved)
+}
+
+func TestRequests_Discard(t *testing.T) {
+	store := newTestStore(time.Minute, 5)
+
+	_, err := store.Add(context.Background(), "TX1", "ACC1", []byte(`{}`))
+	assert.NoError(t, err)
+
+	discarded, err := store.Discard(context.Background(), "TX1")
+	assert.NoError(t, err)
+	assert.Equal(t, pending.StateDiscarded, discarded.State)
+}
+
+func TestRequests_PerAccountLimit(t *testing.T) {
+	store := newTestStore(time.Minute, 1)
+
+	_, err := store.Add(context.Background(), "TX1", "ACC1", []byte(`{}`))
+	assert.NoError(t, err)
+
+	_, err = store.Add(context.Background(), "TX2", "ACC1", []byte(`{}`))
+	assert.ErrorIs(t, err, pending.ErrTooManyPending)
+}
+
+func TestRequests_WaitReturnsOnTransition(t *testing.T) {
+	store := newTestStore(time.Minute, 5)
+	_, err := store.Add(context.Background(), "TX1", "ACC1", []byte(`{}`))
+	assert.NoError(t, err)
+
+	done := make(chan pending.State, 1)
+	go func() {
+		state, err := store.Wait(context.Background(), "TX1")
+		assert.NoError(t, err)
+		done <- state
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	_, err = store.Discard(context.Background(), "TX1")
+	assert.NoError(t, err)
+
+	select {
+	case state := <-done:
+		assert.Equal(t, pending.StateDiscarded, state)
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after state transition")
+	}
+}
+
+func TestRequests_WaitTimesOutViaContext(t *testing.T) {
+	store := newTestStore(time.Minute, 5)
+	_, err := store.Add(context.Background(), "TX1", "ACC1", []byte(`{}`))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = store.Wait(ctx, "TX1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRequests_NotFound(t *testing.T) {
+	store := newTestStore(time.Minute, 5)
+
+	_, err := store.Approve(context.Background(), "missing")
+	assert.ErrorIs(t, err, pending.ErrNotFound)
+}
+
+// TestRequests_TransitionReadsThroughToDB exercises the cross-process case:
+// the API process's Add() and the worker process's MarkCompleted()/
+// MarkFailed() run against independent *Requests backed by the same table,
+// so a transition must read a row it never saw Add()ed back out of Postgres
+// instead of treating its own empty byID cache as authoritative.
+func TestRequests_TransitionReadsThroughToDB(t *testing.T) {
+	var updatedState string
+	db := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			updatedState = string(arguments[0].(pending.State))
+			return pgconn.CommandTag{}, nil
+		},
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			row := 0
+			return &internal.MockPgxRows{
+				NextFunc: func() bool {
+					row++
+					return row <= 1
+				},
+				ScanFunc: func(dest ...interface{}) error {
+					*dest[0].(*string) = "ACC1"
+					*dest[1].(*[]byte) = []byte(`{}`)
+					*dest[2].(*string) = string(pending.StateInFlight)
+					*dest[3].(*time.Time) = time.Now()
+					*dest[4].(*time.Time) = time.Now().Add(time.Minute)
+					return nil
+				},
+			}, nil
+		},
+	}
+
+	worker := pending.NewRequests(db, time.Minute, 5)
+
+	completed, err := worker.MarkCompleted(context.Background(), "TX1")
+	assert.NoError(t, err)
+	assert.Equal(t, pending.StateCompleted, completed.State)
+	assert.Equal(t, string(pending.StateCompleted), updatedState)
+}
+
+// TestRequests_TransitionNotFoundInDB confirms a transition still reports
+// ErrNotFound, rather than a read error, when the ID is genuinely absent
+// from both the in-memory cache and Postgres.
+func TestRequests_TransitionNotFoundInDB(t *testing.T) {
+	db := &internal.MockPgDBConnection{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			return &internal.MockPgxRows{
+				NextFunc: func() bool { return false },
+			}, nil
+		},
+	}
+
+	worker := pending.NewRequests(db, time.Minute, 5)
+
+	_, err := worker.MarkCompleted(context.Background(), "missing")
+	assert.ErrorIs(t, err, pending.ErrNotFound)
+}
+
+// TestRequests_GetExpiresPastTTL confirms Get stops reporting a request as
+// PENDING once its TTL has passed, and persists that expiry to Postgres
+// rather than leaving it to the in-memory map alone.
+func TestRequests_GetExpiresPastTTL(t *testing.T) {
+	var updatedState string
+	db := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			if state, ok := arguments[0].(pending.State); ok {
+				updatedState = string(state)
+			}
+			return pgconn.CommandTag{}, nil
+		},
+	}
+	store := pending.NewRequests(db, time.Millisecond, 5)
+
+	_, err := store.Add(context.Background(), "TX1", "ACC1", []byte(`{}`))
+	assert.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+
+	_, ok := store.Get(context.Background(), "TX1")
+	assert.False(t, ok)
+	assert.Equal(t, string(pending.StateDiscarded), updatedState)
+}
+
+// TestRequests_ApproveRejectsPastTTL confirms transition (via Approve) treats
+// an expired PENDING request as not found instead of letting a client
+// approve a request well past its TTL.
+func TestRequests_ApproveRejectsPastTTL(t *testing.T) {
+	db := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.CommandTag{}, nil
+		},
+	}
+	store := pending.NewRequests(db, time.Millisecond, 5)
+
+	_, err := store.Add(context.Background(), "TX1", "ACC1", []byte(`{}`))
+	assert.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = store.Approve(context.Background(), "TX1")
+	assert.ErrorIs(t, err, pending.ErrNotFound)
+}
+
+// TestRequests_TransitionRollsBackOnDBFailure confirms a failed Postgres
+// UPDATE during a transition leaves the in-memory state at `from` rather
+// than stranding it at `to` - otherwise a retried call would see
+// ErrAlreadyResolved instead of the real DB error, masking it.
+func TestRequests_TransitionRollsBackOnDBFailure(t *testing.T) {
+	boom := errors.New("connection reset")
+	failNextUpdate := true
+	db := &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			if strings.HasPrefix(strings.TrimSpace(sql), "UPDATE") && failNextUpdate {
+				failNextUpdate = false
+				return pgconn.CommandTag{}, boom
+			}
+			return pgconn.CommandTag{}, nil
+		},
+	}
+	store := pending.NewRequests(db, time.Minute, 5)
+
+	_, err := store.Add(context.Background(), "TX1", "ACC1", []byte(`{}`))
+	assert.NoError(t, err)
+
+	_, err = store.Approve(context.Background(), "TX1")
+	assert.ErrorIs(t, err, boom)
+
+	approved, err := store.Approve(context.Background(), "TX1")
+	assert.NoError(t, err)
+	assert.Equal(t, pending.StateApproved, approved.State)
+}
@@ -12,6 +12,7 @@ type PgDBConnection interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
 	Close(ctx context.Context) error
 	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 }
 
 // Transaction interface defines the methods needed for transaction operations
@@ -9,18 +9,18 @@ import (
 
 // MockPgDBConnection implements the pkg.PgDBConnection interface for testing
 type MockPgDBConnection struct {
-	ExecFunc     func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
-	BeginFunc    func(ctx context.Context) (pgx.Tx, error)
-	RollbackFunc func(ctx context.Context) error
-	QueryRowFunc func(ctx context.Context, sql string, args ...any) pgx.Row
-	CloseFunc    func(ctx context.Context) error
-	CommitFunc   func(ctx context.Context) error
-	ConnFunc     func() *pgx.Conn
-	CopyFromFunc func(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowProvider pgx.CopyFromSource) (int64, error)
-	QueryFunc    func(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
-	SendBatchFunc func(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	ExecFunc         func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	BeginFunc        func(ctx context.Context) (pgx.Tx, error)
+	RollbackFunc     func(ctx context.Context) error
+	QueryRowFunc     func(ctx context.Context, sql string, args ...any) pgx.Row
+	CloseFunc        func(ctx context.Context) error
+	CommitFunc       func(ctx context.Context) error
+	ConnFunc         func() *pgx.Conn
+	CopyFromFunc     func(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowProvider pgx.CopyFromSource) (int64, error)
+	QueryFunc        func(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	SendBatchFunc    func(ctx context.Context, b *pgx.Batch) pgx.BatchResults
 	LargeObjectsFunc func() pgx.LargeObjects
-	PrepareFunc func(ctx context.Context, name string, sql string) (*pgconn.StatementDescription, error)
+	PrepareFunc      func(ctx context.Context, name string, sql string) (*pgconn.StatementDescription, error)
 }
 
 // LargeObjects implements pgx.Tx.
@@ -122,3 +122,78 @@ func (m *MockPgxRow) Scan(dest ...interface{}) error {
 	}
 	return nil
 }
+
+// MockPgxRows mocks pgx.Rows
+type MockPgxRows struct {
+	CloseFunc             func()
+	ErrFunc               func() error
+	CommandTagFunc        func() pgconn.CommandTag
+	FieldDescriptionsFunc func() []pgconn.FieldDescription
+	NextFunc              func() bool
+	ScanFunc              func(dest ...interface{}) error
+	ValuesFunc            func() ([]any, error)
+	RawValuesFunc         func() [][]byte
+	ConnFunc              func() *pgx.Conn
+}
+
+func (m *MockPgxRows) Close() {
+	if m.CloseFunc != nil {
+		m.CloseFunc()
+	}
+}
+
+func (m *MockPgxRows) Err() error {
+	if m.ErrFunc != nil {
+		return m.ErrFunc()
+	}
+	return nil
+}
+
+func (m *MockPgxRows) CommandTag() pgconn.CommandTag {
+	if m.CommandTagFunc != nil {
+		return m.CommandTagFunc()
+	}
+	return pgconn.CommandTag{}
+}
+
+func (m *MockPgxRows) FieldDescriptions() []pgconn.FieldDescription {
+	if m.FieldDescriptionsFunc != nil {
+		return m.FieldDescriptionsFunc()
+	}
+	return nil
+}
+
+func (m *MockPgxRows) Next() bool {
+	if m.NextFunc != nil {
+		return m.NextFunc()
+	}
+	return false
+}
+
+func (m *MockPgxRows) Scan(dest ...interface{}) error {
+	if m.ScanFunc != nil {
+		return m.ScanFunc(dest...)
+	}
+	return nil
+}
+
+func (m *MockPgxRows) Values() ([]any, error) {
+	if m.ValuesFunc != nil {
+		return m.ValuesFunc()
+	}
+	return nil, nil
+}
+
+func (m *MockPgxRows) RawValues() [][]byte {
+	if m.RawValuesFunc != nil {
+		return m.RawValuesFunc()
+	}
+	return nil
+}
+
+func (m *MockPgxRows) Conn() *pgx.Conn {
+	if m.ConnFunc != nil {
+		return m.ConnFunc()
+	}
+	return nil
+}
@@ -2,7 +2,13 @@ package pkg
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/google/uuid"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -16,6 +22,7 @@ type AMQPConnectionCloser interface {
 type AMQPConnection interface {
 	AMQPConnectionCloser
 	AMQPChannelCreator
+	AMQPCloseNotifier
 }
 
 // AMQPChannelCreator defines an interface for creating AMQP channels
@@ -48,12 +55,94 @@ type AMQPQueueDeclarer interface {
 	QueueDeclare(name string, durable bool, autoDelete bool, exclusive bool, noWait bool, args amqp.Table) (amqp.Queue, error)
 }
 
+// AMQPQueueInspector defines an interface for inspecting a queue's depth and
+// consumer count without declaring or consuming from it.
+type AMQPQueueInspector interface {
+	// QueueInspect returns the current state of a queue that already exists
+	QueueInspect(name string) (amqp.Queue, error)
+}
+
+// AMQPQueueGetter defines an interface for fetching a single message off a
+// queue without registering a standing consumer, e.g. to peek a dead-letter
+// queue for operator inspection.
+type AMQPQueueGetter interface {
+	// Get fetches one message from queue if one is available. ok is false
+	// if the queue was empty.
+	Get(queue string, autoAck bool) (amqp.Delivery, bool, error)
+}
+
+// AMQPExchangeDeclarer defines an interface for declaring AMQP exchanges,
+// such as the dead-letter exchange a queue's x-dead-letter-exchange arg
+// points at.
+type AMQPExchangeDeclarer interface {
+	// ExchangeDeclare creates or verifies an exchange on the RabbitMQ server
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+}
+
+// AMQPQueueBinder defines an interface for binding a queue to an exchange.
+type AMQPQueueBinder interface {
+	// QueueBind binds name to exchange so messages published with
+	// routing key key land in the queue.
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+}
+
+// AMQPQueueQosSetter defines an interface for capping how many
+// unacknowledged deliveries the broker will hand a channel's consumers at
+// once.
+type AMQPQueueQosSetter interface {
+	// Qos sets the channel's prefetch count/size. global applies the
+	// limit to every consumer on the underlying connection rather than
+	// just this channel.
+	Qos(prefetchCount, prefetchSize int, global bool) error
+}
+
+// AMQPConsumerCanceler defines an interface for stopping a standing
+// consumer registered via AMQPQueueConsumer.Consume.
+type AMQPConsumerCanceler interface {
+	// Cancel stops delivery to the consumer identified by tag. Deliveries
+	// already in flight are unaffected; the broker simply stops sending
+	// new ones.
+	Cancel(consumer string, noWait bool) error
+}
+
+// AMQPCloseNotifier defines an interface for being told when a connection or
+// channel has closed, e.g. because the broker restarted.
+type AMQPCloseNotifier interface {
+	// NotifyClose registers receiver to be sent the error that closed the
+	// connection or channel (nil for a clean, caller-initiated Close).
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+}
+
+// AMQPPublishConfirmer defines an interface for enabling and observing
+// publisher confirms on a channel.
+type AMQPPublishConfirmer interface {
+	// Confirm puts the channel into confirm mode; once enabled, the broker
+	// acknowledges (or negatively acknowledges) every subsequent publish via
+	// the channel registered with NotifyPublish.
+	Confirm(noWait bool) error
+	// NotifyPublish registers confirm to receive one delivery-tag
+	// acknowledgement, in publish order, for every message published after
+	// Confirm is called.
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+	// NotifyReturn registers ret to receive messages the broker could not
+	// route, e.g. a mandatory publish with no matching queue.
+	NotifyReturn(ret chan amqp.Return) chan amqp.Return
+}
+
 // AMQPChannel combines all the capabilities needed from a channel
 type AMQPChannel interface {
 	AMQPChannelCloser
 	AMQPQueueDeclarer
+	AMQPQueueInspector
+	AMQPQueueGetter
 	AMQPQueuePublisher
 	AMQPQueueConsumer
+	AMQPExchangeDeclarer
+	AMQPQueueBinder
+	AMQPPublishConfirmer
+	AMQPCloseNotifier
+	AMQPQueueQosSetter
+	AMQPConsumerCanceler
 }
 
 // AMQPConnectionWrapper wraps an amqp.Connection to implement AMQPConnection interface
@@ -75,11 +164,107 @@ func (w *AMQPConnectionWrapper) Close() error {
 	return w.conn.Close()
 }
 
-// CreateAMQPConnection establishes a connection to a RabbitMQ server
-// url: The connection string for the RabbitMQ server
-// Returns a connection object that can create channels, or an error if connection fails
-func CreateAMQPConnection(url string) (AMQPConnection, error) {
-	conn, err := amqp.Dial(url)
+// NotifyClose forwards to the wrapped connection's NotifyClose.
+func (w *AMQPConnectionWrapper) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	return w.conn.NotifyClose(receiver)
+}
+
+// SASLMechanism selects how a TLS AMQP connection authenticates once the
+// transport is established.
+type SASLMechanism string
+
+const (
+	// SASLMechanismPlain authenticates with the URL's username/password,
+	// RabbitMQ's default. It is the zero value of SASLMechanism.
+	SASLMechanismPlain SASLMechanism = "PLAIN"
+	// SASLMechanismExternal authenticates using the TLS client certificate
+	// itself, with no username/password exchanged; ClientCertPEM and
+	// ClientKeyPEM are required.
+	SASLMechanismExternal SASLMechanism = "EXTERNAL"
+)
+
+// AMQPConfig configures a RabbitMQ connection: a URL plus optional TLS
+// material and a SASL mechanism, mirroring the TLS/client-cert options
+// telegraf's AMQP output plugin exposes so the ledger can run against a
+// managed broker (CloudAMQP, AWS MQ) that mandates both.
+type AMQPConfig struct {
+	// URL is the amqp:// or amqps:// connection string, including
+	// credentials for SASLMechanismPlain.
+	URL string
+
+	// CACertPEM, if set, authenticates the broker's certificate against a
+	// pool seeded with it instead of the system root pool.
+	CACertPEM []byte
+	// ClientCertPEM and ClientKeyPEM present a client certificate during
+	// the TLS handshake; both are required together, and required at all
+	// when SASLMechanism is SASLMechanismExternal.
+	ClientCertPEM []byte
+	ClientKeyPEM  []byte
+	// InsecureSkipVerify disables verification of the broker's certificate
+	// chain and hostname. Only ever set for local development.
+	InsecureSkipVerify bool
+
+	// SASLMechanism selects the authentication mechanism for a TLS
+	// connection; the zero value behaves as SASLMechanismPlain. Ignored
+	// when no TLS material is configured.
+	SASLMechanism SASLMechanism
+}
+
+// tlsConfig builds a *tls.Config from cfg's PEM material, reporting whether
+// TLS was requested at all - true as soon as any CA/client cert material or
+// InsecureSkipVerify is set.
+func (cfg AMQPConfig) tlsConfig() (*tls.Config, bool, error) {
+	if len(cfg.CACertPEM) == 0 && len(cfg.ClientCertPEM) == 0 && len(cfg.ClientKeyPEM) == 0 && !cfg.InsecureSkipVerify {
+		return nil, false, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if len(cfg.CACertPEM) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(cfg.CACertPEM) {
+			return nil, false, errors.New("failed to parse CA certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(cfg.ClientCertPEM) > 0 || len(cfg.ClientKeyPEM) > 0 {
+		cert, err := tls.X509KeyPair(cfg.ClientCertPEM, cfg.ClientKeyPEM)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, true, nil
+}
+
+// CreateAMQPConnectionWithConfig establishes a connection to a RabbitMQ
+// server using cfg. With no TLS material set it dials plainly via
+// amqp.Dial; with TLS material but SASLMechanismPlain (the default) it
+// dials via amqp.DialTLS; with SASLMechanismExternal it dials via
+// amqp.DialConfig so the client certificate itself - not a username and
+// password - authenticates the connection.
+// Returns a connection object that can create channels, or an error if
+// connection fails.
+func CreateAMQPConnectionWithConfig(cfg AMQPConfig) (AMQPConnection, error) {
+	tlsConfig, useTLS, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build TLS config: %w", err)
+	}
+
+	var conn *amqp.Connection
+	switch {
+	case !useTLS:
+		conn, err = amqp.Dial(cfg.URL)
+	case cfg.SASLMechanism == SASLMechanismExternal:
+		conn, err = amqp.DialConfig(cfg.URL, amqp.Config{
+			TLSClientConfig: tlsConfig,
+			SASL:            []amqp.Authentication{&amqp.ExternalAuth{}},
+		})
+	default:
+		conn, err = amqp.DialTLS(cfg.URL, tlsConfig)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +272,15 @@ func CreateAMQPConnection(url string) (AMQPConnection, error) {
 	return &AMQPConnectionWrapper{conn: conn}, nil
 }
 
+// CreateAMQPConnection establishes a plain, unencrypted connection to a
+// RabbitMQ server. It is a thin wrapper around
+// CreateAMQPConnectionWithConfig for callers that don't need TLS.
+// url: The connection string for the RabbitMQ server
+// Returns a connection object that can create channels, or an error if connection fails
+func CreateAMQPConnection(url string) (AMQPConnection, error) {
+	return CreateAMQPConnectionWithConfig(AMQPConfig{URL: url})
+}
+
 // CloseAMQPConnection gracefully closes an AMQP connection
 // conn: The connection to close
 // Returns any error encountered during closing
@@ -120,20 +314,67 @@ func CloseAMQPChannel(channel AMQPChannelCloser) error {
 // autoDelete: If true, queue will be deleted when no consumers remain
 // exclusive: If true, queue can only be used by this connection
 // noWait: If true, don't wait for server confirmation
+// args: Additional arguments for queue declaration, e.g. x-dead-letter-exchange; may be nil
 // Returns the declared queue and any error encountered
-func QueueDeclare(channel AMQPQueueDeclarer, name string, durable, autoDelete, exclusive, noWait bool) (amqp.Queue, error) {
+func QueueDeclare(channel AMQPQueueDeclarer, name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
 	q, err := channel.QueueDeclare(
 		name,       // name of the queue
 		durable,    // durable: queue will survive broker restart if true
 		autoDelete, // delete when unused: queue will be deleted when no consumers if true
 		exclusive,  // exclusive: queue can only be used by this connection if true
 		noWait,     // no-wait: don't wait for server confirmation if true
-		nil,        // arguments for queue declaration
+		args,       // arguments for queue declaration
 	)
 
 	return q, err
 }
 
+// QueueInspect returns the depth and consumer count of an existing queue.
+// channel: The channel to use for inspection
+// name: The name of the queue to inspect
+// Returns the queue's current state and any error encountered
+func QueueInspect(channel AMQPQueueInspector, name string) (amqp.Queue, error) {
+	return channel.QueueInspect(name)
+}
+
+// ExchangeDeclare creates an exchange on the RabbitMQ server if it doesn't
+// exist, such as a dead-letter exchange a queue's x-dead-letter-exchange arg
+// points at.
+// channel: The channel to use for exchange declaration
+// name: The name of the exchange to declare
+// kind: The exchange type, e.g. "direct", "fanout", "topic"
+// durable: If true, exchange will survive broker restart
+// autoDelete: If true, exchange will be deleted once no queues are bound to it
+// Returns any error encountered during declaration
+func ExchangeDeclare(channel AMQPExchangeDeclarer, name, kind string, durable, autoDelete bool) error {
+	return channel.ExchangeDeclare(
+		name,
+		kind,
+		durable,
+		autoDelete,
+		false, // internal: clients may publish to this exchange directly
+		false, // no-wait: wait for server confirmation
+		nil,   // arguments for exchange declaration
+	)
+}
+
+// QueueBind binds queue to exchange under routingKey, so messages published
+// to exchange with that routing key land in queue.
+// channel: The channel to use for binding
+// queue: The name of the queue to bind
+// routingKey: The routing key to bind under
+// exchange: The name of the exchange to bind to
+// Returns any error encountered during binding
+func QueueBind(channel AMQPQueueBinder, queue, routingKey, exchange string) error {
+	return channel.QueueBind(
+		queue,
+		routingKey,
+		exchange,
+		false, // no-wait: wait for server confirmation
+		nil,   // arguments for binding
+	)
+}
+
 // PublishWithContext publishes a message to a RabbitMQ exchange with context support
 // ctx: Context for the operation, allowing for cancellation and timeouts
 // body: The message content to publish
@@ -157,6 +398,85 @@ func PublishWithContext(ctx context.Context, body []byte, channel AMQPQueuePubli
 	return err
 }
 
+// PublishWithHeaders is PublishWithContext plus an explicit amqp.Table of
+// headers, for callers that need to carry broker-stamped headers (e.g.
+// x-death) across a manual republish instead of starting a fresh message.
+func PublishWithHeaders(ctx context.Context, body []byte, headers amqp.Table, channel AMQPQueuePublisher, exchange, key string, mandatory, immediate bool) error {
+	err := channel.PublishWithContext(ctx,
+		exchange,  // exchange
+		key,       // routing key
+		mandatory, // mandatory
+		immediate, // immediate
+		amqp.Publishing{
+			ContentType: "text/plain",
+			Headers:     headers,
+			Body:        body,
+		})
+
+	return err
+}
+
+// ErrReplyTimeout is returned by PublishAndAwaitReply when no reply matching
+// the request's CorrelationId arrives before timeout elapses or ctx is
+// done.
+var ErrReplyTimeout = errors.New("timed out waiting for reply")
+
+// PublishAndAwaitReply publishes body to exchange under key and blocks for
+// the matching reply, implementing the request/reply pattern from
+// amqp091-go's RPC client example: it declares an exclusive, auto-delete
+// reply queue scoped to this one call, sets it as the publish's ReplyTo,
+// tags the publish with a generated CorrelationId, and returns the body of
+// the first delivery on the reply queue whose CorrelationId matches -
+// discarding anything else, since the auto-generated queue name means no
+// other caller can be sharing it, but a slow consumer could otherwise still
+// have a stale delivery sitting in the channel.
+//
+// It returns ErrReplyTimeout if ctx is done or timeout elapses first. The
+// caller on the other end of key must publish its response to the
+// delivery's ReplyTo with the same CorrelationId for this to ever return.
+func PublishAndAwaitReply(ctx context.Context, channel AMQPChannel, exchange, key string, body []byte, timeout time.Duration) ([]byte, error) {
+	replyQueue, err := channel.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to declare reply queue: %w", err)
+	}
+
+	deliveries, err := channel.Consume(replyQueue.Name, "rpc-"+replyQueue.Name, true, true, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume reply queue %s: %w", replyQueue.Name, err)
+	}
+
+	correlationID := uuid.New().String()
+	err = channel.PublishWithContext(ctx, exchange, key, false, false, amqp.Publishing{
+		ContentType:   "application/json",
+		CorrelationId: correlationID,
+		ReplyTo:       replyQueue.Name,
+		Body:          body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish request: %w", err)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ErrReplyTimeout
+		case <-deadline.C:
+			return nil, ErrReplyTimeout
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return nil, ErrReplyTimeout
+			}
+			if delivery.CorrelationId != correlationID {
+				continue
+			}
+			return delivery.Body, nil
+		}
+	}
+}
+
 // Consume starts consuming messages from a RabbitMQ queue
 // channel: The channel to use for consuming
 // queue: The name of the queue to consume from
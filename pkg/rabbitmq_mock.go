@@ -23,11 +23,83 @@ func (m *MockAMQPChannel) PublishWithContext(ctx context.Context, exchange, key
 }
 
 func (m *MockAMQPChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, table amqp.Table) (amqp.Queue, error) {
-	args := m.Called(name, durable, autoDelete, exclusive, noWait, nil)
+	args := m.Called(name, durable, autoDelete, exclusive, noWait, table)
 	return amqp.Queue{}, args.Error(0)
 }
 
-func (m *MockAMQPChannel) Consume(name,	consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+func (m *MockAMQPChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	callArgs := m.Called(name, kind, durable, autoDelete, internal, noWait, args)
+	return callArgs.Error(0)
+}
+
+func (m *MockAMQPChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	callArgs := m.Called(name, key, exchange, noWait, args)
+	return callArgs.Error(0)
+}
+
+func (m *MockAMQPChannel) Confirm(noWait bool) error {
+	args := m.Called(noWait)
+	return args.Error(0)
+}
+
+func (m *MockAMQPChannel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	args := m.Called(confirm)
+	return args.Get(0).(chan amqp.Confirmation)
+}
+
+func (m *MockAMQPChannel) NotifyReturn(ret chan amqp.Return) chan amqp.Return {
+	args := m.Called(ret)
+	return args.Get(0).(chan amqp.Return)
+}
+
+func (m *MockAMQPChannel) Consume(name, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
 	mockArgs := m.Called(name, consumer, autoAck, exclusive, noLocal, noWait, nil)
 	return mockArgs.Get(0).(<-chan amqp.Delivery), mockArgs.Error(1)
-}
\ No newline at end of file
+}
+
+func (m *MockAMQPChannel) QueueInspect(name string) (amqp.Queue, error) {
+	args := m.Called(name)
+	return args.Get(0).(amqp.Queue), args.Error(1)
+}
+
+func (m *MockAMQPChannel) Get(queue string, autoAck bool) (amqp.Delivery, bool, error) {
+	args := m.Called(queue, autoAck)
+	delivery, _ := args.Get(0).(amqp.Delivery)
+	return delivery, args.Bool(1), args.Error(2)
+}
+
+func (m *MockAMQPChannel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	args := m.Called(receiver)
+	return args.Get(0).(chan *amqp.Error)
+}
+
+func (m *MockAMQPChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	args := m.Called(prefetchCount, prefetchSize, global)
+	return args.Error(0)
+}
+
+func (m *MockAMQPChannel) Cancel(consumer string, noWait bool) error {
+	args := m.Called(consumer, noWait)
+	return args.Error(0)
+}
+
+// MockAMQPConnection is a mock implementation of the internal.AMQPConnection interface
+type MockAMQPConnection struct {
+	mock.Mock
+}
+
+func (m *MockAMQPConnection) Channel() (AMQPChannel, error) {
+	args := m.Called()
+	channel, _ := args.Get(0).(AMQPChannel)
+	return channel, args.Error(1)
+}
+
+func (m *MockAMQPConnection) Close() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockAMQPConnection) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	args := m.Called(receiver)
+	return args.Get(0).(chan *amqp.Error)
+}
@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPublishAndAwaitReply_ReturnsMatchingReply(t *testing.T) {
+	channel := &MockAMQPChannel{}
+	channel.On("QueueDeclare", "", false, true, true, false, amqp.Table(nil)).Return(nil)
+
+	deliveries := make(chan amqp.Delivery, 1)
+	channel.On("Consume", "", "rpc-", true, true, false, false, nil).Return((<-chan amqp.Delivery)(deliveries), nil)
+
+	var published amqp.Publishing
+	channel.On("PublishWithContext", mock.Anything, "", "transaction_processor", false, false, mock.Anything).
+		Run(func(args mock.Arguments) {
+			published = args.Get(5).(amqp.Publishing)
+			deliveries <- amqp.Delivery{CorrelationId: published.CorrelationId, Body: []byte(`{"status":"ok"}`)}
+		}).
+		Return(nil)
+
+	body, err := PublishAndAwaitReply(context.Background(), channel, "", "transaction_processor", []byte(`{"transactionId":"TX1"}`), time.Second)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"status":"ok"}`, string(body))
+	assert.NotEmpty(t, published.CorrelationId)
+	assert.Equal(t, published.ReplyTo, "")
+	channel.AssertExpectations(t)
+}
+
+func TestPublishAndAwaitReply_IgnoresMismatchedCorrelationId(t *testing.T) {
+	channel := &MockAMQPChannel{}
+	channel.On("QueueDeclare", "", false, true, true, false, amqp.Table(nil)).Return(nil)
+
+	deliveries := make(chan amqp.Delivery, 2)
+	channel.On("Consume", "", "rpc-", true, true, false, false, nil).Return((<-chan amqp.Delivery)(deliveries), nil)
+
+	channel.On("PublishWithContext", mock.Anything, "", "transaction_processor", false, false, mock.Anything).
+		Run(func(args mock.Arguments) {
+			published := args.Get(5).(amqp.Publishing)
+			deliveries <- amqp.Delivery{CorrelationId: "someone-elses-request", Body: []byte(`{"stale":true}`)}
+			deliveries <- amqp.Delivery{CorrelationId: published.CorrelationId, Body: []byte(`{"status":"ok"}`)}
+		}).
+		Return(nil)
+
+	body, err := PublishAndAwaitReply(context.Background(), channel, "", "transaction_processor", []byte(`{}`), time.Second)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"status":"ok"}`, string(body))
+}
+
+func TestPublishAndAwaitReply_TimesOutWithNoReply(t *testing.T) {
+	channel := &MockAMQPChannel{}
+	channel.On("QueueDeclare", "", false, true, true, false, amqp.Table(nil)).Return(nil)
+
+	deliveries := make(chan amqp.Delivery)
+	channel.On("Consume", "", "rpc-", true, true, false, false, nil).Return((<-chan amqp.Delivery)(deliveries), nil)
+	channel.On("PublishWithContext", mock.Anything, "", "transaction_processor", false, false, mock.Anything).Return(nil)
+
+	_, err := PublishAndAwaitReply(context.Background(), channel, "", "transaction_processor", []byte(`{}`), 10*time.Millisecond)
+
+	assert.ErrorIs(t, err, ErrReplyTimeout)
+}
@@ -0,0 +1,329 @@
+// Package resilientamqp wraps an internal.AMQPConnection with automatic
+// reconnection and publisher confirms, so a RabbitMQ restart doesn't force a
+// service restart. It registers a topology (exchanges, queues, bindings) and
+// consumers up front; whenever the broker connection or channel reports
+// NotifyClose, it redials with exponential backoff and full jitter - the
+// reconnect pattern telegraf's amqp output plugin uses - and replays that
+// topology before any caller's next Publish or Consume resumes.
+package resilientamqp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// Default reconnect backoff tuning for New.
+const (
+	DefaultBaseBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff  = 30 * time.Second
+)
+
+// ErrNacked is returned by PublishJSON when the broker negatively
+// acknowledges a publish.
+var ErrNacked = errors.New("broker nacked publish")
+
+// ErrPublishTimeout is returned by PublishJSON when the broker acknowledges
+// neither an ack nor a return before the caller's timeout elapses.
+var ErrPublishTimeout = errors.New("timed out waiting for publish confirmation")
+
+// Dialer opens a fresh AMQP connection, e.g. internal.CreateAMQPConnection
+// bound to a broker URL. New takes one directly (rather than a URL) so
+// tests can substitute a fake instead of dialing a real broker.
+type Dialer func() (internal.AMQPConnection, error)
+
+// ExchangeDecl mirrors the arguments internal.ExchangeDeclare takes.
+type ExchangeDecl struct {
+	Name, Kind          string
+	Durable, AutoDelete bool
+}
+
+// QueueDecl mirrors the arguments internal.QueueDeclare takes.
+type QueueDecl struct {
+	Name                                   string
+	Durable, AutoDelete, Exclusive, NoWait bool
+	Args                                   amqp.Table
+}
+
+// BindingDecl mirrors the arguments internal.QueueBind takes.
+type BindingDecl struct {
+	Queue, Key, Exchange string
+}
+
+// ConsumerDecl mirrors the arguments internal.Consume takes. Handler runs in
+// its own goroutine against the delivery channel returned by every (re)dial;
+// it returns when that channel closes, at which point the client restarts it
+// against the channel opened by the next successful reconnect.
+type ConsumerDecl struct {
+	Queue, Consumer                     string
+	AutoAck, Exclusive, NoLocal, NoWait bool
+	Args                                amqp.Table
+	Handler                             func(<-chan amqp.Delivery)
+}
+
+// Client owns a single AMQP connection and channel, transparently redialing
+// and replaying its registered topology when the broker drops either one.
+// Publishing goes through PublishJSON rather than the raw channel, since the
+// channel the client is currently holding can be swapped out from under a
+// caller at any time.
+type Client struct {
+	dial        Dialer
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+
+	mu       sync.RWMutex
+	conn     internal.AMQPConnection
+	channel  internal.AMQPChannel
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+
+	// publishMu serializes PublishJSON calls: NotifyPublish/NotifyReturn
+	// only deliver one ack (or return) per publish, in publish order, so it
+	// must stay held across a publish and the wait that follows it -
+	// otherwise a concurrent caller's ack could be mistaken for this one's.
+	publishMu sync.Mutex
+
+	// topologyMu guards the registered-declaration slices below, which are
+	// replayed - in registration order - against every channel connect
+	// opens, including the very first one.
+	topologyMu sync.Mutex
+	exchanges  []ExchangeDecl
+	queues     []QueueDecl
+	bindings   []BindingDecl
+	consumers  []ConsumerDecl
+
+	closed chan struct{}
+}
+
+// New dials an initial connection via dial and starts the background
+// goroutine that watches for NotifyClose and redials on it. baseBackoff and
+// maxBackoff bound the reconnect schedule; either may be zero to use
+// DefaultBaseBackoff/DefaultMaxBackoff.
+func New(dial Dialer, baseBackoff, maxBackoff time.Duration) (*Client, error) {
+	if baseBackoff <= 0 {
+		baseBackoff = DefaultBaseBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	c := &Client{
+		dial:        dial,
+		baseBackoff: baseBackoff,
+		maxBackoff:  maxBackoff,
+		closed:      make(chan struct{}),
+	}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	go c.watch()
+	return c, nil
+}
+
+// DeclareExchange registers decl to be re-declared on every reconnect, and
+// declares it against the current channel immediately.
+func (c *Client) DeclareExchange(decl ExchangeDecl) error {
+	c.topologyMu.Lock()
+	c.exchanges = append(c.exchanges, decl)
+	c.topologyMu.Unlock()
+	return internal.ExchangeDeclare(c.currentChannel(), decl.Name, decl.Kind, decl.Durable, decl.AutoDelete)
+}
+
+// DeclareQueue registers decl to be re-declared on every reconnect, and
+// declares it against the current channel immediately.
+func (c *Client) DeclareQueue(decl QueueDecl) (amqp.Queue, error) {
+	c.topologyMu.Lock()
+	c.queues = append(c.queues, decl)
+	c.topologyMu.Unlock()
+	return internal.QueueDeclare(c.currentChannel(), decl.Name, decl.Durable, decl.AutoDelete, decl.Exclusive, decl.NoWait, decl.Args)
+}
+
+// Bind registers decl to be re-bound on every reconnect, and binds it
+// against the current channel immediately.
+func (c *Client) Bind(decl BindingDecl) error {
+	c.topologyMu.Lock()
+	c.bindings = append(c.bindings, decl)
+	c.topologyMu.Unlock()
+	return internal.QueueBind(c.currentChannel(), decl.Queue, decl.Key, decl.Exchange)
+}
+
+// Consume registers decl so decl.Handler is (re-)started against every
+// reconnect's delivery channel, and starts it against the current one
+// immediately.
+func (c *Client) Consume(decl ConsumerDecl) error {
+	c.topologyMu.Lock()
+	c.consumers = append(c.consumers, decl)
+	c.topologyMu.Unlock()
+	return startConsumer(c.currentChannel(), decl)
+}
+
+func startConsumer(channel internal.AMQPChannel, decl ConsumerDecl) error {
+	deliveries, err := internal.Consume(channel, decl.Queue, decl.Consumer, decl.AutoAck, decl.Exclusive, decl.NoLocal, decl.NoWait, decl.Args)
+	if err != nil {
+		return fmt.Errorf("failed to consume %s: %w", decl.Queue, err)
+	}
+	go decl.Handler(deliveries)
+	return nil
+}
+
+// PublishJSON marshals v and publishes it as a mandatory message to
+// exchange/key on a channel already in confirm mode, blocking until the
+// broker acks it, returns it as unroutable, or timeout elapses - whichever
+// happens first. Concurrent calls are serialized via publishMu so one
+// caller's ack can never be read by another waiting on a different publish.
+func (c *Client) PublishJSON(ctx context.Context, exchange, key string, v interface{}, timeout time.Duration) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	c.mu.RLock()
+	channel := c.channel
+	confirms := c.confirms
+	returns := c.returns
+	c.mu.RUnlock()
+
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+
+	if err := internal.PublishWithContext(ctx, body, channel, exchange, key, true, false); err != nil {
+		return err
+	}
+
+	select {
+	case ret := <-returns:
+		return fmt.Errorf("message returned as unroutable: %s", ret.ReplyText)
+	case conf := <-confirms:
+		if !conf.Ack {
+			return ErrNacked
+		}
+		return nil
+	case <-time.After(timeout):
+		return ErrPublishTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) currentChannel() internal.AMQPChannel {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.channel
+}
+
+// connect dials a fresh connection and channel, puts the channel into
+// confirm mode, and replays every registered exchange, queue, binding and
+// consumer against it, in registration order.
+func (c *Client) connect() error {
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		conn.Close()
+		return fmt.Errorf("failed to put channel into confirm mode: %w", err)
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.channel = channel
+	c.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	c.returns = channel.NotifyReturn(make(chan amqp.Return, 1))
+	c.mu.Unlock()
+
+	c.topologyMu.Lock()
+	defer c.topologyMu.Unlock()
+	for _, decl := range c.exchanges {
+		if err := internal.ExchangeDeclare(channel, decl.Name, decl.Kind, decl.Durable, decl.AutoDelete); err != nil {
+			return fmt.Errorf("failed to redeclare exchange %s: %w", decl.Name, err)
+		}
+	}
+	for _, decl := range c.queues {
+		if _, err := internal.QueueDeclare(channel, decl.Name, decl.Durable, decl.AutoDelete, decl.Exclusive, decl.NoWait, decl.Args); err != nil {
+			return fmt.Errorf("failed to redeclare queue %s: %w", decl.Name, err)
+		}
+	}
+	for _, decl := range c.bindings {
+		if err := internal.QueueBind(channel, decl.Queue, decl.Key, decl.Exchange); err != nil {
+			return fmt.Errorf("failed to rebind queue %s: %w", decl.Queue, err)
+		}
+	}
+	for _, decl := range c.consumers {
+		if err := startConsumer(channel, decl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watch waits for the current connection or channel to report NotifyClose,
+// then redials with exponential backoff and full jitter - the same pattern
+// webhook.Dispatcher and esresilience.Client use - until connect succeeds.
+func (c *Client) watch() {
+	for {
+		c.mu.RLock()
+		conn := c.conn
+		channel := c.channel
+		c.mu.RUnlock()
+
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		channelClosed := channel.NotifyClose(make(chan *amqp.Error, 1))
+
+		select {
+		case <-c.closed:
+			return
+		case err := <-connClosed:
+			log.Printf("resilientamqp: connection closed: %v", err)
+		case err := <-channelClosed:
+			log.Printf("resilientamqp: channel closed: %v", err)
+		}
+
+		for attempt := 1; ; attempt++ {
+			select {
+			case <-c.closed:
+				return
+			default:
+			}
+			if err := c.connect(); err != nil {
+				log.Printf("resilientamqp: reconnect attempt %d failed: %v", attempt, err)
+				time.Sleep(c.backoff(attempt))
+				continue
+			}
+			log.Println("resilientamqp: reconnected")
+			break
+		}
+	}
+}
+
+// backoff computes the exponential delay before the next reconnect attempt,
+// with full jitter, capped at c.maxBackoff.
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.baseBackoff * time.Duration(uint64(1)<<uint(attempt-1))
+	if ceiling > c.maxBackoff {
+		ceiling = c.maxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// Close stops the reconnect watcher and closes the underlying connection.
+func (c *Client) Close() error {
+	close(c.closed)
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+	return conn.Close()
+}
@@ -0,0 +1,296 @@
+package resilientamqp_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/resilientamqp"
+)
+
+// fakeChannel is a minimal internal.AMQPChannel that records declarations
+// and lets a test control confirmations and closure, since the real
+// amqp091-go channel needs a live broker.
+type fakeChannel struct {
+	mu sync.Mutex
+
+	confirms chan amqp.Confirmation
+	closedCh chan *amqp.Error
+
+	nextAck           bool
+	stallPublish      bool
+	ackDelay          time.Duration
+	declaredQueues    []string
+	declaredExchanges []string
+	consumed          []string
+}
+
+func newFakeChannel() *fakeChannel {
+	return &fakeChannel{nextAck: true}
+}
+
+func (f *fakeChannel) Close() error { return nil }
+
+func (f *fakeChannel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	f.mu.Lock()
+	stall := f.stallPublish
+	f.mu.Unlock()
+	if stall {
+		return nil
+	}
+	go func() {
+		f.mu.Lock()
+		ack := f.nextAck
+		confirms := f.confirms
+		delay := f.ackDelay
+		f.mu.Unlock()
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		confirms <- amqp.Confirmation{Ack: ack}
+	}()
+	return nil
+}
+
+func (f *fakeChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.declaredQueues = append(f.declaredQueues, name)
+	return amqp.Queue{Name: name}, nil
+}
+
+func (f *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.declaredExchanges = append(f.declaredExchanges, name)
+	return nil
+}
+
+func (f *fakeChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	return nil
+}
+
+func (f *fakeChannel) Confirm(noWait bool) error { return nil }
+
+func (f *fakeChannel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	f.mu.Lock()
+	f.confirms = confirm
+	f.mu.Unlock()
+	return confirm
+}
+
+func (f *fakeChannel) NotifyReturn(ret chan amqp.Return) chan amqp.Return {
+	return ret
+}
+
+func (f *fakeChannel) Consume(name, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.consumed = append(f.consumed, name)
+	return make(chan amqp.Delivery), nil
+}
+
+func (f *fakeChannel) QueueInspect(name string) (amqp.Queue, error) {
+	return amqp.Queue{Name: name}, nil
+}
+
+func (f *fakeChannel) Get(queue string, autoAck bool) (amqp.Delivery, bool, error) {
+	return amqp.Delivery{}, false, nil
+}
+
+func (f *fakeChannel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	f.mu.Lock()
+	f.closedCh = receiver
+	f.mu.Unlock()
+	return receiver
+}
+
+func (f *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error { return nil }
+
+func (f *fakeChannel) Cancel(consumer string, noWait bool) error { return nil }
+
+// fakeConn is a minimal internal.AMQPConnection wrapping a single
+// fakeChannel, since resilientamqp only ever opens one channel per
+// connection.
+type fakeConn struct {
+	channel *fakeChannel
+}
+
+func (f *fakeConn) Channel() (internal.AMQPChannel, error) { return f.channel, nil }
+func (f *fakeConn) Close() error                           { return nil }
+func (f *fakeConn) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	return receiver
+}
+
+// channelLog records every fakeChannel a dialer has created, safe to read
+// and append to concurrently - dial runs on the client's own watch
+// goroutine, while a test reads the log from the main test goroutine.
+type channelLog struct {
+	mu       sync.Mutex
+	channels []*fakeChannel
+}
+
+func (l *channelLog) add(ch *fakeChannel) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.channels = append(l.channels, ch)
+}
+
+func (l *channelLog) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.channels)
+}
+
+func (l *channelLog) at(i int) *fakeChannel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.channels[i]
+}
+
+// dialerOf returns a resilientamqp.Dialer that hands back a fresh fakeConn
+// (and fakeChannel) on every call, recording each channel it creates in log
+// so a test can inspect every connection the client has opened.
+func dialerOf(log *channelLog) resilientamqp.Dialer {
+	return func() (internal.AMQPConnection, error) {
+		ch := newFakeChannel()
+		log.add(ch)
+		return &fakeConn{channel: ch}, nil
+	}
+}
+
+func TestClient_PublishJSONWaitsForBrokerAck(t *testing.T) {
+	log := &channelLog{}
+	client, err := resilientamqp.New(dialerOf(log), time.Millisecond, 10*time.Millisecond)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	err = client.PublishJSON(context.Background(), "", "account_creator", map[string]string{"hello": "world"}, time.Second)
+
+	assert.NoError(t, err)
+}
+
+func TestClient_PublishJSONSurfacesANack(t *testing.T) {
+	log := &channelLog{}
+	client, err := resilientamqp.New(dialerOf(log), time.Millisecond, 10*time.Millisecond)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	first := log.at(0)
+	first.mu.Lock()
+	first.nextAck = false
+	first.mu.Unlock()
+
+	err = client.PublishJSON(context.Background(), "", "account_creator", map[string]string{"hello": "world"}, time.Second)
+
+	assert.ErrorIs(t, err, resilientamqp.ErrNacked)
+}
+
+func TestClient_PublishJSONTimesOutWithNoConfirmation(t *testing.T) {
+	log := &channelLog{}
+	client, err := resilientamqp.New(dialerOf(log), time.Millisecond, 10*time.Millisecond)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	first := log.at(0)
+	first.mu.Lock()
+	first.stallPublish = true
+	first.mu.Unlock()
+
+	err = client.PublishJSON(context.Background(), "", "account_creator", map[string]string{"hello": "world"}, 10*time.Millisecond)
+
+	assert.ErrorIs(t, err, resilientamqp.ErrPublishTimeout)
+}
+
+// TestClient_PublishJSONSerializesConcurrentCallers guards against
+// publishMu regressing: without it, two concurrent PublishJSON calls race
+// on the shared confirms channel and can read each other's ack, since
+// NotifyPublish only ever delivers one confirmation per publish. With
+// publishMu held across publish-and-await, concurrent callers queue up and
+// each publish's ack-wait only overlaps the next publish's send, never its
+// wait - so N calls with a per-ack delay take at least N*delay in total.
+func TestClient_PublishJSONSerializesConcurrentCallers(t *testing.T) {
+	log := &channelLog{}
+	client, err := resilientamqp.New(dialerOf(log), time.Millisecond, 10*time.Millisecond)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	const ackDelay = 20 * time.Millisecond
+	const callers = 5
+
+	first := log.at(0)
+	first.mu.Lock()
+	first.ackDelay = ackDelay
+	first.mu.Unlock()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.PublishJSON(context.Background(), "", "account_creator", map[string]string{"hello": "world"}, time.Second)
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, elapsed, callers*ackDelay, "publishes were not serialized: concurrent callers overlapped their ack waits")
+}
+
+func TestClient_ReconnectsAndReplaysTopologyAfterNotifyClose(t *testing.T) {
+	log := &channelLog{}
+	client, err := resilientamqp.New(dialerOf(log), time.Millisecond, 10*time.Millisecond)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	assert.NoError(t, client.DeclareExchange(resilientamqp.ExchangeDecl{Name: "account_creator.dlx", Kind: "direct", Durable: true}))
+	if _, err := client.DeclareQueue(resilientamqp.QueueDecl{Name: "account_creator", Durable: true}); err != nil {
+		t.Fatalf("DeclareQueue: %s", err)
+	}
+
+	first := log.at(0)
+	first.mu.Lock()
+	assert.Equal(t, []string{"account_creator"}, first.declaredQueues)
+	assert.Equal(t, []string{"account_creator.dlx"}, first.declaredExchanges)
+	first.mu.Unlock()
+
+	var closedCh chan *amqp.Error
+	assert.Eventually(t, func() bool {
+		first.mu.Lock()
+		defer first.mu.Unlock()
+		closedCh = first.closedCh
+		return closedCh != nil
+	}, time.Second, time.Millisecond, "watch goroutine never registered NotifyClose")
+	closedCh <- &amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restarted"}
+
+	assert.Eventually(t, func() bool {
+		return log.len() >= 2
+	}, time.Second, time.Millisecond, "client did not redial after NotifyClose")
+
+	second := log.at(1)
+	assert.Eventually(t, func() bool {
+		second.mu.Lock()
+		defer second.mu.Unlock()
+		return len(second.declaredQueues) == 1 && len(second.declaredExchanges) == 1
+	}, time.Second, time.Millisecond, "client did not replay its topology against the reconnected channel")
+}
+
+func TestClient_NewSurfacesADialFailure(t *testing.T) {
+	boom := errors.New("connection refused")
+	_, err := resilientamqp.New(func() (internal.AMQPConnection, error) {
+		return nil, boom
+	}, time.Millisecond, 10*time.Millisecond)
+
+	assert.ErrorIs(t, err, boom)
+}
@@ -0,0 +1,300 @@
+// Package webhook delivers signed HTTP callbacks to clients subscribed to
+// asynchronous events (currently account-creation completion/failure),
+// saving callers a long-poll against GET /account/status/:referenceId.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// SignatureHeader is the header a subscriber must verify before trusting a
+// delivered payload, Stripe-style: "t=<unix>,v1=<hex hmac>".
+const SignatureHeader = "X-Banking-Signature"
+
+// Sign computes the SignatureHeader value for body, signed with secret at
+// timestamp. Subscribers recompute the same HMAC over "<unix>.<body>" and
+// reject the delivery if it doesn't match, or if timestamp is too old, to
+// defeat replay.
+func Sign(secret string, body []byte, timestamp time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", timestamp.Unix(), body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp.Unix(), hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Subscription is a registered webhook callback, as persisted to the
+// webhook_subscriptions table.
+type Subscription struct {
+	ID string
+	// ReferenceID scopes the subscription to a single account-creation
+	// request, as registered inline via AccountRequest.Callback. Empty means
+	// the subscription applies to every reference ID (registered via
+	// POST /subscriptions).
+	ReferenceID string
+	URL         string
+	Secret      string
+	Events      []string
+	CreatedAt   time.Time
+}
+
+// matches reports whether sub should be notified of event on referenceID.
+func (sub Subscription) matches(event, referenceID string) bool {
+	if sub.ReferenceID != "" && sub.ReferenceID != referenceID {
+		return false
+	}
+	for _, e := range sub.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Store persists webhook subscriptions and a delivery audit trail to
+// Postgres.
+type Store struct {
+	db internal.PgDBConnection
+}
+
+// NewStore creates a Store backed by db.
+func NewStore(db internal.PgDBConnection) *Store {
+	return &Store{db: db}
+}
+
+const insertSubscriptionQuery = `
+	INSERT INTO webhook_subscriptions (id, reference_id, url, secret, events, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+// Create registers sub, assigning it an ID and CreatedAt timestamp.
+func (s *Store) Create(ctx context.Context, sub Subscription) (Subscription, error) {
+	sub.ID = uuid.New().String()
+	sub.CreatedAt = time.Now()
+	if _, err := s.db.Exec(ctx, insertSubscriptionQuery, sub.ID, sub.ReferenceID, sub.URL, sub.Secret, sub.Events, sub.CreatedAt); err != nil {
+		return Subscription{}, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+const listSubscriptionsForEventQuery = `
+	SELECT id, reference_id, url, secret, events, created_at
+	FROM webhook_subscriptions
+	WHERE (reference_id = '' OR reference_id = $1) AND $2 = ANY(events)
+`
+
+// ListForEvent returns every subscription that should be notified of event
+// on referenceID: subscriptions scoped to referenceID specifically, plus any
+// registered globally (ReferenceID == "").
+func (s *Store) ListForEvent(ctx context.Context, referenceID, event string) ([]Subscription, error) {
+	rows, err := s.db.Query(ctx, listSubscriptionsForEventQuery, referenceID, event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := make([]Subscription, 0)
+	for rows.Next() {
+		var sub Subscription
+		if err := rows.Scan(&sub.ID, &sub.ReferenceID, &sub.URL, &sub.Secret, &sub.Events, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read webhook subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+const insertDeliveryQuery = `
+	INSERT INTO webhook_deliveries (subscription_id, event, attempt, status_code, error, delivered_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+// recordAttempt audits a single delivery attempt, success or failure. A
+// failure to write the audit row is logged by the caller but never fails the
+// delivery itself - the webhook subscriber already either got the callback
+// or didn't.
+func (s *Store) recordAttempt(ctx context.Context, subscriptionID, event string, attempt, statusCode int, deliveryErr error) error {
+	var errText string
+	if deliveryErr != nil {
+		errText = deliveryErr.Error()
+	}
+	_, err := s.db.Exec(ctx, insertDeliveryQuery, subscriptionID, event, attempt, statusCode, errText, time.Now())
+	return err
+}
+
+// Default retry/circuit-breaker tuning for Dispatcher.
+const (
+	DefaultMaxAttempts = 5
+	DefaultBaseBackoff = 500 * time.Millisecond
+	// DefaultBreakerThreshold counts exhausted deliveries (every retry in the
+	// budget failed), not individual attempts: a subscriber that can't take
+	// one full delivery is unlikely to take the next, so the breaker trips
+	// immediately rather than burning another delivery's retry budget on it.
+	DefaultBreakerThreshold = 1
+	DefaultBreakerCooldown  = time.Minute
+)
+
+// breakerState is the in-memory circuit-breaker bookkeeping for a single
+// subscription. It is intentionally not persisted: a dispatcher restart
+// should give a struggling subscriber a fresh chance rather than staying
+// tripped forever.
+type breakerState struct {
+	consecutiveFailures int
+	openUntil           time.Time
+}
+
+// Dispatcher delivers webhook callbacks for subscriptions registered with
+// Store, retrying transient failures with exponential backoff and jitter and
+// circuit-breaking per subscription so one unreachable endpoint doesn't
+// burn the retry budget of every delivery that targets it.
+type Dispatcher struct {
+	store  *Store
+	client *http.Client
+
+	maxAttempts      int
+	baseBackoff      time.Duration
+	breakerThreshold int
+	breakerCooldown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*breakerState
+}
+
+// NewDispatcher creates a Dispatcher backed by store. A nil client defaults
+// to http.DefaultClient. maxAttempts bounds the retry budget per delivery;
+// baseBackoff is the jittered delay before the second attempt, doubling
+// every attempt after that. breakerThreshold consecutive failures against
+// the same subscription trips its circuit breaker for breakerCooldown.
+func NewDispatcher(store *Store, client *http.Client, maxAttempts int, baseBackoff time.Duration, breakerThreshold int, breakerCooldown time.Duration) *Dispatcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Dispatcher{
+		store:            store,
+		client:           client,
+		maxAttempts:      maxAttempts,
+		baseBackoff:      baseBackoff,
+		breakerThreshold: breakerThreshold,
+		breakerCooldown:  breakerCooldown,
+		breakers:         make(map[string]*breakerState),
+	}
+}
+
+// Dispatch notifies every subscription registered for event on referenceID,
+// delivering payload to each. Delivery failures (including a tripped
+// circuit breaker) are logged to the audit trail but never returned to the
+// caller - a webhook subscriber being unreachable must not fail the
+// transaction it's reporting on.
+func (d *Dispatcher) Dispatch(ctx context.Context, referenceID, event string, payload []byte) error {
+	subs, err := d.store.ListForEvent(ctx, referenceID, event)
+	if err != nil {
+		return err
+	}
+	for _, sub := range subs {
+		if !sub.matches(event, referenceID) {
+			continue
+		}
+		d.deliver(ctx, sub, event, payload)
+	}
+	return nil
+}
+
+// deliver attempts delivery to sub up to d.maxAttempts times, backing off
+// exponentially with jitter between attempts, and records every attempt to
+// the audit trail.
+func (d *Dispatcher) deliver(ctx context.Context, sub Subscription, event string, payload []byte) {
+	if d.breakerOpen(sub.ID) {
+		_ = d.store.recordAttempt(ctx, sub.ID, event, 0, 0, fmt.Errorf("circuit open: too many recent failures"))
+		return
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		statusCode, err := d.send(ctx, sub, payload)
+		_ = d.store.recordAttempt(ctx, sub.ID, event, attempt, statusCode, err)
+		if err == nil {
+			d.recordSuccess(sub.ID)
+			return
+		}
+		lastErr = err
+
+		if attempt < d.maxAttempts {
+			time.Sleep(d.backoff(attempt))
+		}
+	}
+
+	d.recordFailure(sub.ID, lastErr)
+}
+
+// send performs a single delivery attempt and returns the response status
+// code (0 if the request never got a response) and an error if the request
+// failed or the subscriber returned a non-2xx status.
+func (d *Dispatcher) send(ctx context.Context, sub Subscription, payload []byte) (int, error) {
+	now := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, payload, now))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("webhook subscriber returned %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// backoff computes the exponential delay before attempt+1, with full jitter
+// to avoid every retry landing on the same schedule.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	ceiling := d.baseBackoff * time.Duration(1<<uint(attempt-1))
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+func (d *Dispatcher) breakerOpen(subscriptionID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	b, ok := d.breakers[subscriptionID]
+	if !ok {
+		return false
+	}
+	return time.Now().Before(b.openUntil)
+}
+
+func (d *Dispatcher) recordSuccess(subscriptionID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.breakers, subscriptionID)
+}
+
+func (d *Dispatcher) recordFailure(subscriptionID string, _ error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	b, ok := d.breakers[subscriptionID]
+	if !ok {
+		b = &breakerState{}
+		d.breakers[subscriptionID] = b
+	}
+	b.consecutiveFailures++
+	if b.consecutiveFailures >= d.breakerThreshold {
+		b.openUntil = time.Now().Add(d.breakerCooldown)
+	}
+}
@@ -0,0 +1,150 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/webhook"
+)
+
+// dbWithSubscriptions stubs out ListForEvent with subs and ignores (accepts)
+// every Exec, matching what recordAttempt and Create issue.
+func dbWithSubscriptions(subs []webhook.Subscription) *internal.MockPgDBConnection {
+	return &internal.MockPgDBConnection{
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			row := -1
+			return &internal.MockPgxRows{
+				NextFunc: func() bool {
+					row++
+					return row < len(subs)
+				},
+				ScanFunc: func(dest ...interface{}) error {
+					sub := subs[row]
+					*dest[0].(*string) = sub.ID
+					*dest[1].(*string) = sub.ReferenceID
+					*dest[2].(*string) = sub.URL
+					*dest[3].(*string) = sub.Secret
+					*dest[4].(*[]string) = sub.Events
+					*dest[5].(*time.Time) = sub.CreatedAt
+					return nil
+				},
+			}, nil
+		},
+	}
+}
+
+func TestSign_IsVerifiableWithTheSameSecretAndBody(t *testing.T) {
+	body := []byte(`{"referenceID":"ref-1"}`)
+	now := time.Now()
+
+	header := webhook.Sign("shh", body, now)
+
+	assert.Equal(t, header, webhook.Sign("shh", body, now), "signing the same body/secret/timestamp must be deterministic")
+	assert.NotEqual(t, header, webhook.Sign("different-secret", body, now))
+}
+
+func TestDispatcher_DeliversASignedPayloadAndStopsOnSuccess(t *testing.T) {
+	var receivedSignature string
+	var receivedBody []byte
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		receivedSignature = r.Header.Get(webhook.SignatureHeader)
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sub := webhook.Subscription{ID: "sub-1", ReferenceID: "ref-1", URL: server.URL, Secret: "shh", Events: []string{"account.completed"}, CreatedAt: time.Now()}
+	db := dbWithSubscriptions([]webhook.Subscription{sub})
+	store := webhook.NewStore(db)
+	dispatcher := webhook.NewDispatcher(store, server.Client(), webhook.DefaultMaxAttempts, time.Millisecond, webhook.DefaultBreakerThreshold, time.Minute)
+
+	payload := []byte(`{"referenceID":"ref-1","status":"COMPLETED"}`)
+	err := dispatcher.Dispatch(context.Background(), "ref-1", "account.completed", payload)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "a successful delivery must not be retried")
+	assert.NotEmpty(t, receivedSignature)
+	assert.Equal(t, payload, receivedBody)
+}
+
+func TestDispatcher_IgnoresSubscriptionsForOtherEventsOrReferenceIDs(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	subs := []webhook.Subscription{
+		{ID: "sub-1", ReferenceID: "ref-1", URL: server.URL, Secret: "shh", Events: []string{"account.failed"}},
+		{ID: "sub-2", ReferenceID: "ref-2", URL: server.URL, Secret: "shh", Events: []string{"account.completed"}},
+	}
+	db := dbWithSubscriptions(subs)
+	store := webhook.NewStore(db)
+	dispatcher := webhook.NewDispatcher(store, server.Client(), webhook.DefaultMaxAttempts, time.Millisecond, webhook.DefaultBreakerThreshold, time.Minute)
+
+	err := dispatcher.Dispatch(context.Background(), "ref-1", "account.completed", []byte(`{}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls), "neither subscription matches both the event and the reference ID")
+}
+
+func TestDispatcher_RetriesOn5xxThenGivesUp(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sub := webhook.Subscription{ID: "sub-1", ReferenceID: "ref-1", URL: server.URL, Secret: "shh", Events: []string{"account.completed"}}
+	db := dbWithSubscriptions([]webhook.Subscription{sub})
+	store := webhook.NewStore(db)
+	dispatcher := webhook.NewDispatcher(store, server.Client(), webhook.DefaultMaxAttempts, time.Millisecond, webhook.DefaultBreakerThreshold, time.Minute)
+
+	err := dispatcher.Dispatch(context.Background(), "ref-1", "account.completed", []byte(`{}`))
+
+	assert.NoError(t, err, "a subscriber being unreachable must not fail the caller")
+	assert.Equal(t, int32(webhook.DefaultMaxAttempts), atomic.LoadInt32(&calls), "every attempt up to the retry budget should have been tried")
+}
+
+func TestDispatcher_TripsCircuitBreakerAfterRepeatedFailures(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sub := webhook.Subscription{ID: "sub-1", ReferenceID: "ref-1", URL: server.URL, Secret: "shh", Events: []string{"account.completed"}}
+	db := dbWithSubscriptions([]webhook.Subscription{sub})
+	store := webhook.NewStore(db)
+	dispatcher := webhook.NewDispatcher(store, server.Client(), webhook.DefaultMaxAttempts, time.Millisecond, webhook.DefaultBreakerThreshold, time.Minute)
+
+	// First dispatch exhausts the retry budget, which alone trips the
+	// breaker (DefaultBreakerThreshold counts exhausted deliveries, not
+	// individual attempts).
+	assert.NoError(t, dispatcher.Dispatch(context.Background(), "ref-1", "account.completed", []byte(`{}`)))
+	callsAfterFirstDispatch := atomic.LoadInt32(&calls)
+
+	// A second dispatch should short-circuit on the open breaker without
+	// hitting the server again.
+	assert.NoError(t, dispatcher.Dispatch(context.Background(), "ref-1", "account.completed", []byte(`{}`)))
+
+	assert.Equal(t, callsAfterFirstDispatch, atomic.LoadInt32(&calls), "an open circuit breaker must skip delivery entirely")
+}
@@ -4,61 +4,123 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/idempotency"
+	"github.com/siddarth99/banking-ledger/pkg/money"
 )
 
 type CreateAccountProcessor struct {
 	ProcessWorker
 	Data AccountData
+	// StatusDLQChannel publishes a reference-status stage document to
+	// ReferenceStatusDeadLetterQueue when indexing it into Elasticsearch
+	// fails. A nil StatusDLQChannel means a failed stage write is only
+	// logged.
+	StatusDLQChannel internal.AMQPQueuePublisher
 }
 
 // AccountData represents the data needed to create a new account
 type AccountData struct {
-	AccountNumber     string  `json:"accountNumber"`
-	AccountHolderName string  `json:"accountHolderName"`
-	InitialDeposit    float64 `json:"initialDeposit"`
-	BranchCode        string  `json:"branchCode"`
-	ReferenceID       string  `json:"referenceID"`
+	AccountNumber     string       `json:"accountNumber"`
+	AccountHolderName string       `json:"accountHolderName"`
+	InitialDeposit    money.Amount `json:"initialDeposit"`
+	BranchCode        string       `json:"branchCode"`
+	ReferenceID       string       `json:"referenceID"`
 }
 
 // CreateAccount creates a new account in the database
 func (p *CreateAccountProcessor) CreateAccount(ctx context.Context) error {
 
 	// Validate available balance is not negative
-	if p.Data.InitialDeposit < 0 {
-		return fmt.Errorf("initial Deposit cannot be negative")
+	if p.Data.InitialDeposit.Cmp(money.Zero(p.Data.InitialDeposit.Currency())) < 0 {
+		err := fmt.Errorf("initial Deposit cannot be negative")
+		p.recordStage(ctx, StageValidated, err)
+		return err
 	}
+	p.recordStage(ctx, StageValidated, nil)
+
+	tx, err := p.PgxConn.Begin(ctx)
+	if err != nil {
+		p.heartbeat(ctx, "", 0, 1)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // Will be ignored if transaction is committed
+
+	// WithIdempotency guards against RabbitMQ redelivering this message: a
+	// reference ID that already created its account and wrote its outbox
+	// entry in a prior, committed attempt returns that outcome here instead
+	// of minting a second account.
+	resultJSON, err := idempotency.WithIdempotency(ctx, tx, p.Data.ReferenceID, func() (json.RawMessage, error) {
+		return p.insertAccount(ctx, tx)
+	})
+	if err != nil {
+		p.heartbeat(ctx, "", 0, 1)
+		p.recordStage(ctx, StagePersisted, err)
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		p.heartbeat(ctx, "", 0, 1)
+		p.recordStage(ctx, StagePersisted, err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	p.recordStage(ctx, StagePersisted, nil)
+
+	var transactionDoc TransactionDocument
+	if err := json.Unmarshal(resultJSON, &transactionDoc); err != nil {
+		return fmt.Errorf("failed to decode processed account-creation result: %w", err)
+	}
+	p.Data.AccountNumber = transactionDoc.AccountNumber
+
+	// The outbox entry written inside the just-committed tx is durably
+	// queued for delivery to Elasticsearch; OutboxRelay owns actually
+	// getting it there (see writeOutboxEntry).
+	p.recordStage(ctx, StageIndexed, nil)
 
+	p.heartbeat(ctx, "", 1, 0)
+	p.notify(ctx, p.Data.ReferenceID, "account.completed", resultJSON)
+
+	return nil
+}
+
+// insertAccount generates an account number, inserts the new account row and
+// writes its outbox entry inside tx, returning the resulting
+// TransactionDocument marshaled as JSON - the result CreateAccount's
+// WithIdempotency call stores against p.Data.ReferenceID so a redelivery of
+// the same message replays this outcome instead of running it again.
+func (p *CreateAccountProcessor) insertAccount(ctx context.Context, tx pgx.Tx) (json.RawMessage, error) {
 	randomNumber := 1000000 + time.Now().UnixNano()%9000000
 	p.Data.AccountNumber = fmt.Sprintf("%s%07d", p.Data.BranchCode, randomNumber%10000000)
 
 	query := `
 		INSERT INTO accounts (
-			account_number, 
-			account_holder_name, 
-			available_balance, 
-			branch_code, 
+			account_number,
+			account_holder_name,
+			available_balance,
+			branch_code,
+			currency,
 			status,
 			created_at
-		) VALUES ($1, $2, $3, $4, $5, $6)
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
 
 	now := time.Now()
-	_, err := p.PgxConn.Exec(
+	if _, err := tx.Exec(
 		ctx,
 		query,
 		p.Data.AccountNumber,
 		p.Data.AccountHolderName,
 		p.Data.InitialDeposit,
 		p.Data.BranchCode,
+		p.Data.InitialDeposit.Currency(),
 		"ACTIVE",
 		now,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to create account: %w", err)
+	); err != nil {
+		return nil, fmt.Errorf("failed to create account: %w", err)
 	}
 
 	transactionDoc := TransactionDocument{
@@ -72,23 +134,19 @@ func (p *CreateAccountProcessor) CreateAccount(ctx context.Context) error {
 		BalanceAfterTransaction: p.Data.InitialDeposit,
 	}
 
-	// Create index name with date format for better data management
-	indexName := fmt.Sprintf("bank-transactions-%s", now.Format("2006-01-02"))
-
-	// Index the transaction document
 	transactionDocJSON, err := json.Marshal(transactionDoc)
 	if err != nil {
-		panic(fmt.Sprintf("failed to marshal JSON: %v", err))
-	}
-	req := strings.NewReader(string(transactionDocJSON))
-	res, err := p.EsConn.Index(indexName, req)
-	if err != nil {
-		// Log the error but don't fail the account creation
-		log.Printf("Failed to index transaction in Elasticsearch: %v", err)
+		return nil, fmt.Errorf("failed to marshal transaction document: %w", err)
 	}
-	if res != nil {
-		defer res.Body.Close()
+
+	// Write the outbox row in the same tx as the account insert, instead of
+	// indexing into Elasticsearch directly, so a committed account can
+	// never diverge from the search index: OutboxRelay delivers this row
+	// (with retries) after the fact.
+	indexName := fmt.Sprintf("bank-transactions-%s", now.Format("2006-01-02"))
+	if err := writeOutboxEntry(ctx, tx, indexName, transactionDocJSON); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return transactionDocJSON, nil
 }
@@ -1,78 +1,84 @@
 package processor_test
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
-	"io"
-	"log"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 
-	"github.com/siddarth99/banking-ledger/workers/processor"
 	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+	"github.com/siddarth99/banking-ledger/workers/processor"
 )
 
 // TransactionDocument represents a transaction document for testing
 type TransactionDocument struct {
-	TransactionID           string    `json:"transaction_id"`
-	AccountNumber           string    `json:"account_number"`
-	Amount                  float64   `json:"amount"`
-	Type                    string    `json:"type"`
-	Status                  string    `json:"status"`
-	Timestamp               time.Time `json:"timestamp"`
-	BranchCode              string    `json:"branch_code"`
-	BalanceAfterTransaction float64   `json:"balance_after_transaction"`
+	TransactionID           string       `json:"transaction_id"`
+	AccountNumber           string       `json:"account_number"`
+	Amount                  money.Amount `json:"amount"`
+	Type                    string       `json:"type"`
+	Status                  string       `json:"status"`
+	Timestamp               time.Time    `json:"timestamp"`
+	BranchCode              string       `json:"branch_code"`
+	BalanceAfterTransaction money.Amount `json:"balance_after_transaction"`
 }
 
 func TestCreateAccount_Success(t *testing.T) {
 	// Arrange
 	capturedSQL := ""
 	capturedArgs := []interface{}{}
-	capturedIndex := ""
-	capturedBody := ""
+	capturedOutboxIndex := ""
+	capturedOutboxPayload := ""
 
-	// Mock PgxConn
-	mockConn := &internal.MockPgDBConnection{
+	// Mock tx
+	mockTx := &internal.MockPgDBConnection{
 		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
-			capturedSQL = sql
-			capturedArgs = arguments
+			if strings.Contains(sql, "INSERT INTO processed_messages") {
+				return pgconn.NewCommandTag("INSERT 0 1"), nil
+			}
+			switch {
+			case strings.Contains(sql, "INSERT INTO accounts"):
+				capturedSQL = sql
+				capturedArgs = arguments
+			case strings.Contains(sql, "INSERT INTO transaction_outbox"):
+				capturedOutboxIndex = arguments[0].(string)
+				capturedOutboxPayload = string(arguments[1].([]byte))
+			}
 			return pgconn.CommandTag{}, nil
 		},
 	}
 
-	// Mock Elasticsearch client
-	mockEsClient := &internal.MockElasticsearchClient{
-		IndexFunc: func(index string, body io.Reader) (*esapi.Response, error) {
-			capturedIndex = index
-			bodyBytes, _ := io.ReadAll(body)
-			capturedBody = string(bodyBytes)
-			return internal.MockResponse(201, `{"result":"created"}`), nil
+	// Mock PgxConn
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
 		},
 	}
 
 	// Create processor with mocks
+	initialDeposit, err := money.FromString("1000.00", "USD")
+	assert.NoError(t, err)
+
 	proc := processor.CreateAccountProcessor{
 		ProcessWorker: processor.ProcessWorker{
 			PgxConn: mockConn,
-			EsConn:  mockEsClient,
 		},
 		Data: processor.AccountData{
 			AccountHolderName: "Test User",
-			InitialDeposit:    1000.00,
+			InitialDeposit:    initialDeposit,
 			BranchCode:        "BR1",
 			ReferenceID:       "REF123456",
 		},
 	}
 
 	// Act
-	err := proc.CreateAccount(context.Background())
+	err = proc.CreateAccount(context.Background())
 
 	// Assert
 	assert.NoError(t, err)
@@ -85,39 +91,42 @@ func TestCreateAccount_Success(t *testing.T) {
 	assert.Contains(t, capturedSQL, "INSERT INTO accounts")
 	assert.Equal(t, proc.Data.AccountNumber, capturedArgs[0])
 	assert.Equal(t, "Test User", capturedArgs[1])
-	assert.Equal(t, 1000.00, capturedArgs[2])
+	assert.Equal(t, initialDeposit, capturedArgs[2])
 	assert.Equal(t, "BR1", capturedArgs[3])
-	assert.Equal(t, "ACTIVE", capturedArgs[4])
+	assert.Equal(t, "USD", capturedArgs[4])
+	assert.Equal(t, "ACTIVE", capturedArgs[5])
 
-	// Verify Elasticsearch indexing
-	assert.Contains(t, capturedIndex, "bank-transactions-")
+	// Verify the outbox row, rather than Elasticsearch, was written
+	assert.Contains(t, capturedOutboxIndex, "bank-transactions-")
 
-	// Verify document content
 	var doc TransactionDocument
-	err = json.Unmarshal([]byte(capturedBody), &doc)
+	err = json.Unmarshal([]byte(capturedOutboxPayload), &doc)
 	assert.NoError(t, err)
 	assert.Equal(t, "REF123456", doc.TransactionID)
 	assert.Equal(t, proc.Data.AccountNumber, doc.AccountNumber)
-	assert.Equal(t, 1000.00, doc.Amount)
+	assert.Equal(t, initialDeposit, doc.Amount)
 	assert.Equal(t, "DEPOSIT", doc.Type)
 	assert.Equal(t, "COMPLETED", doc.Status)
 	assert.Equal(t, "BR1", doc.BranchCode)
-	assert.Equal(t, 1000.00, doc.BalanceAfterTransaction)
+	assert.Equal(t, initialDeposit, doc.BalanceAfterTransaction)
 }
 
 func TestCreateAccount_NegativeDeposit(t *testing.T) {
 	// Arrange
+	negativeDeposit, err := money.FromString("-100.00", "USD")
+	assert.NoError(t, err)
+
 	proc := processor.CreateAccountProcessor{
 		Data: processor.AccountData{
 			AccountHolderName: "Test User",
-			InitialDeposit:    -100.00, // Negative deposit
+			InitialDeposit:    negativeDeposit,
 			BranchCode:        "BR1",
 			ReferenceID:       "REF123456",
 		},
 	}
 
 	// Act
-	err := proc.CreateAccount(context.Background())
+	err = proc.CreateAccount(context.Background())
 
 	// Assert
 	assert.Error(t, err)
@@ -128,75 +137,88 @@ func TestCreateAccount_DatabaseError(t *testing.T) {
 	// Arrange
 	expectedError := errors.New("database connection error")
 
-	// Mock PgxConn
-	mockConn := &internal.MockPgDBConnection{
+	mockTx := &internal.MockPgDBConnection{
 		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			if strings.Contains(sql, "INSERT INTO processed_messages") {
+				return pgconn.NewCommandTag("INSERT 0 1"), nil
+			}
 			return pgconn.CommandTag{}, expectedError
 		},
 	}
 
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+
+	initialDeposit, err := money.FromString("1000.00", "USD")
+	assert.NoError(t, err)
+
 	proc := processor.CreateAccountProcessor{
 		ProcessWorker: processor.ProcessWorker{
 			PgxConn: mockConn,
 		},
 		Data: processor.AccountData{
 			AccountHolderName: "Test User",
-			InitialDeposit:    1000.00,
+			InitialDeposit:    initialDeposit,
 			BranchCode:        "BR1",
 			ReferenceID:       "REF123456",
 		},
 	}
 
 	// Act
-	err := proc.CreateAccount(context.Background())
+	err = proc.CreateAccount(context.Background())
 
 	// Assert
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to create account")
 }
 
-func TestCreateAccount_ElasticsearchError(t *testing.T) {
-	// Arrange
-	capturedLogs := &bytes.Buffer{}
-	log.SetOutput(capturedLogs)
-	defer func() {
-		log.SetOutput(nil) // Reset logger output
-	}()
-
-	// Mock PgxConn
-	mockConn := &internal.MockPgDBConnection{
+func TestCreateAccount_OutboxWriteFailure(t *testing.T) {
+	// Arrange: the account insert succeeds, but the outbox insert in the
+	// same tx fails - the whole account creation must fail along with it,
+	// since the outbox row and the account insert either both land or
+	// neither does.
+	mockTx := &internal.MockPgDBConnection{
 		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			if strings.Contains(sql, "INSERT INTO processed_messages") {
+				return pgconn.NewCommandTag("INSERT 0 1"), nil
+			}
+			if strings.Contains(sql, "INSERT INTO transaction_outbox") {
+				return pgconn.CommandTag{}, errors.New("outbox insert failed")
+			}
 			return pgconn.CommandTag{}, nil
 		},
 	}
 
-	// Mock Elasticsearch client with error
-	mockEsClient := &internal.MockElasticsearchClient{
-		IndexFunc: func(index string, body io.Reader) (*esapi.Response, error) {
-			return nil, errors.New("elasticsearch connection error")
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
 		},
 	}
 
+	initialDeposit, err := money.FromString("1000.00", "USD")
+	assert.NoError(t, err)
+
 	proc := processor.CreateAccountProcessor{
 		ProcessWorker: processor.ProcessWorker{
 			PgxConn: mockConn,
-			EsConn:  mockEsClient,
 		},
 		Data: processor.AccountData{
 			AccountHolderName: "Test User",
-			InitialDeposit:    1000.00,
+			InitialDeposit:    initialDeposit,
 			BranchCode:        "BR1",
 			ReferenceID:       "REF123456",
 		},
 	}
 
 	// Act
-	err := proc.CreateAccount(context.Background())
+	err = proc.CreateAccount(context.Background())
 
 	// Assert
-	assert.NoError(t, err) // Should not fail the overall function
-	assert.Contains(t, capturedLogs.String(), "Failed to index transaction in Elasticsearch")
-	assert.Contains(t, capturedLogs.String(), "elasticsearch connection error")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to write outbox entry")
 }
 
 func TestCreateAccount_AccountNumberGeneration(t *testing.T) {
@@ -204,30 +226,34 @@ func TestCreateAccount_AccountNumberGeneration(t *testing.T) {
 	accounts := make(map[string]bool)
 	numAccounts := 100
 
-	// Mock PgxConn that always succeeds
-	mockConn := &internal.MockPgDBConnection{
+	mockTx := &internal.MockPgDBConnection{
 		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			if strings.Contains(sql, "INSERT INTO processed_messages") {
+				return pgconn.NewCommandTag("INSERT 0 1"), nil
+			}
 			return pgconn.CommandTag{}, nil
 		},
 	}
 
-	// Mock Elasticsearch client that always succeeds
-	mockEsClient := &internal.MockElasticsearchClient{
-		IndexFunc: func(index string, body io.Reader) (*esapi.Response, error) {
-			return internal.MockResponse(201, `{"result":"created"}`), nil
+	// Mock PgxConn that always succeeds
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
 		},
 	}
 
+	initialDeposit, err := money.FromString("1000.00", "USD")
+	assert.NoError(t, err)
+
 	// Act - Create multiple accounts and check for duplicate account numbers
 	for i := 0; i < numAccounts; i++ {
 		proc := processor.CreateAccountProcessor{
 			ProcessWorker: processor.ProcessWorker{
 				PgxConn: mockConn,
-				EsConn:  mockEsClient,
 			},
 			Data: processor.AccountData{
 				AccountHolderName: "Test User",
-				InitialDeposit:    1000.00,
+				InitialDeposit:    initialDeposit,
 				BranchCode:        "BR001",
 				ReferenceID:       "REF123456",
 			},
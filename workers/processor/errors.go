@@ -0,0 +1,99 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ErrorCode classifies why a transaction failed so clients can react
+// programmatically instead of pattern-matching on log strings.
+type ErrorCode string
+
+const (
+	ErrCodeInsufficientFunds ErrorCode = "INSUFFICIENT_FUNDS"
+	ErrCodeInvalidType       ErrorCode = "INVALID_TYPE"
+	ErrCodeNegativeAmount    ErrorCode = "NEGATIVE_AMOUNT"
+	ErrCodeCurrencyMismatch  ErrorCode = "CURRENCY_MISMATCH"
+	ErrCodeAccountNotFound   ErrorCode = "ACCOUNT_NOT_FOUND"
+	ErrCodeDBTxBeginFailed   ErrorCode = "DB_TX_BEGIN_FAILED"
+	ErrCodeDBQueryFailed     ErrorCode = "DB_QUERY_FAILED"
+	ErrCodeDBUpdateFailed    ErrorCode = "DB_UPDATE_FAILED"
+	ErrCodeESIndexFailed     ErrorCode = "ES_INDEX_FAILED"
+	ErrCodeUnknown           ErrorCode = "UNKNOWN"
+)
+
+// ErrorDocument is indexed to bank-transaction-errors-* whenever a
+// transaction fails, so the failure reason is queryable after the fact
+// instead of living only in worker logs.
+type ErrorDocument struct {
+	TransactionID string    `json:"transaction_id"`
+	AccountNumber string    `json:"account_number"`
+	ErrorCode     ErrorCode `json:"error_code"`
+	ErrorMessage  string    `json:"error_message"`
+	Stage         string    `json:"stage"`
+	OccurredAt    time.Time `json:"occurred_at"`
+	Retryable     bool      `json:"retryable"`
+}
+
+// classifyError maps the loosely-typed errors produced by transact into a
+// stable ErrorCode. It's a pragmatic stopgap until the processor's errors
+// are typed throughout.
+func classifyError(err error) (code ErrorCode, stage string, retryable bool) {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "insufficient funds"):
+		return ErrCodeInsufficientFunds, "validate", false
+	case strings.Contains(msg, "invalid transaction type"):
+		return ErrCodeInvalidType, "validate", false
+	case strings.Contains(msg, "must be positive"):
+		return ErrCodeNegativeAmount, "validate", false
+	case strings.Contains(msg, "does not match account currency"):
+		return ErrCodeCurrencyMismatch, "validate", false
+	case strings.Contains(msg, "failed to get account balance"):
+		return ErrCodeAccountNotFound, "lookup_account", false
+	case strings.Contains(msg, "failed to begin transaction"):
+		return ErrCodeDBTxBeginFailed, "db_begin", true
+	case strings.Contains(msg, "failed to update account balance"):
+		return ErrCodeDBUpdateFailed, "db_update", true
+	case strings.Contains(msg, "failed to commit transaction"):
+		return ErrCodeDBUpdateFailed, "db_commit", true
+	default:
+		return ErrCodeUnknown, "unknown", true
+	}
+}
+
+// indexTransactionError records a structured failure document for a
+// transaction so GetTransactionErrorHandler can surface it later.
+func (p *TransactionProcessor) indexTransactionError(ctx context.Context, err error) {
+	code, stage, retryable := classifyError(err)
+
+	errorDoc := ErrorDocument{
+		TransactionID: p.Data.TransactionID,
+		AccountNumber: p.Data.AccountNumber,
+		ErrorCode:     code,
+		ErrorMessage:  err.Error(),
+		Stage:         stage,
+		OccurredAt:    time.Now(),
+		Retryable:     retryable,
+	}
+
+	indexName := fmt.Sprintf("bank-transaction-errors-%s", time.Now().Format("2006-01-02"))
+	errorDocJSON, marshalErr := json.Marshal(errorDoc)
+	if marshalErr != nil {
+		log.Printf("Failed to marshal transaction error document: %v", marshalErr)
+		return
+	}
+
+	res, indexErr := p.EsConn.Index(indexName, strings.NewReader(string(errorDocJSON)))
+	if indexErr != nil {
+		log.Printf("Failed to index transaction error in Elasticsearch: %v", indexErr)
+		return
+	}
+	if res != nil {
+		defer res.Body.Close()
+	}
+}
@@ -0,0 +1,313 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+const insertOutboxEntryQuery = `
+	INSERT INTO transaction_outbox (index_name, payload, created_at, attempts)
+	VALUES ($1, $2, $3, 0)
+`
+
+// writeOutboxEntry records payload for later delivery to Elasticsearch's
+// indexName index, inside the same tx as the balance update it describes.
+// OutboxRelay is solely responsible for ever reading this row back out.
+func writeOutboxEntry(ctx context.Context, tx pgx.Tx, indexName string, payload []byte) error {
+	if _, err := tx.Exec(ctx, insertOutboxEntryQuery, indexName, payload, time.Now()); err != nil {
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+	return nil
+}
+
+// DefaultOutboxBatchSize, DefaultOutboxMaxAttempts and
+// DefaultOutboxPollInterval are sane defaults for OutboxRelay.
+const (
+	DefaultOutboxBatchSize    = 100
+	DefaultOutboxMaxAttempts  = 8
+	DefaultOutboxPollInterval = 5 * time.Second
+)
+
+// outboxBaseBackoff is the delay applied after the first failed delivery
+// attempt; it doubles with every attempt thereafter, up to
+// DefaultOutboxMaxAttempts.
+const outboxBaseBackoff = 10 * time.Second
+
+// outboxEntry is a single undelivered transaction_outbox row.
+type outboxEntry struct {
+	id        int64
+	indexName string
+	payload   []byte
+	attempts  int
+}
+
+// OutboxRelay polls transaction_outbox for rows TransactionProcessor and
+// CreateAccountProcessor wrote inside their own Postgres transactions, and
+// delivers them to Elasticsearch via the bulk API. This decouples balance
+// updates (which must commit immediately) from search indexing (which only
+// needs to happen eventually), so a slow or unavailable Elasticsearch
+// cluster never blocks or risks a transaction.
+//
+// This is the bulk-indexing pipeline for transaction/account documents:
+// batched delivery via _bulk, size-bounded batches, retry with backoff on
+// rejected rows, and the flush/queue-depth/rejection Metrics below. An
+// in-memory buffering indexer would lose its queue on a crash; writing the
+// payload to transaction_outbox inside the same tx as the balance update it
+// describes gets the same batching benefit without that risk. This
+// supersedes a batching Elasticsearch client built as its own standalone
+// component rather than on top of the outbox.
+type OutboxRelay struct {
+	PgxConn internal.PgDBConnection
+	EsConn  internal.ElasticsearchClient
+	// BatchSize caps how many undelivered rows RelayOnce claims per call. A
+	// zero value is treated as DefaultOutboxBatchSize.
+	BatchSize int
+	// MaxAttempts is how many delivery attempts a row gets before it's left
+	// undelivered for good (and must be triaged by hand). A zero value is
+	// treated as DefaultOutboxMaxAttempts.
+	MaxAttempts int
+	// Metrics, if set, is updated at the end of every RelayOnce call. A nil
+	// Metrics disables instrumentation entirely.
+	Metrics *OutboxRelayMetrics
+}
+
+// OutboxRelayMetrics holds counters an operator can poll to see how the
+// relay is keeping up - e.g. from an admin endpoint or a periodic log line.
+// Every field is an atomic so concurrent RelayOnce calls (multiple replicas,
+// or a manual trigger racing the poll loop) can update it without a lock.
+type OutboxRelayMetrics struct {
+	// FlushCount is how many times RelayOnce has run to completion.
+	FlushCount atomic.Int64
+	// LastFlushDuration is how long the most recent RelayOnce call took to
+	// claim, bulk-index and mark its batch of rows, in nanoseconds.
+	LastFlushDuration atomic.Int64
+	// DeliveredCount and RejectedCount tally every row RelayOnce has ever
+	// marked delivered, or left undelivered after a rejected or failed bulk
+	// attempt, respectively.
+	DeliveredCount atomic.Int64
+	RejectedCount  atomic.Int64
+	// QueueDepth is the number of rows still awaiting delivery as of the end
+	// of the most recent RelayOnce call.
+	QueueDepth atomic.Int64
+}
+
+const selectUndeliveredOutboxQuery = `
+	SELECT id, index_name, payload, attempts
+	FROM transaction_outbox
+	WHERE delivered_at IS NULL
+		AND attempts < $1
+		AND created_at <= now() - ($2 * power(2, LEAST(attempts, 10)) * interval '1 second')
+	ORDER BY created_at
+	LIMIT $3
+	FOR UPDATE SKIP LOCKED
+`
+
+// Run polls for undelivered outbox rows every pollInterval until ctx is
+// done, logging (rather than failing) a relay error so one bad tick doesn't
+// stop the relay from trying again on the next one.
+func (r *OutboxRelay) Run(ctx context.Context, pollInterval time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.RelayOnce(ctx); err != nil {
+				log.Printf("Outbox relay failed: %s", err)
+			}
+		}
+	}
+}
+
+// RelayOnce claims up to BatchSize undelivered rows, bulk-indexes them into
+// Elasticsearch, and marks each one delivered or bumps its attempts count
+// depending on whether its own item in the bulk response succeeded.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) error {
+	batchSize := r.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultOutboxBatchSize
+	}
+	maxAttempts := r.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultOutboxMaxAttempts
+	}
+
+	start := time.Now()
+	defer func() {
+		if r.Metrics != nil {
+			r.Metrics.FlushCount.Add(1)
+			r.Metrics.LastFlushDuration.Store(int64(time.Since(start)))
+		}
+	}()
+
+	tx, err := r.PgxConn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin outbox relay transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, selectUndeliveredOutboxQuery, maxAttempts, outboxBaseBackoff.Seconds(), batchSize)
+	if err != nil {
+		return fmt.Errorf("failed to select undelivered outbox entries: %w", err)
+	}
+	entries := make([]outboxEntry, 0, batchSize)
+	for rows.Next() {
+		var e outboxEntry
+		if err := rows.Scan(&e.id, &e.indexName, &e.payload, &e.attempts); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read outbox entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read outbox entries: %w", err)
+	}
+	if len(entries) == 0 {
+		r.recordQueueDepth(ctx, tx, maxAttempts)
+		return nil
+	}
+
+	results, err := bulkIndex(r.EsConn, entries)
+	if err != nil {
+		// The whole batch failed to even reach Elasticsearch (connection
+		// refused, malformed request, ...); bump every row's attempts so
+		// the backoff still grows instead of retrying at full speed.
+		for _, e := range entries {
+			if markErr := bumpOutboxAttempts(ctx, tx, e.id); markErr != nil {
+				return markErr
+			}
+		}
+		if r.Metrics != nil {
+			r.Metrics.RejectedCount.Add(int64(len(entries)))
+		}
+		r.recordQueueDepth(ctx, tx, maxAttempts)
+		if commitErr := tx.Commit(ctx); commitErr != nil {
+			return fmt.Errorf("failed to commit outbox relay transaction: %w", commitErr)
+		}
+		return fmt.Errorf("failed to bulk-index outbox entries: %w", err)
+	}
+
+	var delivered, rejected int64
+	for i, e := range entries {
+		if results[i] {
+			if err := markOutboxDelivered(ctx, tx, e.id); err != nil {
+				return err
+			}
+			delivered++
+		} else {
+			if err := bumpOutboxAttempts(ctx, tx, e.id); err != nil {
+				return err
+			}
+			rejected++
+		}
+	}
+	if r.Metrics != nil {
+		r.Metrics.DeliveredCount.Add(delivered)
+		r.Metrics.RejectedCount.Add(rejected)
+	}
+	r.recordQueueDepth(ctx, tx, maxAttempts)
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit outbox relay transaction: %w", err)
+	}
+	return nil
+}
+
+const countUndeliveredOutboxQuery = `SELECT count(*) FROM transaction_outbox WHERE delivered_at IS NULL AND attempts < $1`
+
+// recordQueueDepth updates Metrics.QueueDepth with the current undelivered
+// backlog, within the same tx RelayOnce is about to commit. It is
+// best-effort: a failure to measure the backlog is logged but never fails
+// the relay tick that called it.
+func (r *OutboxRelay) recordQueueDepth(ctx context.Context, tx pgx.Tx, maxAttempts int) {
+	if r.Metrics == nil {
+		return
+	}
+	var depth int64
+	if err := tx.QueryRow(ctx, countUndeliveredOutboxQuery, maxAttempts).Scan(&depth); err != nil {
+		log.Printf("Failed to measure outbox queue depth: %s", err)
+		return
+	}
+	r.Metrics.QueueDepth.Store(depth)
+}
+
+const markOutboxDeliveredQuery = `UPDATE transaction_outbox SET delivered_at = $1 WHERE id = $2`
+
+func markOutboxDelivered(ctx context.Context, tx pgx.Tx, id int64) error {
+	if _, err := tx.Exec(ctx, markOutboxDeliveredQuery, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to mark outbox entry %d delivered: %w", id, err)
+	}
+	return nil
+}
+
+const bumpOutboxAttemptsQuery = `UPDATE transaction_outbox SET attempts = attempts + 1 WHERE id = $1`
+
+func bumpOutboxAttempts(ctx context.Context, tx pgx.Tx, id int64) error {
+	if _, err := tx.Exec(ctx, bumpOutboxAttemptsQuery, id); err != nil {
+		return fmt.Errorf("failed to bump outbox entry %d attempts: %w", id, err)
+	}
+	return nil
+}
+
+// bulkItem is one line of an Elasticsearch _bulk response's "items" array.
+type bulkItem struct {
+	Index struct {
+		Status int `json:"status"`
+	} `json:"index"`
+}
+
+type bulkResponseBody struct {
+	Errors bool       `json:"errors"`
+	Items  []bulkItem `json:"items"`
+}
+
+// bulkIndex submits entries to Elasticsearch's _bulk API in one request and
+// reports, per entry and in the same order, whether it was indexed
+// successfully.
+func bulkIndex(esConn internal.ElasticsearchClient, entries []outboxEntry) ([]bool, error) {
+	var body bytes.Buffer
+	for _, e := range entries {
+		action, err := json.Marshal(map[string]any{
+			"index": map[string]string{"_index": e.indexName},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal bulk action line: %w", err)
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(e.payload)
+		body.WriteByte('\n')
+	}
+
+	res, err := esConn.Bulk(&body)
+	if err != nil {
+		return nil, fmt.Errorf("bulk request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	var parsed bulkResponseBody
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if len(parsed.Items) != len(entries) {
+		return nil, fmt.Errorf("bulk response had %d items for %d entries", len(parsed.Items), len(entries))
+	}
+
+	results := make([]bool, len(entries))
+	for i, item := range parsed.Items {
+		results[i] = item.Index.Status >= 200 && item.Index.Status < 300
+	}
+	return results, nil
+}
@@ -0,0 +1,269 @@
+package processor_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/workers/processor"
+)
+
+func TestOutboxRelay_RelayOnceDeliversAndMarksRows(t *testing.T) {
+	// Arrange: two undelivered rows, one of which Elasticsearch rejects.
+	var execSQLs []string
+	var execArgs [][]any
+
+	mockTx := &internal.MockPgDBConnection{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			row := 0
+			rowData := []struct {
+				id        int64
+				indexName string
+				payload   []byte
+				attempts  int
+			}{
+				{1, "bank-transactions-2026-07-25", []byte(`{"transaction_id":"TX1"}`), 0},
+				{2, "bank-transactions-2026-07-25", []byte(`{"transaction_id":"TX2"}`), 0},
+			}
+			return &internal.MockPgxRows{
+				NextFunc: func() bool {
+					row++
+					return row <= len(rowData)
+				},
+				ScanFunc: func(dest ...interface{}) error {
+					d := rowData[row-1]
+					*dest[0].(*int64) = d.id
+					*dest[1].(*string) = d.indexName
+					*dest[2].(*[]byte) = d.payload
+					*dest[3].(*int) = d.attempts
+					return nil
+				},
+			}, nil
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			execSQLs = append(execSQLs, sql)
+			execArgs = append(execArgs, arguments)
+			return pgconn.CommandTag{}, nil
+		},
+	}
+
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+
+	mockEsClient := &internal.MockElasticsearchClient{
+		BulkFunc: func(body io.Reader) (*esapi.Response, error) {
+			return internal.MockResponse(200, `{
+				"errors": true,
+				"items": [
+					{"index": {"status": 201}},
+					{"index": {"status": 400}}
+				]
+			}`), nil
+		},
+	}
+
+	relay := &processor.OutboxRelay{
+		PgxConn: mockConn,
+		EsConn:  mockEsClient,
+	}
+
+	// Act
+	err := relay.RelayOnce(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+
+	var delivered, bumped []any
+	for i, sql := range execSQLs {
+		switch {
+		case strings.Contains(sql, "SET delivered_at"):
+			delivered = append(delivered, execArgs[i][1])
+		case strings.Contains(sql, "SET attempts = attempts + 1"):
+			bumped = append(bumped, execArgs[i][0])
+		}
+	}
+	assert.Equal(t, []any{int64(1)}, delivered)
+	assert.Equal(t, []any{int64(2)}, bumped)
+}
+
+func TestOutboxRelay_RelayOnceNoUndeliveredRows(t *testing.T) {
+	mockTx := &internal.MockPgDBConnection{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			return &internal.MockPgxRows{
+				NextFunc: func() bool { return false },
+			}, nil
+		},
+	}
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+	bulkCalled := false
+	mockEsClient := &internal.MockElasticsearchClient{
+		BulkFunc: func(body io.Reader) (*esapi.Response, error) {
+			bulkCalled = true
+			return internal.MockResponse(200, `{"items":[]}`), nil
+		},
+	}
+
+	relay := &processor.OutboxRelay{
+		PgxConn: mockConn,
+		EsConn:  mockEsClient,
+	}
+
+	err := relay.RelayOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, bulkCalled, "no rows to deliver means no bulk request")
+}
+
+func TestOutboxRelay_RelayOnceUpdatesMetrics(t *testing.T) {
+	// Arrange: same two-row, one-rejected batch as the delivery test above,
+	// but this time with Metrics set so we can assert it was populated.
+	mockTx := &internal.MockPgDBConnection{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			row := 0
+			rowData := []struct {
+				id        int64
+				indexName string
+				payload   []byte
+				attempts  int
+			}{
+				{1, "bank-transactions-2026-07-25", []byte(`{"transaction_id":"TX1"}`), 0},
+				{2, "bank-transactions-2026-07-25", []byte(`{"transaction_id":"TX2"}`), 0},
+			}
+			return &internal.MockPgxRows{
+				NextFunc: func() bool {
+					row++
+					return row <= len(rowData)
+				},
+				ScanFunc: func(dest ...interface{}) error {
+					d := rowData[row-1]
+					*dest[0].(*int64) = d.id
+					*dest[1].(*string) = d.indexName
+					*dest[2].(*[]byte) = d.payload
+					*dest[3].(*int) = d.attempts
+					return nil
+				},
+			}, nil
+		},
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			return &internal.MockPgxRow{
+				ScanFunc: func(dest ...interface{}) error {
+					*dest[0].(*int64) = 3
+					return nil
+				},
+			}
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.CommandTag{}, nil
+		},
+	}
+
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+
+	mockEsClient := &internal.MockElasticsearchClient{
+		BulkFunc: func(body io.Reader) (*esapi.Response, error) {
+			return internal.MockResponse(200, `{
+				"errors": true,
+				"items": [
+					{"index": {"status": 201}},
+					{"index": {"status": 400}}
+				]
+			}`), nil
+		},
+	}
+
+	metrics := &processor.OutboxRelayMetrics{}
+	relay := &processor.OutboxRelay{
+		PgxConn: mockConn,
+		EsConn:  mockEsClient,
+		Metrics: metrics,
+	}
+
+	// Act
+	err := relay.RelayOnce(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, metrics.FlushCount.Load())
+	assert.EqualValues(t, 1, metrics.DeliveredCount.Load())
+	assert.EqualValues(t, 1, metrics.RejectedCount.Load())
+	assert.EqualValues(t, 3, metrics.QueueDepth.Load())
+	assert.GreaterOrEqual(t, metrics.LastFlushDuration.Load(), int64(0))
+}
+
+func TestOutboxRelay_RelayOnceBulkRequestFailure(t *testing.T) {
+	// Arrange: a single undelivered row, but the bulk request itself fails
+	// (connection error) rather than individual items being rejected.
+	var execSQLs []string
+	var execArgs [][]any
+
+	mockTx := &internal.MockPgDBConnection{
+		QueryFunc: func(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+			row := 0
+			return &internal.MockPgxRows{
+				NextFunc: func() bool {
+					row++
+					return row <= 1
+				},
+				ScanFunc: func(dest ...interface{}) error {
+					*dest[0].(*int64) = 7
+					*dest[1].(*string) = "bank-transactions-2026-07-25"
+					*dest[2].(*[]byte) = []byte(`{"transaction_id":"TX7"}`)
+					*dest[3].(*int) = 0
+					return nil
+				},
+			}, nil
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			execSQLs = append(execSQLs, sql)
+			execArgs = append(execArgs, arguments)
+			return pgconn.CommandTag{}, nil
+		},
+	}
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+	mockEsClient := &internal.MockElasticsearchClient{
+		BulkFunc: func(body io.Reader) (*esapi.Response, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	relay := &processor.OutboxRelay{
+		PgxConn: mockConn,
+		EsConn:  mockEsClient,
+	}
+
+	err := relay.RelayOnce(context.Background())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+
+	var bumped []any
+	for i, sql := range execSQLs {
+		if strings.Contains(sql, "SET attempts = attempts + 1") {
+			bumped = append(bumped, execArgs[i][0])
+		}
+	}
+	assert.Equal(t, []any{int64(7)}, bumped)
+}
@@ -1,25 +1,84 @@
 package processor
 
 import (
+	"context"
+	"log"
+	"sync/atomic"
 	"time"
 
-	"github.com/jackc/pgx/v5"
 	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/admin"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+	"github.com/siddarth99/banking-ledger/pkg/pending"
+	"github.com/siddarth99/banking-ledger/pkg/webhook"
 )
 
 type ProcessWorker struct {
-	PgxConn *pgx.Conn
+	PgxConn internal.PgDBConnection
 	EsConn  internal.ElasticsearchClient
+	// PendingRequests tracks the approval workflow for transactions submitted
+	// through the two-phase transaction API. It is nil for processors (such
+	// as account creation) that aren't gated behind client approval.
+	PendingRequests *pending.Requests
+	// Heartbeats reports this worker's liveness and throughput to the admin
+	// API. It is nil in tests and anywhere else /admin/workers isn't wired
+	// up.
+	Heartbeats *admin.Heartbeats
+	// WorkerID identifies this worker to Heartbeats and to ShouldDrain. It is
+	// only meaningful when Heartbeats is non-nil.
+	WorkerID string
+	// Running, when non-nil, is cleared by main's drain poller once
+	// DrainWorkersHandler asks this worker to stop; main's consume loop
+	// checks it between messages so whatever's already in flight still runs
+	// to completion.
+	Running *atomic.Bool
+	// WebhookDispatcher delivers account.completed/account.failed
+	// notifications to any subscription registered against the processed
+	// request's reference ID. It is nil anywhere webhook callbacks aren't
+	// wired up.
+	WebhookDispatcher *webhook.Dispatcher
+}
+
+// notify dispatches event for referenceID via WebhookDispatcher, if
+// configured, logging (rather than failing the caller) if delivery setup
+// itself errors - a subscriber being unreachable must never fail the
+// transaction it's reporting on.
+func (pw *ProcessWorker) notify(ctx context.Context, referenceID, event string, payload []byte) {
+	if pw.WebhookDispatcher == nil {
+		return
+	}
+	if err := pw.WebhookDispatcher.Dispatch(ctx, referenceID, event, payload); err != nil {
+		log.Printf("Failed to dispatch %s webhook for %s: %s", event, referenceID, err)
+	}
+}
+
+// heartbeat records that WorkerID finished processing (or failed to process)
+// one message, if Heartbeats is configured. inFlightTransactionID should be
+// empty once the message has been acked, since the worker is idle again.
+func (pw *ProcessWorker) heartbeat(ctx context.Context, inFlightTransactionID string, processed, failed int64) {
+	if pw.Heartbeats == nil {
+		return
+	}
+	_ = pw.Heartbeats.Heartbeat(ctx, pw.WorkerID, inFlightTransactionID, processed, failed)
 }
 
 // Log the account creation transaction to Elasticsearch
 type TransactionDocument struct {
-	TransactionID           string    `json:"transaction_id"`
-	AccountNumber           string    `json:"account_number"`
-	Amount                  float64   `json:"amount"`
-	Type                    string    `json:"type"`
-	Status                  string    `json:"status"`
-	Timestamp               time.Time `json:"timestamp"`
-	BranchCode              string    `json:"branch_code"`
-	BalanceAfterTransaction float64   `json:"balance_after_transaction"`
-}
\ No newline at end of file
+	TransactionID           string       `json:"transaction_id"`
+	AccountNumber           string       `json:"account_number"`
+	Amount                  money.Amount `json:"amount"`
+	Type                    string       `json:"type"`
+	Status                  string       `json:"status"`
+	Timestamp               time.Time    `json:"timestamp"`
+	BranchCode              string       `json:"branch_code"`
+	BalanceAfterTransaction money.Amount `json:"balance_after_transaction"`
+	// Postings carries every ledger leg the transaction produced, so a
+	// transfer's debit and credit can both be read back from one document.
+	Postings []Posting `json:"postings,omitempty"`
+	// IdempotencyKey lets GetAccountStatusHandler look this document up by
+	// the client's Idempotency-Key as well as by transaction ID.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// RetryCount tracks how many times this transaction has been requeued
+	// by an operator after failing.
+	RetryCount int `json:"retry_count,omitempty"`
+}
@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// Reference-status stages CreateAccountProcessor records as it works through
+// an account-creation message. CreateAccountHandler records StageEnqueued
+// before the message ever reaches a worker - see
+// api/handlers/reference_status.go.
+const (
+	StageValidated = "VALIDATED"
+	StagePersisted = "PERSISTED"
+	// StageIndexed marks the outbox entry as durably queued for delivery to
+	// Elasticsearch, not that it has actually landed there yet - see
+	// writeOutboxEntry and OutboxRelay, which own that delivery.
+	StageIndexed = "INDEXED"
+)
+
+// Outcome values for a referenceStatusStage.
+const (
+	stageStatusOK     = "OK"
+	stageStatusFailed = "FAILED"
+)
+
+// ReferenceStatusDeadLetterQueue is where a referenceStatusStage document is
+// published if Elasticsearch rejects or can't be reached for the index
+// call, so a stage update is never silently dropped; an operator can replay
+// it from there later.
+const ReferenceStatusDeadLetterQueue = "reference_status.dlq"
+
+// referenceStatusStage is a single point in a reference ID's processing
+// timeline, as recorded to bank-reference-status-*.
+type referenceStatusStage struct {
+	ReferenceID string    `json:"reference_id"`
+	Stage       string    `json:"stage"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// recordStage indexes a referenceStatusStage document for p.Data.ReferenceID
+// and stage into Elasticsearch, falling back to publishing the same document
+// to ReferenceStatusDeadLetterQueue over p.StatusDLQChannel if the index
+// call fails, so a stage update is never silently lost. It logs (rather than
+// failing the caller) on any error - a missing status update must never fail
+// the account creation it's reporting on.
+func (p *CreateAccountProcessor) recordStage(ctx context.Context, stage string, stageErr error) {
+	if p.EsConn == nil {
+		return
+	}
+
+	doc := referenceStatusStage{
+		ReferenceID: p.Data.ReferenceID,
+		Stage:       stage,
+		Status:      stageStatusOK,
+		Timestamp:   time.Now(),
+	}
+	if stageErr != nil {
+		doc.Status = stageStatusFailed
+		doc.Error = stageErr.Error()
+	}
+
+	body, err := json.Marshal(doc)
+	if err != nil {
+		log.Printf("failed to marshal reference-status stage %s/%s: %s", doc.ReferenceID, stage, err)
+		return
+	}
+
+	indexName := fmt.Sprintf("bank-reference-status-%s", doc.Timestamp.Format("2006-01-02"))
+	res, indexErr := p.EsConn.Index(indexName, bytes.NewReader(body))
+	failed := indexErr != nil
+	if !failed {
+		defer res.Body.Close()
+		failed = res.IsError()
+	}
+	if !failed {
+		return
+	}
+
+	if p.StatusDLQChannel == nil {
+		log.Printf("failed to index reference-status stage %s/%s and no dead-letter queue configured: %v", doc.ReferenceID, stage, indexErr)
+		return
+	}
+	if pubErr := internal.PublishWithContext(ctx, body, p.StatusDLQChannel, "", ReferenceStatusDeadLetterQueue, false, false); pubErr != nil {
+		log.Printf("failed to publish reference-status dead letter for %s/%s: %s", doc.ReferenceID, stage, pubErr)
+	}
+}
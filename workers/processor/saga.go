@@ -0,0 +1,157 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+)
+
+// Step is a single, independently-committed leg of a Saga. Do performs the
+// step's work inside its own transaction; Compensate, if set, undoes it
+// (also inside its own transaction) if a later step of the same Saga fails.
+type Step struct {
+	Name       string
+	Do         func(ctx context.Context, tx pgx.Tx) error
+	Compensate func(ctx context.Context, tx pgx.Tx) error
+}
+
+// Saga step statuses, as recorded in saga_state.
+const (
+	sagaStepDone        = "DONE"
+	sagaStepFailed      = "FAILED"
+	sagaStepCompensated = "COMPENSATED"
+)
+
+// Saga runs Steps in order, each in its own transaction, recording every
+// step's outcome durably in saga_state rather than holding the whole
+// sequence in one transaction the way TransactionProcessor's same-bank
+// TRANSFER does. That's what lets a Saga span steps a single database
+// transaction can't reach - an external wire confirmation, a card
+// authorization hold - and lets Run resume a saga a crashed worker left
+// partway done, by skipping any step already marked DONE, instead of
+// running it (and whatever side effect it has outside Postgres) twice.
+type Saga struct {
+	// ID identifies this saga's run across process restarts; it is typically
+	// the transaction ID of the transfer it orchestrates.
+	ID    string
+	Steps []Step
+}
+
+const upsertSagaStepQuery = `
+	INSERT INTO saga_state (saga_id, step_name, status, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $4)
+	ON CONFLICT (saga_id, step_name) DO UPDATE SET status = $3, updated_at = $4
+`
+
+const selectSagaStepStatusQuery = `SELECT status FROM saga_state WHERE saga_id = $1 AND step_name = $2`
+
+func recordSagaStep(ctx context.Context, tx pgx.Tx, sagaID, stepName, status string) error {
+	if _, err := tx.Exec(ctx, upsertSagaStepQuery, sagaID, stepName, status, time.Now()); err != nil {
+		return fmt.Errorf("failed to record saga step %s/%s: %w", sagaID, stepName, err)
+	}
+	return nil
+}
+
+// Run executes s.Steps in order against conn. A step already recorded DONE
+// by a previous, interrupted Run is skipped rather than re-executed, so a
+// crashed worker can resume a saga by calling Run again with the same ID.
+// If a step fails, every step that already completed in this or an earlier
+// Run is compensated in reverse order, and the step's error is returned.
+func (s *Saga) Run(ctx context.Context, conn internal.PgDBConnection) error {
+	completed := make([]Step, 0, len(s.Steps))
+
+	for _, step := range s.Steps {
+		done, err := s.stepAlreadyDone(ctx, conn, step.Name)
+		if err != nil {
+			return err
+		}
+		if done {
+			completed = append(completed, step)
+			continue
+		}
+
+		if err := s.runStep(ctx, conn, step); err != nil {
+			s.compensate(ctx, conn, completed)
+			return fmt.Errorf("saga %s failed at step %q: %w", s.ID, step.Name, err)
+		}
+		completed = append(completed, step)
+	}
+
+	return nil
+}
+
+func (s *Saga) stepAlreadyDone(ctx context.Context, conn internal.PgDBConnection, stepName string) (bool, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx, selectSagaStepStatusQuery, s.ID, stepName).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read saga step status: %w", err)
+	}
+	return status == sagaStepDone, nil
+}
+
+func (s *Saga) runStep(ctx context.Context, conn internal.PgDBConnection, step Step) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := step.Do(ctx, tx); err != nil {
+		return err
+	}
+	if err := recordSagaStep(ctx, tx, s.ID, step.Name, sagaStepDone); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit saga step %q: %w", step.Name, err)
+	}
+	return nil
+}
+
+// compensate runs Compensate for every completed step in reverse order. A
+// step that fails to compensate is recorded FAILED and logged rather than
+// stopping the rest of the rollback, since an operator triaging a stuck
+// saga needs to see every step's true state, not just the first failure.
+func (s *Saga) compensate(ctx context.Context, conn internal.PgDBConnection, completed []Step) {
+	for i := len(completed) - 1; i >= 0; i-- {
+		step := completed[i]
+		if step.Compensate == nil {
+			continue
+		}
+		if err := s.compensateStep(ctx, conn, step); err != nil {
+			log.Printf("saga %s: failed to compensate step %q: %s", s.ID, step.Name, err)
+		}
+	}
+}
+
+func (s *Saga) compensateStep(ctx context.Context, conn internal.PgDBConnection, step Step) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := step.Compensate(ctx, tx); err != nil {
+		_ = recordSagaStep(ctx, tx, s.ID, step.Name, sagaStepFailed)
+		_ = tx.Commit(ctx)
+		return err
+	}
+	if err := recordSagaStep(ctx, tx, s.ID, step.Name, sagaStepCompensated); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,108 @@
+package processor_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/workers/processor"
+)
+
+// sagaStateMock backs a fake saga_state table in memory, keyed on
+// saga_id/step_name, and hands out a fresh mockTx (sharing the same map)
+// every time PgxConn.Begin is called - each Saga step runs in its own
+// transaction, so the mock connection has to behave the same way.
+func sagaStateMock(statuses map[string]string) *internal.MockPgDBConnection {
+	return &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return &internal.MockPgDBConnection{
+				QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+					key := args[0].(string) + "/" + args[1].(string)
+					return &internal.MockPgxRow{
+						ScanFunc: func(dest ...interface{}) error {
+							status, ok := statuses[key]
+							if !ok {
+								return pgx.ErrNoRows
+							}
+							*dest[0].(*string) = status
+							return nil
+						},
+					}
+				},
+				ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+					statuses[arguments[0].(string)+"/"+arguments[1].(string)] = arguments[2].(string)
+					return pgconn.NewCommandTag("INSERT 0 1"), nil
+				},
+			}, nil
+		},
+	}
+}
+
+func TestSaga_RunExecutesStepsInOrder(t *testing.T) {
+	var order []string
+	saga := &processor.Saga{
+		ID: "SAGA1",
+		Steps: []processor.Step{
+			{Name: "a", Do: func(ctx context.Context, tx pgx.Tx) error { order = append(order, "a"); return nil }},
+			{Name: "b", Do: func(ctx context.Context, tx pgx.Tx) error { order = append(order, "b"); return nil }},
+		},
+	}
+
+	err := saga.Run(context.Background(), sagaStateMock(map[string]string{}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, order)
+}
+
+func TestSaga_RunCompensatesCompletedStepsInReverseOnFailure(t *testing.T) {
+	var compensated []string
+	saga := &processor.Saga{
+		ID: "SAGA2",
+		Steps: []processor.Step{
+			{
+				Name:       "debit",
+				Do:         func(ctx context.Context, tx pgx.Tx) error { return nil },
+				Compensate: func(ctx context.Context, tx pgx.Tx) error { compensated = append(compensated, "debit"); return nil },
+			},
+			{
+				Name:       "credit",
+				Do:         func(ctx context.Context, tx pgx.Tx) error { return nil },
+				Compensate: func(ctx context.Context, tx pgx.Tx) error { compensated = append(compensated, "credit"); return nil },
+			},
+			{
+				Name: "publish",
+				Do:   func(ctx context.Context, tx pgx.Tx) error { return errors.New("downstream unavailable") },
+			},
+		},
+	}
+
+	err := saga.Run(context.Background(), sagaStateMock(map[string]string{}))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "downstream unavailable")
+	assert.Equal(t, []string{"credit", "debit"}, compensated)
+}
+
+func TestSaga_RunSkipsStepsAlreadyMarkedDone(t *testing.T) {
+	// Simulate a crash after "debit" committed but before "credit" ran.
+	statuses := map[string]string{"SAGA3/debit": "DONE"}
+
+	var ran []string
+	saga := &processor.Saga{
+		ID: "SAGA3",
+		Steps: []processor.Step{
+			{Name: "debit", Do: func(ctx context.Context, tx pgx.Tx) error { ran = append(ran, "debit"); return nil }},
+			{Name: "credit", Do: func(ctx context.Context, tx pgx.Tx) error { ran = append(ran, "credit"); return nil }},
+		},
+	}
+
+	err := saga.Run(context.Background(), sagaStateMock(statuses))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"credit"}, ran, "a step already recorded DONE must not run again")
+}
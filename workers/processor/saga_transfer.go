@@ -0,0 +1,214 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/ledger"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+)
+
+// SagaTransferData is the data needed to run a saga-orchestrated transfer.
+type SagaTransferData struct {
+	SourceAccount      string       `json:"sourceAccount"`
+	DestinationAccount string       `json:"destinationAccount"`
+	Amount             money.Amount `json:"amount"`
+	TransactionID      string       `json:"transactionId"`
+	BranchCode         string       `json:"branchCode"`
+}
+
+// SagaProcessor runs a transfer as a Saga instead of as the single atomic
+// transaction TransactionProcessor uses for same-bank TRANSFERs. It exists
+// for transfers a single Postgres transaction can't serve - an external
+// wire, a scheduled payment - where each leg has to commit (and, on later
+// failure, compensate) independently; TransactionProcessor remains the
+// right tool for an ordinary same-bank transfer.
+type SagaProcessor struct {
+	ProcessWorker
+	Data SagaTransferData
+	// AMQPChannel publishes the saga's completion/failure event to
+	// CompletionQueue. A nil AMQPChannel disables publishing.
+	AMQPChannel internal.AMQPQueuePublisher
+	// CompletionQueue is the queue Process publishes its completion or
+	// failure event to.
+	CompletionQueue string
+
+	// postings accumulates each leg's result as steps complete, so later
+	// steps (indexing, publishing) can describe the whole transfer. Steps
+	// run sequentially, never concurrently, so this needs no locking.
+	postings []Posting
+}
+
+// Process runs p.Data's transfer as a four-step Saga: debit the source
+// account, credit the destination, append the result to the ledger and
+// outbox, and publish a completion event. A failure at any step compensates
+// every step that already committed, in reverse order.
+func (p *SagaProcessor) Process(ctx context.Context) error {
+	saga := &Saga{
+		ID: p.Data.TransactionID,
+		Steps: []Step{
+			{Name: "debit_source", Do: p.debitSource, Compensate: p.undoDebitSource},
+			{Name: "credit_destination", Do: p.creditDestination, Compensate: p.undoCreditDestination},
+			{Name: "index_transfer", Do: p.indexTransfer, Compensate: p.compensateIndex},
+			{Name: "publish_completion", Do: p.publishCompletion, Compensate: p.publishCompensation},
+		},
+	}
+
+	if err := saga.Run(ctx, p.PgxConn); err != nil {
+		p.heartbeat(ctx, "", 0, 1)
+		return err
+	}
+	p.heartbeat(ctx, "", 1, 0)
+	return nil
+}
+
+func (p *SagaProcessor) debitSource(ctx context.Context, tx pgx.Tx) error {
+	posting, err := applyPosting(ctx, tx, p.Data.SourceAccount, DirectionDebit, p.Data.Amount, "saga transfer debit")
+	if err != nil {
+		return err
+	}
+	if err := p.writeLedgerEntry(ctx, tx, posting); err != nil {
+		return err
+	}
+	p.postings = append(p.postings, posting)
+	return nil
+}
+
+// undoDebitSource re-credits the source account for the amount debitSource
+// took from it, reversing that step's effect.
+func (p *SagaProcessor) undoDebitSource(ctx context.Context, tx pgx.Tx) error {
+	posting, err := applyPosting(ctx, tx, p.Data.SourceAccount, DirectionCredit, p.Data.Amount, "saga transfer compensation")
+	if err != nil {
+		return err
+	}
+	return p.writeLedgerEntry(ctx, tx, posting)
+}
+
+func (p *SagaProcessor) creditDestination(ctx context.Context, tx pgx.Tx) error {
+	posting, err := applyPosting(ctx, tx, p.Data.DestinationAccount, DirectionCredit, p.Data.Amount, "saga transfer credit")
+	if err != nil {
+		return err
+	}
+	if err := p.writeLedgerEntry(ctx, tx, posting); err != nil {
+		return err
+	}
+	p.postings = append(p.postings, posting)
+	return nil
+}
+
+// undoCreditDestination re-debits the destination account for the amount
+// creditDestination gave it, reversing that step's effect.
+func (p *SagaProcessor) undoCreditDestination(ctx context.Context, tx pgx.Tx) error {
+	posting, err := applyPosting(ctx, tx, p.Data.DestinationAccount, DirectionDebit, p.Data.Amount, "saga transfer compensation")
+	if err != nil {
+		return err
+	}
+	return p.writeLedgerEntry(ctx, tx, posting)
+}
+
+func (p *SagaProcessor) writeLedgerEntry(ctx context.Context, tx pgx.Tx, posting Posting) error {
+	if _, err := tx.Exec(ctx, insertLedgerEntryQuery,
+		p.Data.TransactionID, posting.AccountNumber, posting.Direction, posting.Amount, posting.BalanceAfter, posting.BranchCode, posting.Amount.Currency(), time.Now(),
+	); err != nil {
+		return fmt.Errorf("failed to write ledger entry: %w", err)
+	}
+	return nil
+}
+
+// indexTransfer writes the transfer's TransactionDocument to the outbox and
+// appends it to the tamper-evident transaction log, once both legs have
+// posted.
+func (p *SagaProcessor) indexTransfer(ctx context.Context, tx pgx.Tx) error {
+	transactionDocJSON, err := p.transactionDocumentJSON("COMPLETED")
+	if err != nil {
+		return err
+	}
+
+	indexName := fmt.Sprintf("bank-transactions-%s", time.Now().Format("2006-01-02"))
+	if err := writeOutboxEntry(ctx, tx, indexName, transactionDocJSON); err != nil {
+		return err
+	}
+	return ledger.Append(ctx, tx, p.Data.TransactionID, transactionDocJSON)
+}
+
+// compensateIndex records that the transfer indexed by indexTransfer was
+// subsequently compensated. The original document is an immutable audit
+// record, so this appends a correction rather than rewriting it.
+func (p *SagaProcessor) compensateIndex(ctx context.Context, tx pgx.Tx) error {
+	transactionDocJSON, err := p.transactionDocumentJSON("COMPENSATED")
+	if err != nil {
+		return err
+	}
+
+	indexName := fmt.Sprintf("bank-transactions-%s", time.Now().Format("2006-01-02"))
+	if err := writeOutboxEntry(ctx, tx, indexName, transactionDocJSON); err != nil {
+		return err
+	}
+	return ledger.Append(ctx, tx, p.Data.TransactionID, transactionDocJSON)
+}
+
+func (p *SagaProcessor) transactionDocumentJSON(status string) ([]byte, error) {
+	now := time.Now()
+	accountNumber, branchCode, balanceAfter := p.Data.SourceAccount, p.Data.BranchCode, p.Data.Amount
+	if len(p.postings) > 0 {
+		accountNumber = p.postings[0].AccountNumber
+		branchCode = p.postings[0].BranchCode
+		balanceAfter = p.postings[0].BalanceAfter
+	}
+
+	doc := TransactionDocument{
+		TransactionID:           p.Data.TransactionID,
+		AccountNumber:           accountNumber,
+		Amount:                  p.Data.Amount,
+		Type:                    "SAGA_TRANSFER",
+		Status:                  status,
+		Timestamp:               now,
+		BranchCode:              branchCode,
+		BalanceAfterTransaction: balanceAfter,
+		Postings:                p.postings,
+	}
+	transactionDocJSON, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transaction document: %w", err)
+	}
+	return transactionDocJSON, nil
+}
+
+// sagaEvent is the payload published to CompletionQueue once the saga
+// reaches a terminal state.
+type sagaEvent struct {
+	TransactionID string `json:"transactionId"`
+	Status        string `json:"status"`
+}
+
+func (p *SagaProcessor) publishEvent(ctx context.Context, status string) error {
+	if p.AMQPChannel == nil {
+		return nil
+	}
+	body, err := json.Marshal(sagaEvent{TransactionID: p.Data.TransactionID, Status: status})
+	if err != nil {
+		return fmt.Errorf("failed to marshal saga event: %w", err)
+	}
+	if err := internal.PublishWithContext(ctx, body, p.AMQPChannel, "", p.CompletionQueue, false, false); err != nil {
+		return fmt.Errorf("failed to publish saga event: %w", err)
+	}
+	return nil
+}
+
+func (p *SagaProcessor) publishCompletion(ctx context.Context, tx pgx.Tx) error {
+	return p.publishEvent(ctx, "COMPLETED")
+}
+
+// publishCompensation is the compensation for publishCompletion; there's
+// nothing to undo about a message that was never sent, but a later step
+// failing after publishCompletion already ran can't happen (it's the last
+// step), so this only guards against Compensate being called with a nil
+// func if more steps are ever appended after this one.
+func (p *SagaProcessor) publishCompensation(ctx context.Context, tx pgx.Tx) error {
+	return p.publishEvent(ctx, "COMPENSATED")
+}
@@ -0,0 +1,127 @@
+package processor_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+	"github.com/siddarth99/banking-ledger/workers/processor"
+)
+
+func TestSagaProcessor_ProcessSuccess(t *testing.T) {
+	balances := map[string]func(dest ...interface{}) error{
+		"ACC111": scanAccountRow("1000.00", "USD", "BR001"),
+		"ACC222": scanAccountRow("200.00", "USD", "BR002"),
+	}
+
+	mockTx := &internal.MockPgDBConnection{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			switch {
+			case strings.Contains(sql, "transaction_log"):
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			case strings.Contains(sql, "saga_state"):
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			default:
+				account := args[0].(string)
+				return &internal.MockPgxRow{ScanFunc: balances[account]}
+			}
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
+	}
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+
+	mockAMQP := &internal.MockAMQPChannel{}
+	mockAMQP.On("PublishWithContext", mock.Anything, "", "saga_transfer.completed", false, false, mock.Anything).
+		Return(nil)
+
+	amount, err := money.FromString("150.00", "USD")
+	assert.NoError(t, err)
+
+	proc := processor.SagaProcessor{
+		ProcessWorker: processor.ProcessWorker{
+			PgxConn: mockConn,
+		},
+		Data: processor.SagaTransferData{
+			SourceAccount:      "ACC111",
+			DestinationAccount: "ACC222",
+			Amount:             amount,
+			TransactionID:      "TX999",
+		},
+		AMQPChannel:     mockAMQP,
+		CompletionQueue: "saga_transfer.completed",
+	}
+
+	err = proc.Process(context.Background())
+
+	assert.NoError(t, err)
+	mockAMQP.AssertExpectations(t)
+}
+
+func TestSagaProcessor_ProcessCompensatesOnPublishFailure(t *testing.T) {
+	balances := map[string]func(dest ...interface{}) error{
+		"ACC111": scanAccountRow("1000.00", "USD", "BR001"),
+		"ACC222": scanAccountRow("200.00", "USD", "BR002"),
+	}
+
+	mockTx := &internal.MockPgDBConnection{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			switch {
+			case strings.Contains(sql, "transaction_log"):
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			case strings.Contains(sql, "saga_state"):
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			default:
+				account := args[0].(string)
+				return &internal.MockPgxRow{ScanFunc: balances[account]}
+			}
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
+	}
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+
+	mockAMQP := &internal.MockAMQPChannel{}
+	mockAMQP.On("PublishWithContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(amqp.ErrClosed)
+
+	amount, err := money.FromString("150.00", "USD")
+	assert.NoError(t, err)
+
+	proc := processor.SagaProcessor{
+		ProcessWorker: processor.ProcessWorker{
+			PgxConn: mockConn,
+		},
+		Data: processor.SagaTransferData{
+			SourceAccount:      "ACC111",
+			DestinationAccount: "ACC222",
+			Amount:             amount,
+			TransactionID:      "TX998",
+		},
+		AMQPChannel:     mockAMQP,
+		CompletionQueue: "saga_transfer.completed",
+	}
+
+	err = proc.Process(context.Background())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "publish_completion")
+}
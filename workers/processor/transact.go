@@ -5,122 +5,413 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/siddarth99/banking-ledger/pkg/idempotency"
+	"github.com/siddarth99/banking-ledger/pkg/ledger"
+	"github.com/siddarth99/banking-ledger/pkg/money"
 )
 
 type TransactionProcessor struct {
 	ProcessWorker
 	Data TransactionData
+
+	// Postings is set by ProcessTransaction once the transaction commits, so
+	// a caller doing a synchronous request/reply over AMQP (see
+	// PublishAndAwaitReply) can read back every ledger leg the transaction
+	// posted, not just the single account/balance mirrored onto Data.
+	Postings []Posting
 }
 
 // TransactionData represents the data needed for a transaction
 type TransactionData struct {
-	AccountNumber     string  `json:"accountNumber"`
-	Amount            float64 `json:"amount"`
-	AvailableBalance  float64 `json:"availableBalance"`
-	Type              string  `json:"type"` // "DEPOSIT" or "WITHDRAWAL"
-	TransactionID     string  `json:"transactionId"`
-	BranchCode        string  `json:"branchCode"`
+	AccountNumber      string       `json:"accountNumber"`
+	SourceAccount      string       `json:"sourceAccount"`      // set for TRANSFER
+	DestinationAccount string       `json:"destinationAccount"` // set for TRANSFER
+	Amount             money.Amount `json:"amount"`
+	AvailableBalance   money.Amount `json:"availableBalance"`
+	Type               string       `json:"type"` // "DEPOSIT", "WITHDRAWAL" or "TRANSFER"
+	TransactionID      string       `json:"transactionId"`
+	BranchCode         string       `json:"branchCode"`
+	// IdempotencyKey is the client-supplied Idempotency-Key the request was
+	// de-duplicated on, if any. It is carried through to the indexed
+	// TransactionDocument so GetAccountStatusHandler can look transactions up
+	// by key as well as by transaction ID.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	// RetryCount is non-zero when this message was republished by an
+	// operator via POST /admin/transactions/:id/requeue rather than
+	// submitted by a client.
+	RetryCount int `json:"retryCount,omitempty"`
+}
+
+// Direction identifies which side of a ledger posting an entry represents.
+type Direction string
+
+const (
+	DirectionDebit  Direction = "DEBIT"
+	DirectionCredit Direction = "CREDIT"
+)
+
+// Posting is a single immutable leg of a ledger entry. A DEPOSIT or
+// WITHDRAWAL produces one posting; a TRANSFER produces a balanced debit and
+// credit pair.
+type Posting struct {
+	AccountNumber string       `json:"account_number"`
+	Direction     Direction    `json:"direction"`
+	Amount        money.Amount `json:"amount"`
+	BalanceAfter  money.Amount `json:"balance_after"`
+	BranchCode    string       `json:"branch_code"`
+}
+
+const selectAccountForUpdateQuery = `SELECT available_balance, branch_code, currency FROM accounts
+	WHERE account_number = $1 AND status='ACTIVE' FOR UPDATE;`
+
+const updateAccountBalanceQuery = `UPDATE accounts SET available_balance = $1 WHERE account_number = $2`
+
+const insertLedgerEntryQuery = `INSERT INTO ledger_entries (
+		transaction_id, account_number, direction, amount, balance_after, branch_code, currency, created_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+// applyPosting debits or credits accountNumber by amount within tx and
+// returns the resulting ledger posting. It does not write the posting to
+// ledger_entries; the caller does that once every leg of the transaction has
+// been computed and found balanced.
+func applyPosting(ctx context.Context, tx pgx.Tx, accountNumber string, direction Direction, amount money.Amount, action string) (Posting, error) {
+	if amount.Cmp(money.Zero(amount.Currency())) <= 0 {
+		return Posting{}, fmt.Errorf("%s amount must be positive", action)
+	}
+
+	var currentBalance money.Amount
+	var branchCode, currency string
+	err := tx.QueryRow(ctx, selectAccountForUpdateQuery, accountNumber).Scan(&currentBalance, &branchCode, &currency)
+	if err != nil {
+		return Posting{}, fmt.Errorf("failed to get account balance: %w", err)
+	}
+	currentBalance = currentBalance.WithCurrency(currency)
+
+	if amount.Currency() != currency {
+		return Posting{}, fmt.Errorf("%s currency %s does not match account currency %s", action, amount.Currency(), currency)
+	}
+
+	var newBalance money.Amount
+	switch direction {
+	case DirectionCredit:
+		newBalance = currentBalance.Add(amount)
+	case DirectionDebit:
+		if currentBalance.Cmp(amount) < 0 {
+			return Posting{}, fmt.Errorf("insufficient funds")
+		}
+		newBalance = currentBalance.Sub(amount)
+	}
+
+	if _, err := tx.Exec(ctx, updateAccountBalanceQuery, newBalance, accountNumber); err != nil {
+		return Posting{}, fmt.Errorf("failed to update account balance: %w", err)
+	}
+
+	return Posting{
+		AccountNumber: accountNumber,
+		Direction:     direction,
+		Amount:        amount,
+		BalanceAfter:  newBalance,
+		BranchCode:    branchCode,
+	}, nil
 }
 
-func (p *TransactionProcessor) transact(ctx context.Context) error {
+// assertBalanced enforces conservation of money per asset: for every
+// currency appearing among postings, debits must sum to exactly credits in
+// that currency. A multi-leg operation can touch more than one asset (a
+// transfer paired with a fee, say), and each one has to balance on its own
+// - there's no implicit conversion between them.
+func assertBalanced(postings []Posting) error {
+	sums := make(map[string]money.Amount)
+	var currencies []string
+	for _, posting := range postings {
+		currency := posting.Amount.Currency()
+		sum, ok := sums[currency]
+		if !ok {
+			sum = money.Zero(currency)
+			currencies = append(currencies, currency)
+		}
+		switch posting.Direction {
+		case DirectionDebit:
+			sum = sum.Add(posting.Amount)
+		case DirectionCredit:
+			sum = sum.Sub(posting.Amount)
+		}
+		sums[currency] = sum
+	}
+
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		if sum := sums[currency]; !sum.IsZero() {
+			return fmt.Errorf("ledger postings are not balanced for %s: debits and credits differ by %s", currency, sum)
+		}
+	}
+	return nil
+}
+
+func (p *TransactionProcessor) transact(ctx context.Context) ([]Posting, error) {
 	// Start a transaction
 	tx, err := p.PgxConn.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx) // Will be ignored if transaction is committed
 
-	// Get current account balance
-	var currentBalance float64
-	var branchCode string
-	query := `SELECT available_balance, branch_code FROM accounts 
-	WHERE account_number = $1 AND status='ACTIVE' FOR UPDATE;`
-
-	err = tx.QueryRow(ctx, query, p.Data.AccountNumber).Scan(&currentBalance, &branchCode)
+	// WithIdempotency guards against RabbitMQ redelivering this message: a
+	// transaction ID that already applied its postings and wrote its outbox
+	// entry in a prior, committed attempt returns that outcome here instead
+	// of debiting or crediting an account a second time.
+	resultJSON, err := idempotency.WithIdempotency(ctx, tx, p.Data.TransactionID, func() (json.RawMessage, error) {
+		return p.applyTransaction(ctx, tx)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get account balance: %w", err)
+		return nil, err
 	}
 
-	p.Data.BranchCode = branchCode
-	p.Data.AvailableBalance = currentBalance
+	// Commit the transaction
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	var postings []Posting
+	if err := json.Unmarshal(resultJSON, &postings); err != nil {
+		return nil, fmt.Errorf("failed to decode processed postings: %w", err)
+	}
+	return postings, nil
+}
 
-	// Calculate new balance based on transaction type
-	var newBalance float64
+// applyTransaction computes and records every ledger leg for p.Data inside
+// tx, writes the resulting TransactionDocument to the outbox, and returns
+// the postings marshaled as JSON - the result transact's WithIdempotency
+// call stores against p.Data.TransactionID so a redelivery of the same
+// message replays this outcome instead of running it again.
+func (p *TransactionProcessor) applyTransaction(ctx context.Context, tx pgx.Tx) (json.RawMessage, error) {
+	var postings []Posting
 	switch p.Data.Type {
 	case "DEPOSIT":
-		if p.Data.Amount <= 0 {
-			return fmt.Errorf("deposit amount must be positive")
+		posting, err := applyPosting(ctx, tx, p.Data.AccountNumber, DirectionCredit, p.Data.Amount, "deposit")
+		if err != nil {
+			return nil, err
 		}
-		newBalance = currentBalance + p.Data.Amount
+		postings = []Posting{posting}
 	case "WITHDRAWAL":
-		if p.Data.Amount <= 0 {
-			return fmt.Errorf("withdrawal amount must be positive")
+		posting, err := applyPosting(ctx, tx, p.Data.AccountNumber, DirectionDebit, p.Data.Amount, "withdrawal")
+		if err != nil {
+			return nil, err
 		}
-		if currentBalance < p.Data.Amount {
-			return fmt.Errorf("insufficient funds")
+		postings = []Posting{posting}
+	case "TRANSFER":
+		if p.Data.SourceAccount == "" || p.Data.DestinationAccount == "" {
+			return nil, fmt.Errorf("transfer requires a sourceAccount and a destinationAccount")
+		}
+		if p.Data.SourceAccount == p.Data.DestinationAccount {
+			return nil, fmt.Errorf("sourceAccount and destinationAccount must differ")
+		}
+		// applyPosting's SELECT ... FOR UPDATE locks the account row, so two
+		// concurrent transfers between the same pair of accounts in opposite
+		// directions would deadlock if each locked source-then-destination:
+		// one holds the first row waiting on the second while the other
+		// holds the second waiting on the first. Always locking the lower
+		// account number first, regardless of which leg is the debit,
+		// gives every transfer on this pair the same lock order.
+		firstAccount, secondAccount := p.Data.SourceAccount, p.Data.DestinationAccount
+		firstDirection, secondDirection := DirectionDebit, DirectionCredit
+		if secondAccount < firstAccount {
+			firstAccount, secondAccount = secondAccount, firstAccount
+			firstDirection, secondDirection = secondDirection, firstDirection
+		}
+
+		firstPosting, err := applyPosting(ctx, tx, firstAccount, firstDirection, p.Data.Amount, "transfer")
+		if err != nil {
+			return nil, err
+		}
+		secondPosting, err := applyPosting(ctx, tx, secondAccount, secondDirection, p.Data.Amount, "transfer")
+		if err != nil {
+			return nil, err
+		}
+
+		debit, credit := firstPosting, secondPosting
+		if firstDirection == DirectionCredit {
+			debit, credit = secondPosting, firstPosting
+		}
+		postings = []Posting{debit, credit}
+
+		// A transfer moves money between two ledger accounts, so its debit
+		// and credit legs must cancel out exactly. DEPOSIT/WITHDRAWAL have no
+		// second leg on the ledger (the other side is external cash), so the
+		// invariant only applies here.
+		if err := assertBalanced(postings); err != nil {
+			return nil, err
 		}
-		newBalance = currentBalance - p.Data.Amount
 	default:
-		return fmt.Errorf("invalid transaction type: %s", p.Data.Type)
+		return nil, fmt.Errorf("invalid transaction type: %s", p.Data.Type)
 	}
 
-	p.Data.AvailableBalance = newBalance
+	now := time.Now()
+	for _, posting := range postings {
+		if _, err := tx.Exec(ctx, insertLedgerEntryQuery,
+			p.Data.TransactionID, posting.AccountNumber, posting.Direction, posting.Amount, posting.BalanceAfter, posting.BranchCode, posting.Amount.Currency(), now,
+		); err != nil {
+			return nil, fmt.Errorf("failed to write ledger entry: %w", err)
+		}
+	}
 
-	// Update account balance
-	updateQuery := `UPDATE accounts SET available_balance = $1 WHERE account_number = $2`
-	_, err = tx.Exec(ctx, updateQuery, newBalance, p.Data.AccountNumber)
+	// The top-level account/balance fields mirror the primary (first) leg so
+	// existing consumers (search, status polling) keep working unchanged;
+	// Postings carries every leg, which matters for a TRANSFER.
+	accountNumber := p.Data.AccountNumber
+	balanceAfter := p.Data.AvailableBalance
+	branchCode := p.Data.BranchCode
+	if len(postings) > 0 {
+		accountNumber = postings[0].AccountNumber
+		balanceAfter = postings[0].BalanceAfter
+		branchCode = postings[0].BranchCode
+	}
+
+	transactionDoc := TransactionDocument{
+		AccountNumber:           accountNumber,
+		Type:                    p.Data.Type,
+		Amount:                  p.Data.Amount,
+		TransactionID:           p.Data.TransactionID,
+		Timestamp:               now,
+		BranchCode:              branchCode,
+		Status:                  "COMPLETED",
+		BalanceAfterTransaction: balanceAfter,
+		Postings:                postings,
+		IdempotencyKey:          p.Data.IdempotencyKey,
+		RetryCount:              p.Data.RetryCount,
+	}
+	transactionDocJSON, err := json.Marshal(transactionDoc)
 	if err != nil {
-		return fmt.Errorf("failed to update account balance: %w", err)
+		return nil, fmt.Errorf("failed to marshal transaction document: %w", err)
 	}
 
-	// Commit the transaction
-	if err = tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	// Write the outbox row in the same tx as the balance update, instead of
+	// indexing into Elasticsearch directly, so a committed transaction can
+	// never diverge from the search index: OutboxRelay delivers this row
+	// (with retries) after the fact, and a failure to write it rolls back
+	// the balance update along with everything else in this tx.
+	indexName := fmt.Sprintf("bank-transactions-%s", now.Format("2006-01-02"))
+	if err := writeOutboxEntry(ctx, tx, indexName, transactionDocJSON); err != nil {
+		return nil, err
 	}
 
-	return nil
+	// Chain this transaction's posted document onto transaction_log in the
+	// same tx as the balance update, so the ledger's tamper-evidence covers
+	// exactly the set of transactions that ever actually committed.
+	if err := ledger.Append(ctx, tx, p.Data.TransactionID, transactionDocJSON); err != nil {
+		return nil, err
+	}
+
+	postingsJSON, err := json.Marshal(postings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal postings: %w", err)
+	}
+	return postingsJSON, nil
 }
 
-// ProcessTransaction handles deposit and withdrawal transactions
-func (p *TransactionProcessor) ProcessTransaction(ctx context.Context) error {
+// TransactionResult is the payload ProcessTransaction's caller replies with
+// when a transaction was submitted via PublishAndAwaitReply: enough of the
+// posted outcome for a synchronous caller to show the account's new balance
+// without a second round trip to GetAccountStatusHandler.
+type TransactionResult struct {
+	TransactionID string       `json:"transactionId"`
+	Status        string       `json:"status"` // "COMPLETED" or "FAILED"
+	Error         string       `json:"error,omitempty"`
+	Postings      []Posting    `json:"postings,omitempty"`
+	BalanceAfter  money.Amount `json:"balanceAfter"`
+}
 
-	err := p.transact(ctx)
-	status := "COMPLETED"
-	if err != nil {
-		log.Println(err)
-		status = "FAILED"
+// Result reports the outcome of the most recent ProcessTransaction call, for
+// a caller that needs to reply to whoever submitted it.
+func (p *TransactionProcessor) Result(processErr error) TransactionResult {
+	if processErr != nil {
+		return TransactionResult{
+			TransactionID: p.Data.TransactionID,
+			Status:        "FAILED",
+			Error:         processErr.Error(),
+		}
 	}
-	transactionDoc := TransactionDocument{
-		AccountNumber: p.Data.AccountNumber,
-		Type:          p.Data.Type,
-		Amount:        p.Data.Amount,
+	return TransactionResult{
 		TransactionID: p.Data.TransactionID,
-		Timestamp:     time.Now(),
-		BranchCode:    p.Data.BranchCode,
-		Status:        status,
-		BalanceAfterTransaction: p.Data.AvailableBalance,
+		Status:        "COMPLETED",
+		Postings:      p.Postings,
+		BalanceAfter:  p.Data.AvailableBalance,
 	}
+}
 
-	// Create index name with date format for better data management
-	indexName := fmt.Sprintf("bank-transactions-%s", time.Now().Format("2006-01-02"))
+// ProcessTransaction handles deposit, withdrawal and transfer transactions
+func (p *TransactionProcessor) ProcessTransaction(ctx context.Context) error {
 
-	// Index the transaction document
-	transactionDocJSON, err := json.Marshal(transactionDoc)
-	if err != nil {
-		panic(fmt.Sprintf("failed to marshal JSON: %v", err))
-	}
+	postings, transactErr := p.transact(ctx)
 
-	req := strings.NewReader(string(transactionDocJSON))
-	res, err := p.EsConn.Index(indexName, req)
-	if err != nil {
-		// Log the error but don't fail the account creation
-		log.Printf("Failed to index transaction in Elasticsearch: %v", err)
+	if transactErr != nil {
+		log.Println(transactErr)
+		p.indexTransactionError(ctx, transactErr)
+		p.heartbeat(ctx, "", 0, 1)
+
+		// transact rolled back its tx on failure, so there's no committed
+		// transaction left to hang an outbox row off of; fall back to
+		// indexing the failure doc directly, best-effort, same as before
+		// the outbox existed.
+		transactionDoc := TransactionDocument{
+			AccountNumber:           p.Data.AccountNumber,
+			Type:                    p.Data.Type,
+			Amount:                  p.Data.Amount,
+			TransactionID:           p.Data.TransactionID,
+			Timestamp:               time.Now(),
+			BranchCode:              p.Data.BranchCode,
+			Status:                  "FAILED",
+			BalanceAfterTransaction: p.Data.AvailableBalance,
+			IdempotencyKey:          p.Data.IdempotencyKey,
+			RetryCount:              p.Data.RetryCount,
+		}
+		indexName := fmt.Sprintf("bank-transactions-%s", time.Now().Format("2006-01-02"))
+		transactionDocJSON, err := json.Marshal(transactionDoc)
+		if err != nil {
+			panic(fmt.Sprintf("failed to marshal JSON: %v", err))
+		}
+		res, err := p.EsConn.Index(indexName, strings.NewReader(string(transactionDocJSON)))
+		if err != nil {
+			// Log the error but don't fail the transaction.
+			log.Printf("Failed to index transaction in Elasticsearch: %v", err)
+		}
+		if res != nil {
+			defer res.Body.Close()
+		}
+	} else {
+		p.heartbeat(ctx, "", 1, 0)
+		// Postings carries the authoritative post-transaction balance;
+		// mirror it onto Data so anything inspecting the processor after
+		// the fact (tests, callers holding onto the struct) sees the same
+		// value transact() already committed.
+		if len(postings) > 0 {
+			p.Data.BranchCode = postings[0].BranchCode
+			p.Data.AvailableBalance = postings[0].BalanceAfter
+		}
+		p.Postings = postings
 	}
 
-	if res != nil {
-		defer res.Body.Close()
+	// Update the pending-request store, if this transaction went through the
+	// two-phase approval workflow, now that the outcome is durable: on
+	// success that means the balance update and its outbox row landed
+	// together, not that Elasticsearch has actually been updated yet -
+	// OutboxRelay delivers that asynchronously.
+	if p.PendingRequests != nil {
+		var markErr error
+		if transactErr == nil {
+			_, markErr = p.PendingRequests.MarkCompleted(ctx, p.Data.TransactionID)
+		} else {
+			_, markErr = p.PendingRequests.MarkFailed(ctx, p.Data.TransactionID)
+		}
+		if markErr != nil {
+			log.Printf("Failed to update pending request %s: %v", p.Data.TransactionID, markErr)
+		}
 	}
 
 	return nil
@@ -15,35 +15,59 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 
-	"github.com/siddarth99/banking-ledger/workers/processor"
 	internal "github.com/siddarth99/banking-ledger/pkg"
+	"github.com/siddarth99/banking-ledger/pkg/money"
+	"github.com/siddarth99/banking-ledger/workers/processor"
 )
 
+// scanAccountRow returns a ScanFunc that mocks the
+// "SELECT available_balance, branch_code, currency" row for accountNumber,
+// reporting balance in currency.
+func scanAccountRow(balance string, currency, branchCode string) func(dest ...interface{}) error {
+	return func(dest ...interface{}) error {
+		balanceDest := dest[0].(*money.Amount)
+		branchCodeDest := dest[1].(*string)
+		currencyDest := dest[2].(*string)
+		parsed, err := money.FromString(balance, currency)
+		if err != nil {
+			return err
+		}
+		*balanceDest = parsed
+		*branchCodeDest = branchCode
+		*currencyDest = currency
+		return nil
+	}
+}
 
 func TestProcessTransaction_DepositSuccess(t *testing.T) {
 	// Arrange
 	capturedSQL := ""
 	capturedArgs := []interface{}{}
-	capturedIndex := ""
-	capturedBody := ""
+	capturedOutboxIndex := ""
+	capturedOutboxPayload := ""
 
 	// Create mock tx
 	mockTx := &internal.MockPgDBConnection{
 		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			if strings.Contains(sql, "transaction_log") {
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			}
 			return &internal.MockPgxRow{
-				ScanFunc: func(dest ...interface{}) error {
-					// Mock scanning balance and branch code
-					balance := dest[0].(*float64)
-					branchCode := dest[1].(*string)
-					*balance = 1000.00
-					*branchCode = "BR001"
-					return nil
-				},
+				ScanFunc: scanAccountRow("1000.00", "USD", "BR001"),
 			}
 		},
 		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
-			capturedSQL = sql
-			capturedArgs = arguments
+			if strings.Contains(sql, "INSERT INTO processed_messages") {
+				return pgconn.NewCommandTag("INSERT 0 1"), nil
+			}
+			switch {
+			case strings.Contains(sql, "UPDATE accounts"):
+				capturedSQL = sql
+				capturedArgs = arguments
+			case strings.Contains(sql, "INSERT INTO transaction_outbox"):
+				capturedOutboxIndex = arguments[0].(string)
+				capturedOutboxPayload = string(arguments[1].([]byte))
+			}
 			return pgconn.CommandTag{}, nil
 		},
 	}
@@ -55,58 +79,49 @@ func TestProcessTransaction_DepositSuccess(t *testing.T) {
 		},
 	}
 
-	// Mock Elasticsearch client
-	mockEsClient := &internal.MockElasticsearchClient{
-		IndexFunc: func(index string, body io.Reader) (*esapi.Response, error) {
-			capturedIndex = index
-			bodyBytes, _ := io.ReadAll(body)
-			capturedBody = string(bodyBytes)
-			return &esapi.Response{
-				StatusCode: 201,
-				Body:       io.NopCloser(strings.NewReader(`{"result":"created"}`)),
-			}, nil
-		},
-	}
+	depositAmount, err := money.FromString("500.00", "USD")
+	assert.NoError(t, err)
 
 	// Create processor with mocks
 	proc := processor.TransactionProcessor{
 		ProcessWorker: processor.ProcessWorker{
 			PgxConn: mockConn,
-			EsConn:  mockEsClient,
 		},
 		Data: processor.TransactionData{
 			AccountNumber: "ACC123456",
-			Amount:        500.00,
+			Amount:        depositAmount,
 			Type:          "DEPOSIT",
 			TransactionID: "TX123456",
 		},
 	}
 
 	// Act
-	err := proc.ProcessTransaction(context.Background())
+	err = proc.ProcessTransaction(context.Background())
 
 	// Assert
 	assert.NoError(t, err)
 
 	// Verify SQL update parameters
 	assert.Contains(t, capturedSQL, "UPDATE accounts")
-	assert.Equal(t, 1500.00, capturedArgs[0]) // 1000 (initial) + 500 (deposit)
+	expectedBalance, err := money.FromString("1500.00", "USD") // 1000 (initial) + 500 (deposit)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedBalance, capturedArgs[0])
 	assert.Equal(t, "ACC123456", capturedArgs[1])
 
-	// Verify Elasticsearch indexing
-	assert.Contains(t, capturedIndex, "bank-transactions-")
+	// Verify the outbox row, rather than Elasticsearch, was written in the
+	// same tx as the balance update
+	assert.Contains(t, capturedOutboxIndex, "bank-transactions-")
 
-	// Verify document content
 	var doc map[string]interface{}
-	err = json.Unmarshal([]byte(capturedBody), &doc)
+	err = json.Unmarshal([]byte(capturedOutboxPayload), &doc)
 	assert.NoError(t, err)
 	assert.Equal(t, "TX123456", doc["transaction_id"])
 	assert.Equal(t, "ACC123456", doc["account_number"])
-	assert.Equal(t, 500.00, doc["amount"])
+	assert.Equal(t, map[string]interface{}{"value": "500.00", "currency": "USD"}, doc["amount"])
 	assert.Equal(t, "DEPOSIT", doc["type"])
 	assert.Equal(t, "COMPLETED", doc["status"])
 	assert.Equal(t, "BR001", doc["branch_code"])
-	assert.Equal(t, 1500.00, doc["balance_after_transaction"])
+	assert.Equal(t, map[string]interface{}{"value": "1500.00", "currency": "USD"}, doc["balance_after_transaction"])
 }
 
 func TestProcessTransaction_WithdrawalSuccess(t *testing.T) {
@@ -117,20 +132,21 @@ func TestProcessTransaction_WithdrawalSuccess(t *testing.T) {
 	// Create mock tx
 	mockTx := &internal.MockPgDBConnection{
 		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			if strings.Contains(sql, "transaction_log") {
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			}
 			return &internal.MockPgxRow{
-				ScanFunc: func(dest ...interface{}) error {
-					// Mock scanning balance and branch code
-					balance := dest[0].(*float64)
-					branchCode := dest[1].(*string)
-					*balance = 1000.00
-					*branchCode = "BR001"
-					return nil
-				},
+				ScanFunc: scanAccountRow("1000.00", "USD", "BR001"),
 			}
 		},
 		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
-			capturedSQL = sql
-			capturedArgs = arguments
+			if strings.Contains(sql, "INSERT INTO processed_messages") {
+				return pgconn.NewCommandTag("INSERT 0 1"), nil
+			}
+			if strings.Contains(sql, "UPDATE accounts") {
+				capturedSQL = sql
+				capturedArgs = arguments
+			}
 			return pgconn.CommandTag{}, nil
 		},
 	}
@@ -152,6 +168,9 @@ func TestProcessTransaction_WithdrawalSuccess(t *testing.T) {
 		},
 	}
 
+	withdrawalAmount, err := money.FromString("300.00", "USD")
+	assert.NoError(t, err)
+
 	// Create processor with mocks
 	proc := processor.TransactionProcessor{
 		ProcessWorker: processor.ProcessWorker{
@@ -160,23 +179,25 @@ func TestProcessTransaction_WithdrawalSuccess(t *testing.T) {
 		},
 		Data: processor.TransactionData{
 			AccountNumber: "ACC123456",
-			Amount:        300.00,
+			Amount:        withdrawalAmount,
 			Type:          "WITHDRAWAL",
 			TransactionID: "TX123456",
 		},
 	}
 
 	// Act
-	err := proc.ProcessTransaction(context.Background())
+	err = proc.ProcessTransaction(context.Background())
 
 	// Assert
 	assert.NoError(t, err)
 
 	// Verify SQL update parameters
 	assert.Contains(t, capturedSQL, "UPDATE accounts")
-	assert.Equal(t, 700.00, capturedArgs[0]) // 1000 (initial) - 300 (withdrawal)
+	expectedBalance, err := money.FromString("700.00", "USD") // 1000 (initial) - 300 (withdrawal)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedBalance, capturedArgs[0])
 	assert.Equal(t, "ACC123456", capturedArgs[1])
-	assert.Equal(t, 700.00, proc.Data.AvailableBalance)
+	assert.Equal(t, expectedBalance, proc.Data.AvailableBalance)
 }
 
 func TestProcessTransaction_InvalidType(t *testing.T) {
@@ -190,16 +211,16 @@ func TestProcessTransaction_InvalidType(t *testing.T) {
 	// Create mock tx
 	mockTx := &internal.MockPgDBConnection{
 		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			if strings.Contains(sql, "transaction_log") {
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			}
 			return &internal.MockPgxRow{
-				ScanFunc: func(dest ...interface{}) error {
-					balance := dest[0].(*float64)
-					branchCode := dest[1].(*string)
-					*balance = 1000.00
-					*branchCode = "BR001"
-					return nil
-				},
+				ScanFunc: scanAccountRow("1000.00", "USD", "BR001"),
 			}
 		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
 	}
 
 	// Create mock db connection
@@ -209,21 +230,34 @@ func TestProcessTransaction_InvalidType(t *testing.T) {
 		},
 	}
 
+	mockEsClient := &internal.MockElasticsearchClient{
+		IndexFunc: func(index string, body io.Reader) (*esapi.Response, error) {
+			return &esapi.Response{
+				StatusCode: 201,
+				Body:       io.NopCloser(strings.NewReader(`{"result":"created"}`)),
+			}, nil
+		},
+	}
+
+	amount, err := money.FromString("500.00", "USD")
+	assert.NoError(t, err)
+
 	// Create processor with mocks
 	proc := processor.TransactionProcessor{
 		ProcessWorker: processor.ProcessWorker{
 			PgxConn: mockConn,
+			EsConn:  mockEsClient,
 		},
 		Data: processor.TransactionData{
 			AccountNumber: "ACC123456",
-			Amount:        500.00,
+			Amount:        amount,
 			Type:          "INVALID_TYPE", // Invalid transaction type
 			TransactionID: "TX123456",
 		},
 	}
 
 	// Act
-	err := proc.ProcessTransaction(context.Background())
+	err = proc.ProcessTransaction(context.Background())
 
 	// Assert
 	assert.NoError(t, err) // ProcessTransaction swallows errors
@@ -242,15 +276,12 @@ func TestProcessTransaction_InsufficientFunds(t *testing.T) {
 	mockTx := &internal.MockPgDBConnection{
 		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
 			return &internal.MockPgxRow{
-				ScanFunc: func(dest ...interface{}) error {
-					balance := dest[0].(*float64)
-					branchCode := dest[1].(*string)
-					*balance = 100.00 // Lower balance than withdrawal amount
-					*branchCode = "BR001"
-					return nil
-				},
+				ScanFunc: scanAccountRow("100.00", "USD", "BR001"), // Lower balance than withdrawal amount
 			}
 		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
 	}
 
 	// Create mock db connection
@@ -268,22 +299,26 @@ func TestProcessTransaction_InsufficientFunds(t *testing.T) {
 			}, nil
 		},
 	}
+
+	amount, err := money.FromString("500.00", "USD")
+	assert.NoError(t, err)
+
 	// Create processor with mocks
 	proc := processor.TransactionProcessor{
 		ProcessWorker: processor.ProcessWorker{
 			PgxConn: mockConn,
-			EsConn: mockEsClient,
+			EsConn:  mockEsClient,
 		},
 		Data: processor.TransactionData{
 			AccountNumber: "ACC123456",
-			Amount:        500.00,
+			Amount:        amount,
 			Type:          "WITHDRAWAL",
 			TransactionID: "TX123456",
 		},
 	}
 
 	// Act
-	err := proc.ProcessTransaction(context.Background())
+	err = proc.ProcessTransaction(context.Background())
 
 	// Assert
 	assert.NoError(t, err) // ProcessTransaction swallows errors
@@ -301,16 +336,16 @@ func TestProcessTransaction_NegativeAmount(t *testing.T) {
 	// Create mock tx
 	mockTx := &internal.MockPgDBConnection{
 		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			if strings.Contains(sql, "transaction_log") {
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			}
 			return &internal.MockPgxRow{
-				ScanFunc: func(dest ...interface{}) error {
-					balance := dest[0].(*float64)
-					branchCode := dest[1].(*string)
-					*balance = 1000.00
-					*branchCode = "BR001"
-					return nil
-				},
+				ScanFunc: scanAccountRow("1000.00", "USD", "BR001"),
 			}
 		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
 	}
 
 	// Create mock db connection
@@ -328,28 +363,96 @@ func TestProcessTransaction_NegativeAmount(t *testing.T) {
 			}, nil
 		},
 	}
+
+	negativeAmount, err := money.FromString("-100.00", "USD")
+	assert.NoError(t, err)
+
 	// Create processor with mocks
 	proc := processor.TransactionProcessor{
 		ProcessWorker: processor.ProcessWorker{
 			PgxConn: mockConn,
-			EsConn: mockEsClient,
+			EsConn:  mockEsClient,
 		},
 		Data: processor.TransactionData{
 			AccountNumber: "ACC123456",
-			Amount:        -100.00, // Negative amount
+			Amount:        negativeAmount, // Negative amount
 			Type:          "DEPOSIT",
 			TransactionID: "TX123456",
 		},
 	}
 
 	// Act
-	err := proc.ProcessTransaction(context.Background())
+	err = proc.ProcessTransaction(context.Background())
 
 	// Assert
 	assert.NoError(t, err) // ProcessTransaction swallows errors
 	assert.Contains(t, capturedLogs.String(), "deposit amount must be positive")
 }
 
+func TestProcessTransaction_CurrencyMismatch(t *testing.T) {
+	// Arrange
+	capturedLogs := &bytes.Buffer{}
+	log.SetOutput(capturedLogs)
+	defer func() {
+		log.SetOutput(nil) // Reset logger output
+	}()
+
+	// Create mock tx; the account is denominated in EUR.
+	mockTx := &internal.MockPgDBConnection{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			if strings.Contains(sql, "transaction_log") {
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			}
+			return &internal.MockPgxRow{
+				ScanFunc: scanAccountRow("1000.00", "EUR", "BR001"),
+			}
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			return pgconn.NewCommandTag("INSERT 0 1"), nil
+		},
+	}
+
+	// Create mock db connection
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+
+	mockEsClient := &internal.MockElasticsearchClient{
+		IndexFunc: func(index string, body io.Reader) (*esapi.Response, error) {
+			return &esapi.Response{
+				StatusCode: 201,
+				Body:       io.NopCloser(strings.NewReader(`{"result":"created"}`)),
+			}, nil
+		},
+	}
+
+	amount, err := money.FromString("500.00", "USD")
+	assert.NoError(t, err)
+
+	// Create processor with mocks
+	proc := processor.TransactionProcessor{
+		ProcessWorker: processor.ProcessWorker{
+			PgxConn: mockConn,
+			EsConn:  mockEsClient,
+		},
+		Data: processor.TransactionData{
+			AccountNumber: "ACC123456",
+			Amount:        amount,
+			Type:          "DEPOSIT",
+			TransactionID: "TX123456",
+		},
+	}
+
+	// Act
+	err = proc.ProcessTransaction(context.Background())
+
+	// Assert
+	assert.NoError(t, err) // ProcessTransaction swallows errors
+	assert.Contains(t, capturedLogs.String(), "does not match account currency")
+}
+
 func TestProcessTransaction_DatabaseError(t *testing.T) {
 	// Arrange
 	capturedLogs := &bytes.Buffer{}
@@ -364,7 +467,7 @@ func TestProcessTransaction_DatabaseError(t *testing.T) {
 			return nil, errors.New("database connection error")
 		},
 	}
-	
+
 	mockEsClient := &internal.MockElasticsearchClient{
 		IndexFunc: func(index string, body io.Reader) (*esapi.Response, error) {
 			return &esapi.Response{
@@ -374,30 +477,37 @@ func TestProcessTransaction_DatabaseError(t *testing.T) {
 		},
 	}
 
+	amount, err := money.FromString("500.00", "USD")
+	assert.NoError(t, err)
+
 	// Create processor with mocks
 	proc := processor.TransactionProcessor{
 		ProcessWorker: processor.ProcessWorker{
 			PgxConn: mockConn,
-			EsConn: mockEsClient,
+			EsConn:  mockEsClient,
 		},
 		Data: processor.TransactionData{
 			AccountNumber: "ACC123456",
-			Amount:        500.00,
+			Amount:        amount,
 			Type:          "DEPOSIT",
 			TransactionID: "TX123456",
 		},
 	}
 
 	// Act
-	err := proc.ProcessTransaction(context.Background())
+	err = proc.ProcessTransaction(context.Background())
 
 	// Assert
 	assert.NoError(t, err) // ProcessTransaction swallows errors
 	assert.Contains(t, capturedLogs.String(), "failed to begin transaction")
 }
 
-func TestProcessTransaction_ElasticsearchError(t *testing.T) {
-	// Arrange
+func TestProcessTransaction_OutboxWriteFailure(t *testing.T) {
+	// Arrange: the balance update succeeds, but the outbox insert in the
+	// same tx fails - the whole transaction must roll back along with it,
+	// since the balance update and its outbox row either both land or
+	// neither does. This is the deposit-side analogue of the old
+	// best-effort "log and ignore" Elasticsearch failure this replaces.
 	capturedLogs := &bytes.Buffer{}
 	log.SetOutput(capturedLogs)
 	defer func() {
@@ -407,15 +517,21 @@ func TestProcessTransaction_ElasticsearchError(t *testing.T) {
 	// Create mock tx
 	mockTx := &internal.MockPgDBConnection{
 		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			if strings.Contains(sql, "transaction_log") {
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			}
 			return &internal.MockPgxRow{
-				ScanFunc: func(dest ...interface{}) error {
-					balance := dest[0].(*float64)
-					branchCode := dest[1].(*string)
-					*balance = 1000.00
-					*branchCode = "BR001"
-					return nil
-				},
+				ScanFunc: scanAccountRow("1000.00", "USD", "BR001"),
+			}
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			if strings.Contains(sql, "INSERT INTO processed_messages") {
+				return pgconn.NewCommandTag("INSERT 0 1"), nil
+			}
+			if strings.Contains(sql, "INSERT INTO transaction_outbox") {
+				return pgconn.CommandTag{}, errors.New("outbox insert failed")
 			}
+			return pgconn.CommandTag{}, nil
 		},
 	}
 
@@ -426,13 +542,22 @@ func TestProcessTransaction_ElasticsearchError(t *testing.T) {
 		},
 	}
 
-	// Mock Elasticsearch client with error
+	// Mock Elasticsearch client; the fallback direct-index path for a
+	// failed transaction should still fire since transact rolled back.
+	indexed := false
 	mockEsClient := &internal.MockElasticsearchClient{
 		IndexFunc: func(index string, body io.Reader) (*esapi.Response, error) {
-			return nil, errors.New("elasticsearch connection error")
+			indexed = true
+			return &esapi.Response{
+				StatusCode: 201,
+				Body:       io.NopCloser(strings.NewReader(`{"result":"created"}`)),
+			}, nil
 		},
 	}
 
+	amount, err := money.FromString("500.00", "USD")
+	assert.NoError(t, err)
+
 	// Create processor with mocks
 	proc := processor.TransactionProcessor{
 		ProcessWorker: processor.ProcessWorker{
@@ -441,16 +566,169 @@ func TestProcessTransaction_ElasticsearchError(t *testing.T) {
 		},
 		Data: processor.TransactionData{
 			AccountNumber: "ACC123456",
-			Amount:        500.00,
+			Amount:        amount,
 			Type:          "DEPOSIT",
 			TransactionID: "TX123456",
 		},
 	}
 
 	// Act
-	err := proc.ProcessTransaction(context.Background())
+	err = proc.ProcessTransaction(context.Background())
 
 	// Assert
-	assert.NoError(t, err) // Should not fail the overall function
-	assert.Contains(t, capturedLogs.String(), "Failed to index transaction in Elasticsearch")
+	assert.NoError(t, err) // ProcessTransaction swallows errors
+	assert.Contains(t, capturedLogs.String(), "failed to write outbox entry")
+	assert.True(t, indexed, "the failure doc should still be indexed directly")
+}
+
+func TestProcessTransaction_TransferSuccess(t *testing.T) {
+	// Arrange
+	var lockOrder []string
+	balances := map[string]func(dest ...interface{}) error{
+		"ACC111": scanAccountRow("1000.00", "USD", "BR001"),
+		"ACC222": scanAccountRow("200.00", "USD", "BR002"),
+	}
+	var updates []struct {
+		account string
+		balance interface{}
+	}
+
+	mockTx := &internal.MockPgDBConnection{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			if strings.Contains(sql, "transaction_log") {
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			}
+			account := args[0].(string)
+			lockOrder = append(lockOrder, account)
+			return &internal.MockPgxRow{ScanFunc: balances[account]}
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			if strings.Contains(sql, "INSERT INTO processed_messages") {
+				return pgconn.NewCommandTag("INSERT 0 1"), nil
+			}
+			if strings.Contains(sql, "UPDATE accounts") {
+				updates = append(updates, struct {
+					account string
+					balance interface{}
+				}{arguments[1].(string), arguments[0]})
+			}
+			return pgconn.CommandTag{}, nil
+		},
+	}
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+	mockEsClient := &internal.MockElasticsearchClient{
+		IndexFunc: func(index string, body io.Reader) (*esapi.Response, error) {
+			return &esapi.Response{
+				StatusCode: 201,
+				Body:       io.NopCloser(strings.NewReader(`{"result":"created"}`)),
+			}, nil
+		},
+	}
+
+	amount, err := money.FromString("150.00", "USD")
+	assert.NoError(t, err)
+
+	proc := processor.TransactionProcessor{
+		ProcessWorker: processor.ProcessWorker{
+			PgxConn: mockConn,
+			EsConn:  mockEsClient,
+		},
+		Data: processor.TransactionData{
+			SourceAccount:      "ACC111",
+			DestinationAccount: "ACC222",
+			Amount:             amount,
+			Type:               "TRANSFER",
+			TransactionID:      "TX789",
+		},
+	}
+
+	// Act
+	err = proc.ProcessTransaction(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	// ACC111 sorts before ACC222, so it's locked (and debited) first even
+	// though it's already first here; TestProcessTransaction_TransferLocksAccountsInAscendingOrder
+	// covers the case where source/destination order disagrees with sort order.
+	assert.Equal(t, []string{"ACC111", "ACC222"}, lockOrder)
+
+	expectedSourceBalance, err := money.FromString("850.00", "USD") // 1000 - 150
+	assert.NoError(t, err)
+	expectedDestinationBalance, err := money.FromString("350.00", "USD") // 200 + 150
+	assert.NoError(t, err)
+	assert.Equal(t, expectedSourceBalance, updates[0].balance)
+	assert.Equal(t, "ACC111", updates[0].account)
+	assert.Equal(t, expectedDestinationBalance, updates[1].balance)
+	assert.Equal(t, "ACC222", updates[1].account)
+}
+
+func TestProcessTransaction_TransferLocksAccountsInAscendingOrder(t *testing.T) {
+	// Arrange: the destination sorts before the source, so applyPosting
+	// must still lock the destination first to keep every transfer between
+	// this pair of accounts acquiring locks in the same order - otherwise a
+	// concurrent transfer running the other direction could deadlock
+	// against it.
+	var lockOrder []string
+	balances := map[string]func(dest ...interface{}) error{
+		"ACC111": scanAccountRow("1000.00", "USD", "BR001"),
+		"ACC222": scanAccountRow("200.00", "USD", "BR002"),
+	}
+
+	mockTx := &internal.MockPgDBConnection{
+		QueryRowFunc: func(ctx context.Context, sql string, args ...any) pgx.Row {
+			if strings.Contains(sql, "transaction_log") {
+				return &internal.MockPgxRow{ScanFunc: func(dest ...interface{}) error { return pgx.ErrNoRows }}
+			}
+			account := args[0].(string)
+			lockOrder = append(lockOrder, account)
+			return &internal.MockPgxRow{ScanFunc: balances[account]}
+		},
+		ExecFunc: func(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+			if strings.Contains(sql, "INSERT INTO processed_messages") {
+				return pgconn.NewCommandTag("INSERT 0 1"), nil
+			}
+			return pgconn.CommandTag{}, nil
+		},
+	}
+	mockConn := &internal.MockPgDBConnection{
+		BeginFunc: func(ctx context.Context) (pgx.Tx, error) {
+			return mockTx, nil
+		},
+	}
+	mockEsClient := &internal.MockElasticsearchClient{
+		IndexFunc: func(index string, body io.Reader) (*esapi.Response, error) {
+			return &esapi.Response{
+				StatusCode: 201,
+				Body:       io.NopCloser(strings.NewReader(`{"result":"created"}`)),
+			}, nil
+		},
+	}
+
+	amount, err := money.FromString("150.00", "USD")
+	assert.NoError(t, err)
+
+	proc := processor.TransactionProcessor{
+		ProcessWorker: processor.ProcessWorker{
+			PgxConn: mockConn,
+			EsConn:  mockEsClient,
+		},
+		Data: processor.TransactionData{
+			SourceAccount:      "ACC222", // sorts after the destination
+			DestinationAccount: "ACC111",
+			Amount:             amount,
+			Type:               "TRANSFER",
+			TransactionID:      "TX789",
+		},
+	}
+
+	// Act
+	err = proc.ProcessTransaction(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ACC111", "ACC222"}, lockOrder) // ascending, not source-then-destination
 }